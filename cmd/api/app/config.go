@@ -4,6 +4,9 @@ import (
 	"doctormakarhina/lumos/internal/inra/boot"
 	"doctormakarhina/lumos/internal/pkg/envconf"
 	"doctormakarhina/lumos/internal/pkg/errs"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -16,6 +19,26 @@ type config struct {
 	handlers      handlersConf
 	cloudPayments CloudPayments
 	unisender     Unisender
+	email         emailConf
+	smtp          smtpConf
+	referrals     referralsConf
+	seats         seatsConf
+	trialAbuse    trialAbuseConf
+	trialPolicy   trialPolicyConf
+	auth          authConf
+	magicLink     magicLinkConf
+	sharing       sharingConf
+	admin         adminConf
+	analytics     analyticsConf
+	ingest        ingestConf
+	retention     boot.RetentionConfig
+	geoip         geoipConf
+	contactsSync  boot.ContactsSyncConfig
+	notify        notifyConf
+	reports       reportsConf
+	memberBot     memberBotConf
+	metrics       metricsConf
+	tracing       tracingConf
 }
 
 type tgBotConfig struct {
@@ -23,6 +46,69 @@ type tgBotConfig struct {
 	PollerTimeout time.Duration `env:"TG_BOT_POLLER_TIMEOUT" envDefault:"30s"`
 	Debug         bool          `env:"TG_BOT_DEBUG"`
 	AdminChatID   int64         `env:"TG_BOT_ADMIN_CHAT_ID,required"`
+	// ActionChatIDs and ActionUserIDs allow notification buttons outside of the admin chat
+	ActionChatIDs []int64 `env:"TG_BOT_ACTION_CHAT_IDS"`
+	ActionUserIDs []int64 `env:"TG_BOT_ACTION_USER_IDS"`
+	QueueSize     int     `env:"TG_BOT_QUEUE_SIZE" envDefault:"1000"`
+	// ChatInterval is min delay between messages to one chat, telegram allows 20 per minute in groups
+	ChatInterval   time.Duration `env:"TG_BOT_CHAT_INTERVAL" envDefault:"3s"`
+	GlobalInterval time.Duration `env:"TG_BOT_GLOBAL_INTERVAL" envDefault:"50ms"`
+	MaxAttempts    int           `env:"TG_BOT_SEND_MAX_ATTEMPTS" envDefault:"5"`
+	DrainTimeout   time.Duration `env:"TG_BOT_DRAIN_TIMEOUT" envDefault:"10s"`
+	// Mode is polling or webhook, webhook is required to run several replicas
+	Mode string `env:"TG_BOT_MODE" envDefault:"polling"`
+	// WebhookBaseURL is public url of the api prefix, e.g. https://api.lumos-care.ru/api
+	WebhookBaseURL     string `env:"TG_BOT_WEBHOOK_BASE_URL"`
+	WebhookPathSecret  string `env:"TG_BOT_WEBHOOK_PATH_SECRET"`
+	WebhookSecretToken string `env:"TG_BOT_WEBHOOK_SECRET_TOKEN"`
+}
+
+const (
+	tgBotModePolling = "polling"
+	tgBotModeWebhook = "webhook"
+)
+
+var tgWebhookSecretTokenRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+func (r *tgBotConfig) Validate() error {
+	switch r.Mode {
+	case tgBotModePolling:
+		return nil
+	case tgBotModeWebhook:
+	default:
+		return fmt.Errorf("unknown tg bot mode %q", r.Mode)
+	}
+
+	if !strings.HasPrefix(r.WebhookBaseURL, "https://") {
+		return fmt.Errorf("tg bot webhook base url must be https")
+	}
+	if r.WebhookPathSecret == "" {
+		return fmt.Errorf("tg bot webhook path secret is required")
+	}
+	if !tgWebhookSecretTokenRe.MatchString(r.WebhookSecretToken) {
+		return fmt.Errorf("tg bot webhook secret token must be 1-256 chars of A-Z, a-z, 0-9, _ and -")
+	}
+
+	return nil
+}
+
+type memberBotConf struct {
+	// Token of the customer bot, the bot is disabled when empty
+	Token    string `env:"TG_MEMBER_BOT_TOKEN"`
+	Username string `env:"TG_MEMBER_BOT_USERNAME"`
+	RenewURL string `env:"MEMBER_RENEW_URL"`
+	// CancelURL is prefix of autopayment cancel page, subscription id is appended
+	CancelURL   string        `env:"MEMBER_CANCEL_URL" envDefault:"https://my.cloudpayments.ru/unsubscribe/from/"`
+	LinkCodeTTL time.Duration `env:"TG_MEMBER_LINK_CODE_TTL" envDefault:"15m"`
+}
+
+type notifyConf struct {
+	// RoutesFile is a json notify.RoutesConfig, all notifications go to the admin chat when empty
+	RoutesFile string        `env:"NOTIFY_ROUTES_FILE"`
+	Timeout    time.Duration `env:"NOTIFY_TIMEOUT" envDefault:"15s"`
+	// QueueSize bounds pending deliveries, notifications are sent in background by Workers
+	QueueSize int `env:"NOTIFY_QUEUE_SIZE" envDefault:"1000"`
+	Workers   int `env:"NOTIFY_WORKERS" envDefault:"4"`
 }
 
 type handlersConf struct {
@@ -36,7 +122,133 @@ type handlersConf struct {
 	ProdamusPayRouteHash            string `env:"HTTP_PRODAMUS_PAYMENT_NOTIFICATION_ROUTE_HASH,required"`
 	CloudPaymentsPayRouteHash       string `env:"HTTP_CLOUD_PAYMENTS_PAY_NOTIFICATION_ROUTE_HASH,required"`
 	CloudPaymentsRecurrentRouteHash string `env:"HTTP_CLOUD_PAYMENTS_RECURRENT_NOTIFICATION_ROUTE_HASH,required"`
-	TildaProjectID                  string `env:"TILDA_PROJECT_ID,required"`
+	// TildaProjectID is the only default project used when ProjectsFile is not set
+	TildaProjectID string `env:"TILDA_PROJECT_ID"`
+	// ProjectsFile is a json array of projects, see domain.Project
+	ProjectsFile string `env:"PROJECTS_FILE"`
+}
+
+type authConf struct {
+	// TokenKeys is a list of key_id:base64_secret pairs, all of them are accepted for verification
+	TokenKeys        map[string]string `env:"AUTH_TOKEN_KEYS,required"`
+	TokenActiveKeyID string            `env:"AUTH_TOKEN_ACTIVE_KEY_ID,required"`
+	TokenTTL         time.Duration     `env:"AUTH_TOKEN_TTL" envDefault:"1h"`
+	TokenIssuer      string            `env:"AUTH_TOKEN_ISSUER" envDefault:"lumos-api"`
+	// LegacyEmailMode keeps deprecated email search params auth for user routes,
+	// it trusts the email without proof of ownership and must be enabled explicitly
+	LegacyEmailMode bool `env:"AUTH_LEGACY_EMAIL_MODE" envDefault:"false"`
+}
+
+type geoipConf struct {
+	// DBPath is MaxMind mmdb file, auth logs are not enriched with location when empty
+	DBPath string `env:"GEOIP_DB_PATH"`
+}
+
+type magicLinkConf struct {
+	BaseURL     string        `env:"MAGIC_LINK_BASE_URL,required"`
+	TTL         time.Duration `env:"MAGIC_LINK_TTL" envDefault:"15m"`
+	RateWindow  time.Duration `env:"MAGIC_LINK_RATE_WINDOW" envDefault:"1h"`
+	MaxPerEmail int           `env:"MAGIC_LINK_MAX_PER_EMAIL" envDefault:"5"`
+	MaxPerIP    int           `env:"MAGIC_LINK_MAX_PER_IP" envDefault:"20"`
+}
+
+type sharingConf struct {
+	Window            time.Duration `env:"SHARING_WINDOW" envDefault:"720h"`
+	FingerprintWeight float64       `env:"SHARING_FINGERPRINT_WEIGHT" envDefault:"1"`
+	IPWeight          float64       `env:"SHARING_IP_WEIGHT" envDefault:"0.5"`
+	UserAgentWeight   float64       `env:"SHARING_USER_AGENT_WEIGHT" envDefault:"0.25"`
+	ReportLimit       int           `env:"SHARING_REPORT_LIMIT" envDefault:"30"`
+	// AlertScore is min score to alert admins about, 0 disables alerts
+	AlertScore    float64       `env:"SHARING_ALERT_SCORE" envDefault:"8"`
+	AlertInterval time.Duration `env:"SHARING_ALERT_INTERVAL" envDefault:"24h"`
+	AlertCooldown time.Duration `env:"SHARING_ALERT_COOLDOWN" envDefault:"168h"`
+	// MaxConcurrentDevices caps distinct fingerprints per account within ConcurrentWindow, 0 disables the cap
+	MaxConcurrentDevices int           `env:"SHARING_MAX_CONCURRENT_DEVICES" envDefault:"0"`
+	ConcurrentWindow     time.Duration `env:"SHARING_CONCURRENT_WINDOW" envDefault:"24h"`
+}
+
+type ingestConf struct {
+	QueueSize     int           `env:"TELEMETRY_QUEUE_SIZE" envDefault:"10000"`
+	BatchSize     int           `env:"TELEMETRY_BATCH_SIZE" envDefault:"500"`
+	FlushInterval time.Duration `env:"TELEMETRY_FLUSH_INTERVAL" envDefault:"2s"`
+	FlushTimeout  time.Duration `env:"TELEMETRY_FLUSH_TIMEOUT" envDefault:"10s"`
+}
+
+type adminConf struct {
+	// ApiToken protects admin routes, they are disabled when it is empty
+	ApiToken string `env:"ADMIN_API_TOKEN"`
+}
+
+type metricsConf struct {
+	// Addr is a separate listener for prometheus scrapes, metrics are disabled when it is empty
+	Addr string `env:"METRICS_ADDR" envDefault:""`
+	Path string `env:"METRICS_PATH" envDefault:"/metrics"`
+	// BusinessTTL caches db backed gauges between scrapes
+	BusinessTTL time.Duration `env:"METRICS_BUSINESS_TTL" envDefault:"1m"`
+}
+
+type tracingConf struct {
+	// Exporter is none, otlp or stdout, otlp exporter is configured with
+	// standard OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_HEADERS
+	Exporter    string  `env:"TRACING_EXPORTER" envDefault:"none"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+}
+
+func (r *tracingConf) Validate() error {
+	if r.SampleRatio < 0 || r.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be in [0, 1]")
+	}
+	return nil
+}
+
+type analyticsConf struct {
+	DefaultLimit     int  `env:"SEARCH_ANALYTICS_DEFAULT_LIMIT" envDefault:"50"`
+	TrendingMinCount int  `env:"SEARCH_ANALYTICS_TRENDING_MIN_COUNT" envDefault:"3"`
+	DigestEnabled    bool `env:"SEARCH_DIGEST_ENABLED" envDefault:"true"`
+	// DigestWeekday is 0 for sunday through 6 for saturday
+	DigestWeekday int `env:"SEARCH_DIGEST_WEEKDAY" envDefault:"1"`
+	DigestHour    int `env:"SEARCH_DIGEST_HOUR" envDefault:"10"`
+	DigestLimit   int `env:"SEARCH_DIGEST_LIMIT" envDefault:"10"`
+}
+
+type reportsConf struct {
+	DailyEnabled  bool `env:"REPORTS_DAILY_ENABLED" envDefault:"true"`
+	WeeklyEnabled bool `env:"REPORTS_WEEKLY_ENABLED" envDefault:"true"`
+	// Weekday is 0 for sunday through 6 for saturday
+	Weekday int `env:"REPORTS_WEEKDAY" envDefault:"1"`
+	Hour    int `env:"REPORTS_HOUR" envDefault:"9"`
+	// Timezone defines report day boundaries
+	Timezone string `env:"REPORTS_TIMEZONE" envDefault:"Europe/Moscow"`
+}
+
+type referralsConf struct {
+	RewardDays         int           `env:"REFERRAL_REWARD_DAYS" envDefault:"14"`
+	MaxRewardsPerMonth int           `env:"REFERRAL_MAX_REWARDS_PER_MONTH" envDefault:"5"`
+	FingerprintWindow  time.Duration `env:"REFERRAL_FINGERPRINT_WINDOW" envDefault:"2160h"`
+}
+
+type seatsConf struct {
+	// PerTariff is a list of tariff:seats pairs, e.g. "3 месяца:3,6 месяцев:5"
+	PerTariff map[string]int `env:"TEAM_SEATS_PER_TARIFF"`
+}
+
+type trialPolicyConf struct {
+	DefaultDays int `env:"TRIAL_DEFAULT_DAYS" envDefault:"3"`
+	MaxDays     int `env:"TRIAL_MAX_DAYS" envDefault:"14"`
+	// AllowedSources is a list of Tilda form ids allowed to register trials, empty allows any
+	AllowedSources []string `env:"TRIAL_ALLOWED_SOURCES"`
+	// Campaigns is a list of campaign:days pairs, e.g. "blog:7,webinar:14"
+	Campaigns map[string]int `env:"TRIAL_CAMPAIGNS"`
+}
+
+type trialAbuseConf struct {
+	DisposableAction        string        `env:"TRIAL_ABUSE_DISPOSABLE_ACTION" envDefault:"deny"`
+	FingerprintLinkedAction string        `env:"TRIAL_ABUSE_FINGERPRINT_LINKED_ACTION" envDefault:"shorten"`
+	IPLinkedAction          string        `env:"TRIAL_ABUSE_IP_LINKED_ACTION" envDefault:"review"`
+	ShortenTo               time.Duration `env:"TRIAL_ABUSE_SHORTEN_TO" envDefault:"24h"`
+	Window                  time.Duration `env:"TRIAL_ABUSE_WINDOW" envDefault:"2160h"`
+	DisposableDomains       []string      `env:"TRIAL_DISPOSABLE_DOMAINS_EXTRA"`
+	DisposableDomainsFile   string        `env:"TRIAL_DISPOSABLE_DOMAINS_FILE"`
 }
 
 type Unisender struct {
@@ -44,6 +256,38 @@ type Unisender struct {
 	AfterTrialExpiredListTitle         string `env:"UNISENDER_AFTER_TRIAL_EXPIRED_LIST_TITLE" envDefault:"Lumos закончился пробный"`
 	AfterReccurrentPaymentListTitle    string `env:"UNISENDER_AFTER_RECCURRENT_PAYMENT_LIST_TITLE" envDefault:"Lumos после автооплаты"`
 	AfterAutopaymentCancelledListTitle string `env:"UNISENDER_AFTER_AUTOPAYMENT_CANCELLED_LIST_TITLE" envDefault:"Lumos отмена автоплатежа"`
+	GiftPurchasedListTitle             string `env:"UNISENDER_GIFT_PURCHASED_LIST_TITLE" envDefault:"Lumos подарок покупателю"`
+	GiftReceivedListTitle              string `env:"UNISENDER_GIFT_RECEIVED_LIST_TITLE" envDefault:"Lumos подарок получателю"`
+	// TransactionalListTitle is used for single sends when EMAIL_TRANSACTIONAL_PROVIDER is unisender
+	TransactionalListTitle string `env:"UNISENDER_TRANSACTIONAL_LIST_TITLE" envDefault:"Lumos вход"`
+	SenderName             string `env:"UNISENDER_SENDER_NAME" envDefault:"Lumos"`
+	SenderEmail            string `env:"UNISENDER_SENDER_EMAIL"`
+}
+
+type emailConf struct {
+	// TransactionalProvider is unisender or smtp
+	TransactionalProvider string `env:"EMAIL_TRANSACTIONAL_PROVIDER" envDefault:"unisender"`
+	Locale                string `env:"EMAIL_LOCALE" envDefault:"ru"`
+	// Timezone is used to format dates in emails
+	Timezone string `env:"EMAIL_TIMEZONE" envDefault:"Europe/Moscow"`
+	// TemplatesEnabled lists transactional templates to send besides magic link:
+	// receipt, trial_started, renewal_succeeded, payment_failed, expiry_reminder
+	TemplatesEnabled []string `env:"EMAIL_TEMPLATES_ENABLED"`
+	// ExpiryReminderBefore is how long before access expiration the reminder is sent
+	ExpiryReminderBefore   time.Duration `env:"EMAIL_EXPIRY_REMINDER_BEFORE" envDefault:"72h"`
+	ExpiryReminderInterval time.Duration `env:"EMAIL_EXPIRY_REMINDER_INTERVAL" envDefault:"1h"`
+}
+
+type smtpConf struct {
+	Host     string `env:"SMTP_HOST"`
+	Port     int    `env:"SMTP_PORT" envDefault:"587"`
+	Username string `env:"SMTP_USERNAME"`
+	Password string `env:"SMTP_PASSWORD"`
+	// TLS is none, starttls or tls
+	TLS       string        `env:"SMTP_TLS" envDefault:"starttls"`
+	FromName  string        `env:"SMTP_FROM_NAME" envDefault:"Lumos"`
+	FromEmail string        `env:"SMTP_FROM_EMAIL"`
+	Timeout   time.Duration `env:"SMTP_TIMEOUT" envDefault:"10s"`
 }
 
 type CloudPayments struct {
@@ -62,5 +306,25 @@ func (r *config) load() error {
 		envconf.Load(&r.handlers),
 		envconf.Load(&r.cloudPayments),
 		envconf.Load(&r.unisender),
+		envconf.Load(&r.email),
+		envconf.Load(&r.smtp),
+		envconf.Load(&r.referrals),
+		envconf.Load(&r.seats),
+		envconf.Load(&r.trialAbuse),
+		envconf.Load(&r.trialPolicy),
+		envconf.Load(&r.auth),
+		envconf.Load(&r.magicLink),
+		envconf.Load(&r.sharing),
+		envconf.Load(&r.admin),
+		envconf.Load(&r.analytics),
+		envconf.Load(&r.ingest),
+		envconf.Load(&r.retention),
+		envconf.Load(&r.geoip),
+		envconf.Load(&r.contactsSync),
+		envconf.Load(&r.notify),
+		envconf.Load(&r.reports),
+		envconf.Load(&r.memberBot),
+		envconf.Load(&r.metrics),
+		envconf.Load(&r.tracing),
 	)
 }
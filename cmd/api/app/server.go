@@ -2,22 +2,43 @@ package app
 
 import (
 	"context"
+	"doctormakarhina/lumos/internal/core/analytics"
+	"doctormakarhina/lumos/internal/core/auth"
+	"doctormakarhina/lumos/internal/core/contacts"
+	"doctormakarhina/lumos/internal/core/deliverability"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/members"
 	"doctormakarhina/lumos/internal/core/notify"
 	"doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/core/projects"
+	"doctormakarhina/lumos/internal/core/referrals"
+	"doctormakarhina/lumos/internal/core/reminders"
+	"doctormakarhina/lumos/internal/core/reports"
+	"doctormakarhina/lumos/internal/core/retention"
+	"doctormakarhina/lumos/internal/core/seats"
+	"doctormakarhina/lumos/internal/core/sharing"
+	"doctormakarhina/lumos/internal/core/trials"
 	"doctormakarhina/lumos/internal/inra/cloudpayments"
 	"doctormakarhina/lumos/internal/inra/emails"
 	"doctormakarhina/lumos/internal/inra/httpapi"
 	"doctormakarhina/lumos/internal/inra/pg"
 	"doctormakarhina/lumos/internal/inra/tgbot"
+	"doctormakarhina/lumos/internal/inra/webhook"
+	"doctormakarhina/lumos/internal/pkg/authtoken"
 	"doctormakarhina/lumos/internal/pkg/db"
 	"doctormakarhina/lumos/internal/pkg/envconf"
+	"doctormakarhina/lumos/internal/pkg/geoip"
 	"doctormakarhina/lumos/internal/pkg/httpx"
+	"doctormakarhina/lumos/internal/pkg/ingest"
 	"doctormakarhina/lumos/internal/pkg/logger"
+	"doctormakarhina/lumos/internal/pkg/metrics"
+	"doctormakarhina/lumos/internal/pkg/tracing"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -33,8 +54,22 @@ type Server struct {
 	rootLogger *slog.Logger
 	db         *sqlx.DB
 	bot        *tgbot.Bot
+	memberBot  *tgbot.MemberBot
 	notif      notify.Service
+	fanout     *notify.Fanout
+	sharing    sharing.Service
+	analytics  analytics.Service
+	authLogsQ  *ingest.Queue[domain.AuthLog]
+	searchQ    *ingest.Queue[domain.SearchQuery]
+	retention  retention.Service
+	reminders  reminders.Service
+	reports    reports.Service
+	contacts   contacts.Service
+	geo        *geoip.Reader
 	api        *httpx.Server
+	metrics    *httpx.Server
+	// stopTracing flushes pending spans
+	stopTracing func(ctx context.Context) error
 }
 
 func (r *Server) Init() error {
@@ -61,6 +96,15 @@ func (r *Server) Init() error {
 	}
 	logger.SetToDefault(r.rootLogger)
 
+	r.stopTracing, err = tracing.Setup(ctx, tracing.Config{
+		Exporter:    r.cfg.tracing.Exporter,
+		ServiceName: r.cfg.common.AppName,
+		SampleRatio: r.cfg.tracing.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+
 	r.db, err = db.NewPG(
 		ctx,
 		r.cfg.pg.Url,
@@ -76,28 +120,127 @@ func (r *Server) Init() error {
 		return fmt.Errorf("failed to ping db: %w", err)
 	}
 
+	routes, err := r.notifyRoutes()
+	if err != nil {
+		return err
+	}
+
 	r.bot, err = tgbot.NewAdminTgBot(tgbot.BotCfg{
 		Token:         r.cfg.tgBot.Token,
 		ChatID:        r.cfg.tgBot.AdminChatID,
+		ActionChatIDs: r.cfg.tgBot.ActionChatIDs,
+		ActionUserIDs: r.cfg.tgBot.ActionUserIDs,
 		Debug:         r.cfg.tgBot.Debug,
 		PollerTimeout: r.cfg.tgBot.PollerTimeout,
-		Logger:        r.rootLogger,
+		Webhook:       r.tgWebhook("admin"),
+		Queue: tgbot.QueueCfg{
+			Size:           r.cfg.tgBot.QueueSize,
+			ChatInterval:   r.cfg.tgBot.ChatInterval,
+			GlobalInterval: r.cfg.tgBot.GlobalInterval,
+			MaxAttempts:    r.cfg.tgBot.MaxAttempts,
+			DrainTimeout:   r.cfg.tgBot.DrainTimeout,
+		},
+		Logger: r.rootLogger,
 	})
 	if err != nil {
-		r.rootLogger.Warn("admin tg bot unavailable, notifications disabled", slog.String("err", err.Error()))
-		r.notif = notify.NewNoop()
-	} else {
-		r.notif = r.bot
+		r.rootLogger.Warn("admin tg bot unavailable, telegram notifications disabled", slog.String("err", err.Error()))
 	}
 
-	usersRepo := pg.NewUserRepo(r.db)
-
-	emailSrv := emails.NewUniSenderSrv(
+	uniSender := emails.NewUniSenderSrv(
 		r.cfg.unisender.ApiKey,
 		emails.UniSenderSrvCfg{
+			TransactionalListTitle: r.cfg.unisender.TransactionalListTitle,
+			SenderName:             r.cfg.unisender.SenderName,
+			SenderEmail:            r.cfg.unisender.SenderEmail,
+		},
+	)
+	transactional, err := r.transactionalEmails(uniSender)
+	if err != nil {
+		return err
+	}
+
+	r.notif = r.notifier(routes, transactional)
+
+	projectsRegistry, err := r.loadProjects()
+	if err != nil {
+		return err
+	}
+
+	usersRepo := pg.NewUserRepo(r.db)
+	entitlementsRepo := pg.NewEntitlementRepo(r.db)
+	giftsRepo := pg.NewGiftRepo(r.db)
+	billingRepo := pg.NewBillingEventRepo(r.db)
+	referralsRepo := pg.NewReferralRepo(r.db)
+	authLogsRepo := pg.NewAuthLogRepo(r.db)
+	seatsRepo := pg.NewSeatRepo(r.db)
+	trialFlagsRepo := pg.NewTrialFlagRepo(r.db)
+	magicLinksRepo := pg.NewMagicLinkRepo(r.db)
+	searchQueriesRepo := pg.NewSearchQueryRepo(r.db)
+
+	r.retention = retention.NewRetentionService(
+		pg.NewPartitionRepo(r.db),
+		r.notif,
+		retention.Config{
+			Tables:      []string{"auth_logs", "search_queries"},
+			Retention:   r.cfg.retention.Retention,
+			MonthsAhead: r.cfg.retention.MonthsAhead,
+			Interval:    r.cfg.retention.Interval,
+		},
+		r.rootLogger,
+	)
+	r.warnShortRetention()
+
+	// partitions of the current month are created before the api accepts telemetry,
+	// rows of a missing partition go to the default one, so a failure does not stop the start
+	maintainCtx, maintainCancel := context.WithTimeout(context.Background(), time.Minute)
+	err = r.retention.Maintain(maintainCtx)
+	maintainCancel()
+	if err != nil {
+		r.rootLogger.Error("telemetry partitions maintenance failed", slog.String("err", err.Error()))
+		r.notif.Notify(notify.Error(
+			"retention.maintenance_failed",
+			"telemetry partitions maintenance failed",
+			notify.F("err", err),
+		))
+	}
+
+	r.authLogsQ = ingest.New(r.ingestConfig("AuthLogs"), authLogsRepo.SaveBatch, r.rootLogger)
+	r.searchQ = ingest.New(r.ingestConfig("SearchQueries"), searchQueriesRepo.SaveBatch, r.rootLogger)
+
+	for _, name := range r.cfg.email.TemplatesEnabled {
+		if !slices.Contains(emails.Templates, name) {
+			return fmt.Errorf("unknown email template %q", name)
+		}
+	}
+	if r.cfg.email.ExpiryReminderInterval <= 0 {
+		return fmt.Errorf("invalid expiry reminder interval %s", r.cfg.email.ExpiryReminderInterval)
+	}
+	emailTZ, err := time.LoadLocation(r.cfg.email.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid email timezone: %w", err)
+	}
+	emailRenderer, err := emails.NewRenderer(r.cfg.email.Locale, emailTZ)
+	if err != nil {
+		return err
+	}
+	deliverabilitySrv := deliverability.NewDeliverabilityService(
+		pg.NewEmailDeliverabilityRepo(r.db),
+		usersRepo,
+		r.notif,
+	)
+	emailSrv := emails.NewSrv(
+		uniSender,
+		transactional,
+		emailRenderer,
+		deliverabilitySrv,
+		emails.SrvCfg{
 			AfterTrialExpiredListTitle:         r.cfg.unisender.AfterTrialExpiredListTitle,
 			AfterReccurrentPaymentListTitle:    r.cfg.unisender.AfterReccurrentPaymentListTitle,
 			AfterAutopaymentCancelledListTitle: r.cfg.unisender.AfterAutopaymentCancelledListTitle,
+			GiftPurchasedListTitle:             r.cfg.unisender.GiftPurchasedListTitle,
+			GiftReceivedListTitle:              r.cfg.unisender.GiftReceivedListTitle,
+			Locale:                             r.cfg.email.Locale,
+			Enabled:                            r.cfg.email.TemplatesEnabled,
 		},
 	)
 
@@ -105,26 +248,282 @@ func (r *Server) Init() error {
 		PublicID:   r.cfg.cloudPayments.PublicID,
 		APISecret:  r.cfg.cloudPayments.APISecret,
 		BaseURL:    r.cfg.cloudPayments.APIBaseURL,
-		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		HTTPClient: &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)},
 	})
 	if err != nil {
 		return err
 	}
 
+	referralSrv := referrals.NewReferralsService(
+		usersRepo,
+		referralsRepo,
+		authLogsRepo,
+		cloudPaymentsClient,
+		r.notif,
+		referrals.Config{
+			RewardDays:         r.cfg.referrals.RewardDays,
+			MaxRewardsPerMonth: r.cfg.referrals.MaxRewardsPerMonth,
+			FingerprintWindow:  r.cfg.referrals.FingerprintWindow,
+		},
+	)
+
+	seatsSrv := seats.NewSeatsService(
+		usersRepo,
+		seatsRepo,
+		r.notif,
+		r.cfg.seats.PerTariff,
+	)
+	if r.bot != nil {
+		r.bot.RegInSeatsCommand(seatsSrv)
+	}
+
+	r.sharing = sharing.NewSharingService(
+		usersRepo,
+		authLogsRepo,
+		r.notif,
+		sharing.Config{
+			Window:               r.cfg.sharing.Window,
+			FingerprintWeight:    r.cfg.sharing.FingerprintWeight,
+			IPWeight:             r.cfg.sharing.IPWeight,
+			UserAgentWeight:      r.cfg.sharing.UserAgentWeight,
+			ReportLimit:          r.cfg.sharing.ReportLimit,
+			AlertScore:           r.cfg.sharing.AlertScore,
+			AlertInterval:        r.cfg.sharing.AlertInterval,
+			AlertCooldown:        r.cfg.sharing.AlertCooldown,
+			MaxConcurrentDevices: r.cfg.sharing.MaxConcurrentDevices,
+			ConcurrentWindow:     r.cfg.sharing.ConcurrentWindow,
+		},
+	)
+	if r.bot != nil {
+		r.bot.RegInSharingCommand(r.sharing)
+	}
+
+	if r.cfg.analytics.DigestWeekday < 0 || r.cfg.analytics.DigestWeekday > 6 ||
+		r.cfg.analytics.DigestHour < 0 || r.cfg.analytics.DigestHour > 23 {
+		return fmt.Errorf("invalid search digest schedule: weekday %d, hour %d", r.cfg.analytics.DigestWeekday, r.cfg.analytics.DigestHour)
+	}
+	r.contacts = contacts.NewContactsService(
+		usersRepo,
+		pg.NewSyncStateRepo(r.db),
+		uniSender,
+		r.notif,
+		contacts.Config{
+			BatchSize: r.cfg.contactsSync.BatchSize,
+			Interval:  r.cfg.contactsSync.Interval,
+		},
+	)
+
+	if r.cfg.reports.Weekday < 0 || r.cfg.reports.Weekday > 6 || r.cfg.reports.Hour < 0 || r.cfg.reports.Hour > 23 {
+		return fmt.Errorf("invalid business reports schedule: weekday %d, hour %d", r.cfg.reports.Weekday, r.cfg.reports.Hour)
+	}
+	reportsTZ, err := time.LoadLocation(r.cfg.reports.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid business reports timezone: %w", err)
+	}
+	r.reports = reports.NewReportsService(
+		billingRepo,
+		r.notif,
+		reports.Config{
+			DailyEnabled:  r.cfg.reports.DailyEnabled,
+			WeeklyEnabled: r.cfg.reports.WeeklyEnabled,
+			Weekday:       time.Weekday(r.cfg.reports.Weekday),
+			Hour:          r.cfg.reports.Hour,
+			Location:      reportsTZ,
+		},
+	)
+	if r.bot != nil {
+		r.bot.RegInReportCommand(r.reports)
+	}
+
+	var messenger members.Messenger = members.NewNoopMessenger()
+	if r.cfg.memberBot.Token != "" {
+		r.memberBot, err = tgbot.NewMemberTgBot(tgbot.MemberBotCfg{
+			Token:         r.cfg.memberBot.Token,
+			Debug:         r.cfg.tgBot.Debug,
+			PollerTimeout: r.cfg.tgBot.PollerTimeout,
+			Webhook:       r.tgWebhook("member"),
+			Queue: tgbot.QueueCfg{
+				Size:           r.cfg.tgBot.QueueSize,
+				ChatInterval:   r.cfg.tgBot.ChatInterval,
+				GlobalInterval: r.cfg.tgBot.GlobalInterval,
+				MaxAttempts:    r.cfg.tgBot.MaxAttempts,
+				DrainTimeout:   r.cfg.tgBot.DrainTimeout,
+			},
+			Logger: r.rootLogger,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to init member tg bot: %w", err)
+		}
+		messenger = r.memberBot
+	}
+	membersSrv := members.NewMembersService(
+		usersRepo,
+		pg.NewTelegramLinkRepo(r.db),
+		messenger,
+		members.Config{
+			CodeTTL:     r.cfg.memberBot.LinkCodeTTL,
+			BotUsername: r.cfg.memberBot.Username,
+			RenewURL:    r.cfg.memberBot.RenewURL,
+			CancelURL:   r.cfg.memberBot.CancelURL,
+			Location:    emailTZ,
+		},
+	)
+	if r.memberBot != nil {
+		r.memberBot.RegInMemberCommands(membersSrv)
+	}
+
+	reminderChannels := map[string]reminders.Sender{}
+	if slices.Contains(r.cfg.email.TemplatesEnabled, emails.TemplateExpiryReminder) {
+		reminderChannels["email"] = emailSrv
+	}
+	if r.memberBot != nil {
+		reminderChannels["telegram"] = membersSrv
+	}
+	r.reminders = reminders.NewRemindersService(
+		usersRepo,
+		pg.NewExpiryReminderRepo(r.db),
+		reminderChannels,
+		r.notif,
+		reminders.Config{
+			Before:   r.cfg.email.ExpiryReminderBefore,
+			Interval: r.cfg.email.ExpiryReminderInterval,
+		},
+	)
+
+	r.analytics = analytics.NewAnalyticsService(
+		searchQueriesRepo,
+		r.notif,
+		analytics.Config{
+			DefaultLimit:     r.cfg.analytics.DefaultLimit,
+			TrendingMinCount: r.cfg.analytics.TrendingMinCount,
+			DigestEnabled:    r.cfg.analytics.DigestEnabled,
+			DigestWeekday:    time.Weekday(r.cfg.analytics.DigestWeekday),
+			DigestHour:       r.cfg.analytics.DigestHour,
+			DigestLimit:      r.cfg.analytics.DigestLimit,
+		},
+	)
+
+	trialAbuse, err := trials.NewAbuseChecker(
+		usersRepo,
+		authLogsRepo,
+		trialFlagsRepo,
+		trials.AbuseConfig{
+			DisposableAction:        r.cfg.trialAbuse.DisposableAction,
+			FingerprintLinkedAction: r.cfg.trialAbuse.FingerprintLinkedAction,
+			IPLinkedAction:          r.cfg.trialAbuse.IPLinkedAction,
+			ShortenTo:               r.cfg.trialAbuse.ShortenTo,
+			Window:                  r.cfg.trialAbuse.Window,
+			DisposableDomains:       r.cfg.trialAbuse.DisposableDomains,
+			DisposableDomainsFile:   r.cfg.trialAbuse.DisposableDomainsFile,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	trialPolicy, err := trials.NewPolicy(
+		trialFlagsRepo,
+		trials.PolicyConfig{
+			DefaultDays:    r.cfg.trialPolicy.DefaultDays,
+			MaxDays:        r.cfg.trialPolicy.MaxDays,
+			AllowedSources: r.cfg.trialPolicy.AllowedSources,
+			Campaigns:      r.cfg.trialPolicy.Campaigns,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
 	paymentSrv := payments.NewPaymentsService(
 		usersRepo,
+		entitlementsRepo,
+		giftsRepo,
+		billingRepo,
+		referralSrv,
+		seatsSrv,
+		r.sharing,
+		trialPolicy,
+		trialAbuse,
 		emailSrv,
+		membersSrv,
 		r.notif,
 		cloudPaymentsClient,
-		r.cfg.handlers.TildaProjectID,
+		projectsRegistry,
+	)
+	if r.bot != nil {
+		r.bot.RegInNotificationActions(paymentSrv, pg.NewActionNonceRepo(r.db))
+	}
+
+	tokenKeys, err := authtoken.ParseKeys(r.cfg.auth.TokenKeys)
+	if err != nil {
+		return err
+	}
+	tokens, err := authtoken.NewManager(authtoken.Config{
+		Keys:        tokenKeys,
+		ActiveKeyID: r.cfg.auth.TokenActiveKeyID,
+		TTL:         r.cfg.auth.TokenTTL,
+		Issuer:      r.cfg.auth.TokenIssuer,
+	})
+	if err != nil {
+		return err
+	}
+
+	authSrv, err := auth.NewAuthService(
+		usersRepo,
+		seatsRepo,
+		tokens,
+		magicLinksRepo,
+		authLogsRepo,
+		emailSrv,
+		r.notif,
+		projectsRegistry,
+		auth.MagicLinkConfig{
+			BaseURL:     r.cfg.magicLink.BaseURL,
+			TTL:         r.cfg.magicLink.TTL,
+			RateWindow:  r.cfg.magicLink.RateWindow,
+			MaxPerEmail: r.cfg.magicLink.MaxPerEmail,
+			MaxPerIP:    r.cfg.magicLink.MaxPerIP,
+		},
 	)
+	if err != nil {
+		return err
+	}
+
+	authenticator := httpapi.NewAuthenticator(tokens, r.cfg.auth.LegacyEmailMode, r.rootLogger)
+	if r.cfg.auth.LegacyEmailMode {
+		r.rootLogger.Warn("deprecated email search params auth is enabled for user routes")
+	}
+
+	trustedProxies, err := httpx.ParseTrustedProxies(r.cfg.http.TrustedProxies)
+	if err != nil {
+		return err
+	}
 
+	var geo geoip.Locator = geoip.NewNoop()
+	if r.cfg.geoip.DBPath != "" {
+		r.geo, err = geoip.Open(r.cfg.geoip.DBPath)
+		if err != nil {
+			return fmt.Errorf("failed to open geoip db: %w", err)
+		}
+		geo = r.geo
+	}
+
+	routeName := httpx.RouteNamer(
+		r.cfg.handlers.TrialPaymentsRouteHash,
+		r.cfg.handlers.ProdamusPayRouteHash,
+		r.cfg.handlers.CloudPaymentsPayRouteHash,
+		r.cfg.handlers.CloudPaymentsRecurrentRouteHash,
+		r.cfg.tgBot.WebhookPathSecret,
+	)
 	apiHandler := httpapi.NewRouter()
 	apiHandler.Use(
+		tracing.HTTPMiddleware(routeName),
+		metrics.HTTPMiddleware(routeName),
+		httpx.ClientIP(trustedProxies),
 		cors.Handler(cors.Options{
 			// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 			// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-			AllowedOrigins:   strings.Split(r.cfg.handlers.ApiCorsAllowedHosts, ","),
+			AllowedOrigins:   projectsRegistry.CorsOrigins(),
 			AllowedMethods:   []string{"GET", "POST", "OPTIONS", "PUT", "DELETE", "PATCH", "HEAD"},
 			AllowedHeaders:   []string{"*"},
 			ExposedHeaders:   []string{"Link"},
@@ -136,18 +535,28 @@ func (r *Server) Init() error {
 	apiHandler.Route(r.cfg.handlers.ApiServePrefix, func(router chi.Router) {
 		httpapi.RegInPing(router)
 		httpapi.RegInHealthz(router, r.rootLogger)
-		httpapi.RegInAuthLogs(router, r.db, r.rootLogger)
-		httpapi.RegInSearchLogs(router, r.db, r.rootLogger)
+		httpapi.RegInAuthLogs(router, r.authLogsQ, geo, r.rootLogger)
+		httpapi.RegInSearchLogs(router, r.searchQ, r.rootLogger)
+		httpapi.RegInAdminTelemetryRoutes(router, r.cfg.admin.ApiToken, map[string]httpapi.QueueStats{
+			"auth_logs":      r.authLogsQ,
+			"search_queries": r.searchQ,
+		}, r.rootLogger)
+		httpapi.RegInAdminSearchRoutes(router, r.cfg.admin.ApiToken, r.analytics, r.rootLogger)
+		httpapi.RegInUniSenderWebhook(router, r.cfg.unisender.ApiKey, deliverabilitySrv, r.rootLogger)
+		httpapi.RegInAdminDeliverabilityRoutes(router, r.cfg.admin.ApiToken, deliverabilitySrv, r.rootLogger)
+		httpapi.RegInAdminEmailRoutes(router, r.cfg.admin.ApiToken, emailRenderer, r.cfg.email.Locale, r.rootLogger)
 		httpapi.RegInTrialPayments(
 			router,
 			r.cfg.handlers.TrialPaymentsRouteHash,
 			paymentSrv,
+			referralSrv,
 			r.notif,
 		)
 		httpapi.RegInProdamusPayWebHook(
 			router,
 			r.cfg.handlers.ProdamusPayRouteHash,
 			paymentSrv,
+			referralSrv,
 			r.notif,
 			r.rootLogger,
 		)
@@ -155,6 +564,7 @@ func (r *Server) Init() error {
 			router,
 			r.cfg.handlers.CloudPaymentsPayRouteHash,
 			paymentSrv,
+			referralSrv,
 			r.notif,
 			r.rootLogger,
 		)
@@ -165,16 +575,64 @@ func (r *Server) Init() error {
 			r.notif,
 			r.rootLogger,
 		)
+		httpapi.RegInAuthTokenRoutes(
+			router,
+			authSrv,
+			authenticator,
+			r.rootLogger,
+		)
+		httpapi.RegInMagicLinkRoutes(
+			router,
+			authSrv,
+			geo,
+			r.rootLogger,
+		)
 		httpapi.RegInUserAccessRoute(
 			router,
 			paymentSrv,
+			authenticator,
 			r.rootLogger,
 		)
 		httpapi.RegInUserInfoRoute(
+			router,
+			paymentSrv,
+			authenticator,
+			r.rootLogger,
+		)
+		httpapi.RegInUserReferralRoute(
+			router,
+			paymentSrv,
+			referralSrv,
+			authenticator,
+			r.rootLogger,
+		)
+		httpapi.RegInUserSeatsRoutes(
+			router,
+			paymentSrv,
+			seatsSrv,
+			authenticator,
+			r.rootLogger,
+		)
+		httpapi.RegInGiftRedeemRoute(
 			router,
 			paymentSrv,
 			r.rootLogger,
 		)
+		if r.bot != nil && r.bot.WebhookHandler() != nil {
+			httpapi.RegInTelegramWebhook(router, "admin", r.cfg.tgBot.WebhookPathSecret, r.bot.WebhookHandler())
+		}
+		if r.memberBot != nil && r.memberBot.WebhookHandler() != nil {
+			httpapi.RegInTelegramWebhook(router, "member", r.cfg.tgBot.WebhookPathSecret, r.memberBot.WebhookHandler())
+		}
+		if r.memberBot != nil {
+			httpapi.RegInUserTelegramLinkRoute(
+				router,
+				paymentSrv,
+				membersSrv,
+				authenticator,
+				r.rootLogger,
+			)
+		}
 	})
 
 	// TODO(add client side caching, etag probably??)
@@ -201,11 +659,122 @@ func (r *Server) Init() error {
 		AutoCertHosts:     r.cfg.http.AutoCertHosts,
 	}, apiHandler, r.rootLogger)
 
+	if r.cfg.metrics.Addr != "" {
+		err = r.initMetrics(usersRepo)
+		if err != nil {
+			return err
+		}
+	}
+
 	r.rootLogger.Info("app initialized")
 
 	return nil
 }
 
+// initMetrics registers db backed collectors and creates internal listener for prometheus.
+func (r *Server) initMetrics(usersRepo *pg.UserRepo) error {
+	err := metrics.RegisterDB(r.db.DB, "lumos")
+	if err != nil {
+		return fmt.Errorf("failed to register db metrics: %w", err)
+	}
+
+	err = metrics.RegisterSubscribers(func(ctx context.Context) (map[string]int, error) {
+		counts, err := usersRepo.ActiveByTariff(ctx, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]int, len(counts))
+		for _, c := range counts {
+			out[c.Tariff] = c.Count
+		}
+		return out, nil
+	}, r.cfg.metrics.BusinessTTL)
+	if err != nil {
+		return fmt.Errorf("failed to register business metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(r.cfg.metrics.Path, metrics.Handler())
+	r.metrics = httpx.NewServer(httpx.Config{
+		Name:              "Metrics",
+		Addr:              r.cfg.metrics.Addr,
+		ReadHeaderTimeout: r.cfg.http.ReadHeaderTimeout,
+		ShutdownTimeout:   r.cfg.http.ShutdownTimeout,
+	}, mux, r.rootLogger)
+
+	return nil
+}
+
+// warnShortRetention reports telemetry windows longer than raw telemetry is kept.
+func (r *Server) warnShortRetention() {
+	if r.cfg.retention.Retention <= 0 {
+		return
+	}
+
+	windows := map[string]time.Duration{
+		"REFERRAL_FINGERPRINT_WINDOW": r.cfg.referrals.FingerprintWindow,
+		"TRIAL_ABUSE_WINDOW":          r.cfg.trialAbuse.Window,
+		"SHARING_WINDOW":              r.cfg.sharing.Window,
+	}
+	for name, window := range windows {
+		if window > r.cfg.retention.Retention {
+			r.rootLogger.Warn("telemetry window is longer than retention",
+				slog.String("window", name),
+				slog.Duration("value", window),
+				slog.Duration("retention", r.cfg.retention.Retention),
+			)
+		}
+	}
+}
+
+func (r *Server) ingestConfig(name string) ingest.Config {
+	return ingest.Config{
+		Name:          name,
+		QueueSize:     r.cfg.ingest.QueueSize,
+		BatchSize:     r.cfg.ingest.BatchSize,
+		FlushInterval: r.cfg.ingest.FlushInterval,
+		FlushTimeout:  r.cfg.ingest.FlushTimeout,
+	}
+}
+
+// loadProjects builds projects registry from the projects file, without it the only
+// default project is configured by TILDA_PROJECT_ID and HTTP_API_CORS_ALLOWED_HOSTS.
+func (r *Server) loadProjects() (*projects.Registry, error) {
+	var list []domain.Project
+	if r.cfg.handlers.ProjectsFile != "" {
+		var err error
+		list, err = projects.LoadFile(r.cfg.handlers.ProjectsFile)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if r.cfg.handlers.TildaProjectID == "" {
+			return nil, fmt.Errorf("either PROJECTS_FILE or TILDA_PROJECT_ID is required")
+		}
+		list = []domain.Project{{
+			ID:          r.cfg.handlers.TildaProjectID,
+			Name:        "Lumos",
+			CorsOrigins: strings.Split(r.cfg.handlers.ApiCorsAllowedHosts, ","),
+			Default:     true,
+		}}
+	}
+
+	registry, err := projects.NewRegistry(list)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range registry.All() {
+		r.rootLogger.Info("project registered",
+			slog.String("id", p.ID),
+			slog.String("name", p.Name),
+			slog.Bool("default", p.Default),
+		)
+	}
+
+	return registry, nil
+}
+
 func (r *Server) Run() error {
 	var g run.Group
 
@@ -239,10 +808,104 @@ func (r *Server) Run() error {
 			},
 		)
 	}
+	if r.memberBot != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				return r.memberBot.Run(ctx)
+			},
+			func(err error) {
+				cancel()
+			},
+		)
+	}
+	// singleton jobs (partition maintenance, alerts, reports, digests and exports) run on one replica only,
+	// reminders are deduplicated by the db and run everywhere
+	leader := pg.NewLeader(r.db, r.rootLogger)
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				return leader.Run(ctx, "retention", r.retention.Run)
+			},
+			func(err error) {
+				cancel()
+			},
+		)
+	}
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				return leader.Run(ctx, "sharing_alerts", r.sharing.Run)
+			},
+			func(err error) {
+				cancel()
+			},
+		)
+	}
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				return leader.Run(ctx, "search_digest", r.analytics.Run)
+			},
+			func(err error) {
+				cancel()
+			},
+		)
+	}
 	{
 		ctx, cancel := context.WithCancel(context.Background())
 		g.Add(
 			func() error {
+				return leader.Run(ctx, "reports", r.reports.Run)
+			},
+			func(err error) {
+				cancel()
+			},
+		)
+	}
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				return r.reminders.Run(ctx)
+			},
+			func(err error) {
+				cancel()
+			},
+		)
+	}
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				return leader.Run(ctx, "contacts_export", r.contacts.Run)
+			},
+			func(err error) {
+				cancel()
+			},
+		)
+	}
+	if r.metrics != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				return r.metrics.Run(ctx)
+			},
+			func(err error) {
+				cancel()
+			},
+		)
+	}
+	// telemetry and notify queues are drained after api is stopped, so in-flight requests are not lost
+	apiStopped := make(chan struct{})
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				defer close(apiStopped)
 				return r.api.Run(ctx)
 			},
 			func(err error) {
@@ -250,11 +913,118 @@ func (r *Server) Run() error {
 			},
 		)
 	}
+	queues := []func(ctx context.Context) error{r.authLogsQ.Run, r.searchQ.Run}
+	if r.fanout != nil {
+		queues = append(queues, r.fanout.Run)
+	}
+	for _, runQueue := range queues {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(
+			func() error {
+				return runQueue(ctx)
+			},
+			func(err error) {
+				go func() {
+					<-apiStopped
+					cancel()
+				}()
+			},
+		)
+	}
 
 	return g.Run()
 }
 
+// transactionalEmails selects provider for single sends, list automations stay in UniSender.
+func (r *Server) transactionalEmails(uniSender *emails.UniSenderSrv) (emails.Transactional, error) {
+	switch r.cfg.email.TransactionalProvider {
+	case "unisender":
+		if r.cfg.unisender.SenderEmail == "" {
+			return nil, fmt.Errorf("UNISENDER_SENDER_EMAIL is required for unisender transactional provider")
+		}
+		return uniSender, nil
+	case "smtp":
+		return emails.NewSMTP(emails.SMTPConfig{
+			Host:      r.cfg.smtp.Host,
+			Port:      r.cfg.smtp.Port,
+			Username:  r.cfg.smtp.Username,
+			Password:  r.cfg.smtp.Password,
+			TLS:       r.cfg.smtp.TLS,
+			FromName:  r.cfg.smtp.FromName,
+			FromEmail: r.cfg.smtp.FromEmail,
+			Timeout:   r.cfg.smtp.Timeout,
+		})
+	default:
+		return nil, fmt.Errorf("unknown email transactional provider %q", r.cfg.email.TransactionalProvider)
+	}
+}
+
+// notifyRoutes loads NOTIFY_ROUTES_FILE, without it everything goes to the admin chat.
+func (r *Server) notifyRoutes() (notify.RoutesConfig, error) {
+	if r.cfg.notify.RoutesFile != "" {
+		return notify.LoadRoutesFile(r.cfg.notify.RoutesFile)
+	}
+
+	return notify.RoutesConfig{
+		Targets: map[string]notify.Target{"admin": {Type: "telegram", ChatID: r.cfg.tgBot.AdminChatID}},
+		Default: []string{"admin"},
+	}, nil
+}
+
+func (r *Server) notifier(routes notify.RoutesConfig, transactional emails.Transactional) notify.Service {
+	senders := make(map[string]notify.Sender, len(routes.Targets))
+	for name, t := range routes.Targets {
+		switch t.Type {
+		case "telegram":
+			if r.bot == nil {
+				continue
+			}
+			senders[name] = r.bot.Target(t.ChatID, t.ThreadID)
+		case "email":
+			senders[name] = emails.NewNotifyTarget(transactional, t.To)
+		case "webhook":
+			senders[name] = webhook.NewNotifyTarget(t.URL, t.Secret, &http.Client{Timeout: r.cfg.notify.Timeout, Transport: tracing.Transport(nil)})
+		}
+	}
+	if len(senders) == 0 {
+		r.rootLogger.Warn("no notify targets available, notifications disabled")
+		return notify.NewNoop()
+	}
+
+	r.fanout = notify.NewFanout(
+		notify.NewRouter(routes.Routes, routes.Default),
+		senders,
+		notify.FanoutConfig{
+			Timeout:   r.cfg.notify.Timeout,
+			QueueSize: r.cfg.notify.QueueSize,
+			Workers:   r.cfg.notify.Workers,
+		},
+		r.rootLogger,
+	)
+	return r.fanout
+}
+
 func (r *Server) Shutdown() {
+	if r.stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), r.cfg.common.ShutdownTimeout)
+		err := r.stopTracing(ctx)
+		cancel()
+		if err != nil && r.rootLogger != nil {
+			r.rootLogger.Error(
+				"failed to flush traces",
+				slog.String("err", err.Error()),
+			)
+		}
+	}
+	if r.geo != nil {
+		err := r.geo.Close()
+		if err != nil && r.rootLogger != nil {
+			r.rootLogger.Error(
+				"failed to close geoip db",
+				slog.String("err", err.Error()),
+			)
+		}
+	}
 	if r.db != nil {
 		err := r.db.Close()
 		if err != nil && r.rootLogger != nil {
@@ -265,3 +1035,14 @@ func (r *Server) Shutdown() {
 		}
 	}
 }
+
+// tgWebhook returns webhook config of the bot, it is empty in polling mode.
+func (r *Server) tgWebhook(bot string) tgbot.WebhookCfg {
+	if r.cfg.tgBot.Mode != tgBotModeWebhook {
+		return tgbot.WebhookCfg{}
+	}
+	return tgbot.WebhookCfg{
+		URL:         strings.TrimSuffix(r.cfg.tgBot.WebhookBaseURL, "/") + httpapi.TelegramWebhookPath(bot, r.cfg.tgBot.WebhookPathSecret),
+		SecretToken: r.cfg.tgBot.WebhookSecretToken,
+	}
+}
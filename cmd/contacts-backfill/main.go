@@ -0,0 +1,91 @@
+// Command contacts-backfill exports all users fields to UniSender contacts,
+// the api keeps them in sync afterwards.
+package main
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/contacts"
+	"doctormakarhina/lumos/internal/core/notify"
+	"doctormakarhina/lumos/internal/inra/boot"
+	"doctormakarhina/lumos/internal/inra/emails"
+	"doctormakarhina/lumos/internal/inra/pg"
+	"doctormakarhina/lumos/internal/pkg/db"
+	"doctormakarhina/lumos/internal/pkg/envconf"
+	"doctormakarhina/lumos/internal/pkg/errs"
+	"doctormakarhina/lumos/internal/pkg/logger"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type uniSenderConf struct {
+	ApiKey string `env:"UNISENDER_API_KEY,required"`
+}
+
+// logNotifier prints admin notifications, e.g. rejected contacts, to the log.
+type logNotifier struct{}
+
+func (logNotifier) ForAdmin(msg string) {
+	slog.Warn(msg)
+}
+
+func (logNotifier) Notify(n notify.Notification) {
+	slog.Warn(n.Text())
+}
+
+func main() {
+	err := run()
+	if err != nil {
+		slog.Error("contacts backfill failed", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	logger.ConfigureDefault()
+	envconf.LoadDotenvIfEnabled()
+
+	var (
+		pgCfg   boot.PgConf
+		syncCfg boot.ContactsSyncConfig
+		usCfg   uniSenderConf
+	)
+	err := errs.First(
+		envconf.Load(&pgCfg),
+		envconf.Load(&syncCfg),
+		envconf.Load(&usCfg),
+	)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	connCtx, connCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer connCancel()
+	conn, err := db.NewPG(connCtx, pgCfg.Url, pgCfg.MaxConns, pgCfg.MaxConns, pgCfg.MaxConnIdleTime)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	srv := contacts.NewContactsService(
+		pg.NewUserRepo(conn),
+		pg.NewSyncStateRepo(conn),
+		emails.NewUniSenderSrv(usCfg.ApiKey, emails.UniSenderSrvCfg{}),
+		logNotifier{},
+		contacts.Config{BatchSize: syncCfg.BatchSize},
+	)
+
+	started := time.Now()
+	total, err := srv.Backfill(ctx)
+	slog.Info("contacts backfill finished",
+		slog.Int("exported", total),
+		slog.Duration("took", time.Since(started)),
+	)
+
+	return err
+}
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS lumos.gifts (
+  id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  code             varchar NOT NULL UNIQUE,
+  payer_email      varchar NOT NULL,
+  recipient_email  varchar NOT NULL,
+  tariff           varchar NOT NULL,
+  price            real NOT NULL,
+  provider         varchar NOT NULL,
+  transaction_id   varchar NOT NULL DEFAULT '',
+  created_at       timestamptz NOT NULL DEFAULT NOW(),
+  redeemed_at      timestamptz,
+  redeemed_by      varchar NOT NULL DEFAULT '',
+  updated_at       timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS lumos_gifts_provider_transaction_id
+ON lumos.gifts (provider, transaction_id)
+WHERE transaction_id <> '';
+
+CREATE INDEX IF NOT EXISTS lumos_gifts_recipient_email
+ON lumos.gifts (recipient_email ASC NULLS LAST);
+
+CREATE OR REPLACE TRIGGER set_timestamp_gifts
+  BEFORE UPDATE ON lumos.gifts
+  FOR EACH ROW
+  EXECUTE FUNCTION trigger_set_timestamp();
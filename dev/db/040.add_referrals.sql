@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS lumos.referral_codes (
+  user_id     uuid PRIMARY KEY REFERENCES lumos.users (id) ON DELETE CASCADE,
+  code        varchar NOT NULL UNIQUE,
+  created_at  timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS lumos.referrals (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  referrer_id    uuid NOT NULL REFERENCES lumos.users (id) ON DELETE CASCADE,
+  referee_email  varchar NOT NULL UNIQUE,
+  source         varchar NOT NULL,
+  status         varchar NOT NULL,
+  reason         varchar NOT NULL DEFAULT '',
+  reward_days    integer NOT NULL DEFAULT 0,
+  created_at     timestamptz NOT NULL DEFAULT NOW(),
+  rewarded_at    timestamptz,
+  updated_at     timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS lumos_referrals_referrer_id_rewarded_at
+ON lumos.referrals (referrer_id, rewarded_at DESC NULLS LAST);
+
+CREATE OR REPLACE TRIGGER set_timestamp_referrals
+  BEFORE UPDATE ON lumos.referrals
+  FOR EACH ROW
+  EXECUTE FUNCTION trigger_set_timestamp();
+
+CREATE INDEX IF NOT EXISTS lumos_auth_logs_fingerprint
+ON lumos.auth_logs (fingerprint ASC NULLS LAST);
+
+CREATE INDEX IF NOT EXISTS lumos_auth_logs_ip
+ON lumos.auth_logs (ip ASC NULLS LAST);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS lumos.seats (
+  id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  owner_id      uuid NOT NULL REFERENCES lumos.users (id) ON DELETE CASCADE,
+  member_email  varchar NOT NULL UNIQUE,
+  created_at    timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS lumos_seats_owner_id_created_at
+ON lumos.seats (owner_id, created_at ASC);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS lumos.trial_flags (
+  id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  email          varchar NOT NULL,
+  action         varchar NOT NULL,
+  reasons        varchar NOT NULL DEFAULT '',
+  linked_logins  varchar NOT NULL DEFAULT '',
+  fingerprint    varchar NOT NULL DEFAULT '',
+  created_at     timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS lumos_trial_flags_created_at
+ON lumos.trial_flags (created_at DESC NULLS LAST);
+
+CREATE INDEX IF NOT EXISTS lumos_trial_flags_email
+ON lumos.trial_flags (email ASC NULLS LAST);
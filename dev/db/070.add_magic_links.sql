@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS lumos.magic_links (
+  id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  token_hash  varchar NOT NULL UNIQUE,
+  email       varchar NOT NULL,
+  project_id  varchar NOT NULL,
+  ip          varchar NOT NULL DEFAULT '',
+  created_at  timestamptz NOT NULL DEFAULT NOW(),
+  expires_at  timestamptz NOT NULL,
+  used_at     timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS lumos_magic_links_email_created_at
+ON lumos.magic_links (email, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS lumos_magic_links_ip_created_at
+ON lumos.magic_links (ip, created_at DESC);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS lumos.entitlements (
+  user_id          uuid NOT NULL REFERENCES lumos.users(id) ON DELETE CASCADE,
+  project_id       varchar NOT NULL,
+  tariff           varchar NOT NULL,
+  expires_at       timestamptz NOT NULL,
+  subscription_id  varchar NOT NULL DEFAULT '',
+  created_at       timestamptz NOT NULL DEFAULT NOW(),
+  updated_at       timestamptz NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, project_id)
+);
+
+CREATE OR REPLACE TRIGGER set_timestamp_entitlements
+  BEFORE UPDATE ON lumos.entitlements
+  FOR EACH ROW
+  EXECUTE FUNCTION trigger_set_timestamp();
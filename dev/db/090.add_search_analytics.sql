@@ -0,0 +1,13 @@
+ALTER TABLE lumos.search_queries
+ADD COLUMN IF NOT EXISTS results_count integer;
+
+-- queries are grouped case and whitespace insensitive
+ALTER TABLE lumos.search_queries
+ADD COLUMN IF NOT EXISTS query_norm varchar
+GENERATED ALWAYS AS (lower(regexp_replace(btrim(query), '\s+', ' ', 'g'))) STORED;
+
+CREATE INDEX IF NOT EXISTS lumos_search_queries_created_at
+ON lumos.search_queries (created_at DESC);
+
+CREATE INDEX IF NOT EXISTS lumos_search_queries_query_norm_created_at
+ON lumos.search_queries (query_norm, created_at DESC);
@@ -0,0 +1,173 @@
+-- auth_logs and search_queries are partitioned by UTC month of created_at, partitions
+-- ahead are created by the service and old ones are rolled up and dropped by retention job
+
+-- rows which arrived into the DEFAULT partition for a month without partition
+-- are moved into the partition when it is created
+CREATE OR REPLACE FUNCTION lumos.create_monthly_partitions(parent text, from_month date, to_month date)
+RETURNS void AS $$
+DECLARE
+  m date := date_trunc('month', from_month)::date;
+  part text;
+  lo timestamptz;
+  hi timestamptz;
+  def text := parent || '_default';
+  cols text;
+BEGIN
+  SELECT string_agg(quote_ident(column_name), ', ' ORDER BY ordinal_position) INTO cols
+  FROM information_schema.columns
+  WHERE table_schema = 'lumos' AND table_name = parent AND is_generated = 'NEVER';
+
+  WHILE m <= to_month LOOP
+    part := parent || '_p' || to_char(m, 'YYYY_MM');
+    -- bounds do not depend on the session timezone, the service parses partition names as UTC
+    lo := m::timestamp AT TIME ZONE 'UTC';
+    hi := (m + interval '1 month') AT TIME ZONE 'UTC';
+
+    IF to_regclass(format('lumos.%I', part)) IS NULL THEN
+      IF to_regclass(format('lumos.%I', def)) IS NOT NULL THEN
+        EXECUTE format(
+          'CREATE TEMP TABLE moved_rows ON COMMIT DROP AS SELECT %s FROM lumos.%I WHERE created_at >= %L AND created_at < %L',
+          cols, def, lo, hi
+        );
+        EXECUTE format(
+          'DELETE FROM lumos.%I WHERE created_at >= %L AND created_at < %L',
+          def, lo, hi
+        );
+      END IF;
+
+      EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS lumos.%I PARTITION OF lumos.%I FOR VALUES FROM (%L) TO (%L)',
+        part,
+        parent,
+        lo,
+        hi
+      );
+
+      IF to_regclass('pg_temp.moved_rows') IS NOT NULL THEN
+        EXECUTE format('INSERT INTO lumos.%I (%s) SELECT %s FROM moved_rows', parent, cols, cols);
+        DROP TABLE moved_rows;
+      END IF;
+    END IF;
+
+    m := (m + interval '1 month')::date;
+  END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+------------------------------
+
+CREATE TABLE lumos.auth_logs_new (
+    id BIGSERIAL,
+    login VARCHAR,
+    ip VARCHAR,
+    useragent VARCHAR,
+    fingerprint VARCHAR,
+    confidenceScore VARCHAR,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+SELECT lumos.create_monthly_partitions(
+  'auth_logs_new',
+  (coalesce((SELECT min(created_at) FROM lumos.auth_logs), NOW()) AT TIME ZONE 'UTC')::date,
+  ((NOW() + interval '2 months') AT TIME ZONE 'UTC')::date
+);
+
+INSERT INTO lumos.auth_logs_new (id, login, ip, useragent, fingerprint, confidenceScore, created_at)
+SELECT id, login, ip, useragent, fingerprint, confidenceScore, created_at
+FROM lumos.auth_logs;
+
+SELECT setval(pg_get_serial_sequence('lumos.auth_logs_new', 'id'), coalesce((SELECT max(id) FROM lumos.auth_logs), 0) + 1, false);
+
+DROP TABLE lumos.auth_logs;
+ALTER TABLE lumos.auth_logs_new RENAME TO auth_logs;
+
+-- partitions were created with the temporary parent name
+DO $$
+DECLARE
+  p record;
+BEGIN
+  FOR p IN
+    SELECT c.relname FROM pg_inherits i
+    JOIN pg_class c ON c.oid = i.inhrelid
+    JOIN pg_class pc ON pc.oid = i.inhparent
+    JOIN pg_namespace n ON n.oid = pc.relnamespace
+    WHERE n.nspname = 'lumos' AND pc.relname = 'auth_logs'
+  LOOP
+    EXECUTE format('ALTER TABLE lumos.%I RENAME TO %I', p.relname, replace(p.relname, 'auth_logs_new_', 'auth_logs_'));
+  END LOOP;
+END $$;
+
+-- catches rows of months without partition, so a missing partition does not fail inserts
+CREATE TABLE IF NOT EXISTS lumos.auth_logs_default PARTITION OF lumos.auth_logs DEFAULT;
+
+CREATE INDEX lumox_auth_logs_created_at ON lumos.auth_logs (created_at DESC NULLS LAST);
+CREATE INDEX lumos_auth_logs_login ON lumos.auth_logs (login ASC NULLS LAST);
+CREATE INDEX lumos_auth_logs_fingerprint ON lumos.auth_logs (fingerprint ASC NULLS LAST);
+CREATE INDEX lumos_auth_logs_ip ON lumos.auth_logs (ip ASC NULLS LAST);
+
+------------------------------
+
+CREATE TABLE lumos.search_queries_new (
+    id BIGSERIAL,
+    query VARCHAR,
+    results_count integer,
+    query_norm varchar GENERATED ALWAYS AS (lower(regexp_replace(btrim(query), '\s+', ' ', 'g'))) STORED,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+SELECT lumos.create_monthly_partitions(
+  'search_queries_new',
+  (coalesce((SELECT min(created_at) FROM lumos.search_queries), NOW()) AT TIME ZONE 'UTC')::date,
+  ((NOW() + interval '2 months') AT TIME ZONE 'UTC')::date
+);
+
+INSERT INTO lumos.search_queries_new (id, query, results_count, created_at)
+SELECT id, query, results_count, created_at
+FROM lumos.search_queries;
+
+SELECT setval(pg_get_serial_sequence('lumos.search_queries_new', 'id'), coalesce((SELECT max(id) FROM lumos.search_queries), 0) + 1, false);
+
+DROP TABLE lumos.search_queries;
+ALTER TABLE lumos.search_queries_new RENAME TO search_queries;
+
+DO $$
+DECLARE
+  p record;
+BEGIN
+  FOR p IN
+    SELECT c.relname FROM pg_inherits i
+    JOIN pg_class c ON c.oid = i.inhrelid
+    JOIN pg_class pc ON pc.oid = i.inhparent
+    JOIN pg_namespace n ON n.oid = pc.relnamespace
+    WHERE n.nspname = 'lumos' AND pc.relname = 'search_queries'
+  LOOP
+    EXECUTE format('ALTER TABLE lumos.%I RENAME TO %I', p.relname, replace(p.relname, 'search_queries_new_', 'search_queries_'));
+  END LOOP;
+END $$;
+
+CREATE TABLE IF NOT EXISTS lumos.search_queries_default PARTITION OF lumos.search_queries DEFAULT;
+
+CREATE INDEX lumox_search_query_query ON lumos.search_queries (query ASC NULLS LAST);
+CREATE INDEX lumos_search_queries_created_at ON lumos.search_queries (created_at DESC);
+CREATE INDEX lumos_search_queries_query_norm_created_at ON lumos.search_queries (query_norm, created_at DESC);
+
+------------------------------
+
+CREATE TABLE IF NOT EXISTS lumos.auth_logs_daily (
+  day           date NOT NULL,
+  login         varchar NOT NULL,
+  events        integer NOT NULL,
+  fingerprints  integer NOT NULL,
+  ips           integer NOT NULL,
+  PRIMARY KEY (day, login)
+);
+
+CREATE TABLE IF NOT EXISTS lumos.search_queries_daily (
+  day           date NOT NULL,
+  query_norm    varchar NOT NULL,
+  searches      integer NOT NULL,
+  zero_results  integer NOT NULL,
+  PRIMARY KEY (day, query_norm)
+);
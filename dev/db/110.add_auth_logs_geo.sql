@@ -0,0 +1,5 @@
+ALTER TABLE lumos.auth_logs
+ADD COLUMN IF NOT EXISTS country varchar NOT NULL DEFAULT '';
+
+ALTER TABLE lumos.auth_logs
+ADD COLUMN IF NOT EXISTS city varchar NOT NULL DEFAULT '';
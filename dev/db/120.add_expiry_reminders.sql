@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS lumos.expiry_reminders (
+  user_id     uuid NOT NULL REFERENCES lumos.users (id) ON DELETE CASCADE,
+  expires_at  timestamptz NOT NULL,
+  channel     varchar NOT NULL,
+  sent_at     timestamptz NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, expires_at, channel)
+);
+
+CREATE INDEX IF NOT EXISTS lumos_users_expires_at
+ON lumos.users (expires_at);
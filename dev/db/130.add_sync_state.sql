@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS lumos.sync_state (
+  name       varchar PRIMARY KEY,
+  synced_at  timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS lumos_users_updated_at_id
+ON lumos.users (updated_at, id);
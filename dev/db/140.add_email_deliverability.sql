@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS lumos.email_deliverability (
+  email       varchar PRIMARY KEY,
+  status      varchar NOT NULL,
+  reason      varchar NOT NULL DEFAULT '',
+  updated_at  timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS lumos_email_deliverability_status_updated_at
+ON lumos.email_deliverability (status, updated_at DESC);
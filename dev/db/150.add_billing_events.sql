@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS lumos.billing_events (
+  id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  kind            varchar NOT NULL,
+  email           varchar NOT NULL,
+  provider        varchar NOT NULL DEFAULT '',
+  transaction_id  varchar NOT NULL DEFAULT '',
+  tariff          varchar NOT NULL DEFAULT '',
+  amount          numeric(12, 2) NOT NULL DEFAULT 0,
+  currency        varchar NOT NULL DEFAULT 'RUB',
+  created_at      timestamptz NOT NULL DEFAULT NOW()
+);
+
+-- webhook retries must not be counted twice
+CREATE UNIQUE INDEX IF NOT EXISTS lumos_billing_events_transaction
+ON lumos.billing_events (kind, provider, transaction_id)
+WHERE transaction_id <> '';
+
+CREATE INDEX IF NOT EXISTS lumos_billing_events_created_at
+ON lumos.billing_events (created_at DESC NULLS LAST);
+
+CREATE INDEX IF NOT EXISTS lumos_billing_events_email
+ON lumos.billing_events (email ASC NULLS LAST, created_at);
+
+-- customers existing before the ledger are recorded once with provider backfill,
+-- so they are not reported as new paid users, see domain.BillingProviderBackfill
+INSERT INTO lumos.billing_events (kind, email, provider, transaction_id, tariff, amount, created_at)
+SELECT 'payment', lower(trim(email)), 'backfill', id::text, tariff, 0, updated_at
+FROM lumos.users
+WHERE tariff <> 'Пробный период' AND (last_transaction_id <> '' OR last_sub_price > 0)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO lumos.billing_events (kind, email, provider, transaction_id, tariff, amount, created_at)
+SELECT 'trial', lower(trim(email)), 'backfill', id::text, tariff, 0, updated_at
+FROM lumos.users
+WHERE tariff = 'Пробный период'
+ON CONFLICT DO NOTHING;
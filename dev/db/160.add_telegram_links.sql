@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS lumos.telegram_links (
+  user_id    uuid PRIMARY KEY REFERENCES lumos.users (id) ON DELETE CASCADE,
+  chat_id    bigint NOT NULL UNIQUE,
+  username   varchar NOT NULL DEFAULT '',
+  linked_at  timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS lumos.telegram_link_codes (
+  code_hash   varchar PRIMARY KEY,
+  user_id     uuid NOT NULL REFERENCES lumos.users (id) ON DELETE CASCADE,
+  created_at  timestamptz NOT NULL DEFAULT NOW(),
+  expires_at  timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS lumos_telegram_link_codes_expires_at
+ON lumos.telegram_link_codes (expires_at);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS lumos.tg_action_nonces (
+  nonce    varchar PRIMARY KEY,
+  used_at  timestamptz NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- device and linked login queries match logins case insensitively,
+-- the index is created on every partition including the default one
+CREATE INDEX IF NOT EXISTS lumos_auth_logs_login_norm_created_at
+ON lumos.auth_logs (lower(trim(login)), created_at DESC);
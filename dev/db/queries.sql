@@ -1,8 +1,17 @@
 select * from lumos.search_queries;
 
+-- top queries for the last week
+select query_norm, count(*) as cnt
+from lumos.search_queries
+where created_at >= now() - interval '7 days'
+group by query_norm
+order by cnt desc
+limit 50;
 
-
-
-
-
-
+-- queries with no results for the last week
+select query_norm, count(*) as cnt
+from lumos.search_queries
+where created_at >= now() - interval '7 days' and results_count = 0
+group by query_norm
+order by cnt desc
+limit 50;
@@ -0,0 +1,22 @@
+package analytics
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+)
+
+type SearchQueryRepo interface {
+	Top(ctx context.Context, rng domain.DateRange, limit int) ([]domain.QueryStat, error)
+	ZeroResults(ctx context.Context, rng domain.DateRange, limit int) ([]domain.QueryStat, error)
+	Trending(ctx context.Context, rng domain.DateRange, minCount int, limit int) ([]domain.TrendingQuery, error)
+}
+
+type Service interface {
+	TopQueries(ctx context.Context, rng domain.DateRange, limit int) ([]domain.QueryStat, error)
+	TrendingQueries(ctx context.Context, rng domain.DateRange, limit int) ([]domain.TrendingQuery, error)
+	ZeroResultQueries(ctx context.Context, rng domain.DateRange, limit int) ([]domain.QueryStat, error)
+	// Digest builds text summary of searches in the range.
+	Digest(ctx context.Context, rng domain.DateRange) (string, error)
+	// Run sends weekly digest to admins until ctx is done.
+	Run(ctx context.Context) error
+}
@@ -0,0 +1,149 @@
+package analytics
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxLimit = 500
+
+type Config struct {
+	// DefaultLimit is used when requested limit is not positive.
+	DefaultLimit int
+	// TrendingMinCount filters out rare queries from trending ones.
+	TrendingMinCount int
+	// DigestEnabled turns on weekly digest sent on DigestWeekday at DigestHour local time.
+	DigestEnabled bool
+	DigestWeekday time.Weekday
+	DigestHour    int
+	DigestLimit   int
+}
+
+type service struct {
+	queries SearchQueryRepo
+	notif   notify.Service
+	cfg     Config
+}
+
+func NewAnalyticsService(
+	queries SearchQueryRepo,
+	notif notify.Service,
+	cfg Config,
+) Service {
+	return &service{
+		queries: queries,
+		notif:   notif,
+		cfg:     cfg,
+	}
+}
+
+func (s *service) TopQueries(ctx context.Context, rng domain.DateRange, limit int) ([]domain.QueryStat, error) {
+	return s.queries.Top(ctx, rng, s.limit(limit))
+}
+
+func (s *service) TrendingQueries(ctx context.Context, rng domain.DateRange, limit int) ([]domain.TrendingQuery, error) {
+	return s.queries.Trending(ctx, rng, s.cfg.TrendingMinCount, s.limit(limit))
+}
+
+func (s *service) ZeroResultQueries(ctx context.Context, rng domain.DateRange, limit int) ([]domain.QueryStat, error) {
+	return s.queries.ZeroResults(ctx, rng, s.limit(limit))
+}
+
+func (s *service) Digest(ctx context.Context, rng domain.DateRange) (string, error) {
+	top, err := s.queries.Top(ctx, rng, s.cfg.DigestLimit)
+	if err != nil {
+		return "", err
+	}
+	trending, err := s.queries.Trending(ctx, rng, s.cfg.TrendingMinCount, s.cfg.DigestLimit)
+	if err != nil {
+		return "", err
+	}
+	zero, err := s.queries.ZeroResults(ctx, rng, s.cfg.DigestLimit)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "search digest %s — %s\n", rng.From.Format("02.01.2006"), rng.To.Add(-time.Second).Format("02.01.2006"))
+
+	b.WriteString("\nTop queries:")
+	writeStats(&b, top)
+
+	b.WriteString("\n\nTrending queries:")
+	if len(trending) == 0 {
+		b.WriteString("\n—")
+	}
+	for i, q := range trending {
+		fmt.Fprintf(&b, "\n%d. %s — %d (было %d)", i+1, q.Query, q.Count, q.PrevCount)
+	}
+
+	b.WriteString("\n\nZero result queries:")
+	writeStats(&b, zero)
+
+	return b.String(), nil
+}
+
+func (s *service) Run(ctx context.Context) error {
+	if !s.cfg.DigestEnabled {
+		<-ctx.Done()
+		return nil
+	}
+
+	for {
+		next := nextWeekly(time.Now(), s.cfg.DigestWeekday, s.cfg.DigestHour)
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		digestCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		digest, err := s.Digest(digestCtx, domain.DateRange{From: next.AddDate(0, 0, -7), To: next})
+		cancel()
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"analytics.digest_failed",
+				"error building weekly digest",
+				notify.F("err", err),
+			))
+			continue
+		}
+
+		s.notif.Notify(notify.Info("analytics.search_digest", digest))
+	}
+}
+
+func (s *service) limit(limit int) int {
+	if limit <= 0 {
+		return s.cfg.DefaultLimit
+	}
+
+	return min(limit, maxLimit)
+}
+
+// nextWeekly returns the closest moment after now at the weekday and hour.
+func nextWeekly(now time.Time, weekday time.Weekday, hour int) time.Time {
+	days := (int(weekday) - int(now.Weekday()) + 7) % 7
+	next := time.Date(now.Year(), now.Month(), now.Day()+days, hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+
+	return next
+}
+
+func writeStats(b *strings.Builder, stats []domain.QueryStat) {
+	if len(stats) == 0 {
+		b.WriteString("\n—")
+	}
+	for i, q := range stats {
+		fmt.Fprintf(b, "\n%d. %s — %d", i+1, q.Query, q.Count)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build unit
+// +build unit
+
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextWeekly(t *testing.T) {
+	loc := time.UTC
+	// 2026-10-19 is monday
+	monday := time.Date(2026, 10, 19, 9, 0, 0, 0, loc)
+
+	require.Equal(t, time.Date(2026, 10, 19, 10, 0, 0, 0, loc), nextWeekly(monday, time.Monday, 10))
+	require.Equal(t, time.Date(2026, 10, 26, 10, 0, 0, 0, loc), nextWeekly(monday.Add(time.Hour), time.Monday, 10))
+	require.Equal(t, time.Date(2026, 10, 21, 10, 0, 0, 0, loc), nextWeekly(monday, time.Wednesday, 10))
+	require.Equal(t, time.Date(2026, 10, 25, 0, 0, 0, 0, loc), nextWeekly(monday, time.Sunday, 0))
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/pkg/authtoken"
+	"time"
+)
+
+type UserRepo interface {
+	ByEmail(ctx context.Context, email string) (*domain.User, error)
+	ByID(ctx context.Context, id string) (*domain.User, error)
+}
+
+type SeatRepo interface {
+	ByMemberEmail(ctx context.Context, email string) (*domain.Seat, error)
+}
+
+type TokenIssuer interface {
+	Issue(claims authtoken.Claims) (string, time.Time, error)
+}
+
+type MagicLinkRepo interface {
+	Create(ctx context.Context, link domain.MagicLink) error
+	CountByEmailSince(ctx context.Context, email string, since time.Time) (int, error)
+	CountByIPSince(ctx context.Context, ip string, since time.Time) (int, error)
+	Consume(ctx context.Context, tokenHash string) (*domain.MagicLink, error)
+}
+
+type AuthLogRepo interface {
+	Save(ctx context.Context, log domain.AuthLog) error
+}
+
+type Mailer interface {
+	SendMagicLink(ctx context.Context, email string, link string) error
+}
+
+type Service interface {
+	// IssueToken issues access token for the user or team member with the email,
+	// the caller is responsible for verifying the email ownership.
+	IssueToken(ctx context.Context, email string, projectID string) (*domain.AccessToken, error)
+	// RefreshToken issues a new token for still valid claims.
+	RefreshToken(ctx context.Context, claims authtoken.Claims) (*domain.AccessToken, error)
+	// RequestMagicLink emails one-time login link, unknown emails are silently skipped.
+	RequestMagicLink(ctx context.Context, email string, projectID string, ip string) error
+	// VerifyMagicLink consumes the link, records the login and issues access token.
+	VerifyMagicLink(ctx context.Context, token string, login domain.AuthLog) (*domain.AccessToken, error)
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const magicLinkSendTimeout = 30 * time.Second
+
+func (s *service) RequestMagicLink(ctx context.Context, email string, projectID string, ip string) error {
+	if _, ok := s.projects.ByID(projectID); !ok {
+		return ErrInvalidProjectID
+	}
+
+	emailNorm := s.normalizeStr(email)
+	if emailNorm == "" {
+		return ErrUnknownUser
+	}
+
+	since := time.Now().Add(-s.magicLinkCfg.RateWindow)
+
+	sentToEmail, err := s.magicLinks.CountByEmailSince(ctx, emailNorm, since)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.magic_link_failed",
+			"error counting links for email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if sentToEmail >= s.magicLinkCfg.MaxPerEmail {
+		return ErrRateLimited
+	}
+
+	if ip != "" {
+		sentFromIP, err := s.magicLinks.CountByIPSince(ctx, ip, since)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"auth.magic_link_failed",
+				"error counting links for ip",
+				notify.F("ip", ip),
+				notify.F("err", err),
+			))
+			return err
+		}
+		if sentFromIP >= s.magicLinkCfg.MaxPerIP {
+			return ErrRateLimited
+		}
+	}
+
+	token, err := newMagicLinkToken()
+	if err != nil {
+		return err
+	}
+
+	// link is saved even for unknown emails, so the requests are counted by rate limits
+	err = s.magicLinks.Create(ctx, domain.MagicLink{
+		ID:        uuid.New().String(),
+		TokenHash: hashMagicLinkToken(token),
+		Email:     emailNorm,
+		ProjectID: strings.TrimSpace(projectID),
+		IP:        ip,
+		ExpiresAt: time.Now().Add(s.magicLinkCfg.TTL),
+	})
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.magic_link_failed",
+			"error saving link",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return err
+	}
+
+	// membership lookup and sending happen in background, so unknown emails get the same
+	// response in the same time and the endpoint can not be used to enumerate members
+	go s.sendMagicLink(context.WithoutCancel(ctx), emailNorm, token)
+
+	return nil
+}
+
+func (s *service) sendMagicLink(ctx context.Context, emailNorm string, token string) {
+	ctx, cancel := context.WithTimeout(ctx, magicLinkSendTimeout)
+	defer cancel()
+
+	known, err := s.known(ctx, emailNorm)
+	if err != nil || !known {
+		return
+	}
+
+	err = s.mailer.SendMagicLink(ctx, emailNorm, s.magicLinkURL(token))
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.magic_link_email_failed",
+			"error sending magic link email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+	}
+}
+
+func (s *service) VerifyMagicLink(ctx context.Context, token string, login domain.AuthLog) (*domain.AccessToken, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrInvalidMagicLink
+	}
+
+	link, err := s.magicLinks.Consume(ctx, hashMagicLinkToken(token))
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.magic_link_failed",
+			"error consuming link",
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	accessToken, err := s.IssueToken(ctx, link.Email, link.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	login.Login = link.Email
+	err = s.authLogs.Save(ctx, login)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.magic_link_failed",
+			"error saving auth log",
+			notify.F("email", link.Email),
+			notify.F("err", err),
+		))
+	}
+
+	return accessToken, nil
+}
+
+func (s *service) known(ctx context.Context, emailNorm string) (bool, error) {
+	user, err := s.users.ByEmail(ctx, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.magic_link_failed",
+			"error fetching user by email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return false, err
+	}
+	if user != nil {
+		return true, nil
+	}
+
+	seat, err := s.seats.ByMemberEmail(ctx, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.magic_link_failed",
+			"error fetching seat by email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return false, err
+	}
+
+	return seat != nil, nil
+}
+
+func (s *service) magicLinkURL(token string) string {
+	sep := "?"
+	if strings.Contains(s.magicLinkCfg.BaseURL, "?") {
+		sep = "&"
+	}
+
+	return s.magicLinkCfg.BaseURL + sep + "token=" + url.QueryEscape(token)
+}
+
+func newMagicLinkToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// only token hash is stored, so leaked table rows can not be used to log in
+func hashMagicLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
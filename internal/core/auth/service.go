@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"doctormakarhina/lumos/internal/core/projects"
+	"doctormakarhina/lumos/internal/pkg/authtoken"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidProjectID = errors.New("invalid project ID")
+	ErrUnknownUser      = errors.New("unknown user")
+	ErrInvalidMagicLink = errors.New("invalid or expired magic link")
+	ErrRateLimited      = errors.New("too many magic link requests")
+)
+
+type MagicLinkConfig struct {
+	// BaseURL is a login page, token is passed to it in the token query param
+	BaseURL     string
+	TTL         time.Duration
+	RateWindow  time.Duration
+	MaxPerEmail int
+	MaxPerIP    int
+}
+
+type service struct {
+	users        UserRepo
+	seats        SeatRepo
+	tokens       TokenIssuer
+	magicLinks   MagicLinkRepo
+	authLogs     AuthLogRepo
+	mailer       Mailer
+	notif        notify.Service
+	projects     *projects.Registry
+	magicLinkCfg MagicLinkConfig
+}
+
+func NewAuthService(
+	users UserRepo,
+	seats SeatRepo,
+	tokens TokenIssuer,
+	magicLinks MagicLinkRepo,
+	authLogs AuthLogRepo,
+	mailer Mailer,
+	notif notify.Service,
+	projects *projects.Registry,
+	magicLinkCfg MagicLinkConfig,
+) (Service, error) {
+	if magicLinkCfg.BaseURL == "" {
+		return nil, errors.New("magic link base url is required")
+	}
+	if magicLinkCfg.TTL <= 0 || magicLinkCfg.RateWindow <= 0 {
+		return nil, errors.New("magic link ttl and rate window must be positive")
+	}
+	if magicLinkCfg.MaxPerEmail <= 0 || magicLinkCfg.MaxPerIP <= 0 {
+		return nil, errors.New("magic link rate limits must be positive")
+	}
+
+	return &service{
+		users:        users,
+		seats:        seats,
+		tokens:       tokens,
+		magicLinks:   magicLinks,
+		authLogs:     authLogs,
+		mailer:       mailer,
+		notif:        notif,
+		projects:     projects,
+		magicLinkCfg: magicLinkCfg,
+	}, nil
+}
+
+func (s *service) IssueToken(ctx context.Context, email string, projectID string) (*domain.AccessToken, error) {
+	if _, ok := s.projects.ByID(projectID); !ok {
+		return nil, ErrInvalidProjectID
+	}
+
+	emailNorm := s.normalizeStr(email)
+	if emailNorm == "" {
+		return nil, ErrUnknownUser
+	}
+
+	user, err := s.users.ByEmail(ctx, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.issue_token_failed",
+			"error fetching user by email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+
+	subject := ""
+	if user != nil {
+		subject = user.ID
+	} else {
+		// team members may have no own account, token is bound to the member email then
+		seat, err := s.seats.ByMemberEmail(ctx, emailNorm)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"auth.issue_token_failed",
+				"error fetching seat by email",
+				notify.F("email", emailNorm),
+				notify.F("err", err),
+			))
+			return nil, err
+		}
+		if seat == nil {
+			return nil, ErrUnknownUser
+		}
+	}
+
+	return s.issue(subject, emailNorm, projectID)
+}
+
+func (s *service) RefreshToken(ctx context.Context, claims authtoken.Claims) (*domain.AccessToken, error) {
+	if claims.Subject == "" {
+		return s.IssueToken(ctx, claims.Email, claims.ProjectID)
+	}
+
+	user, err := s.users.ByID(ctx, claims.Subject)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"auth.refresh_token_failed",
+			"error fetching user by id",
+			notify.F("user_id", claims.Subject),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUnknownUser
+	}
+
+	return s.issue(user.ID, user.Email, claims.ProjectID)
+}
+
+func (s *service) issue(subject string, email string, projectID string) (*domain.AccessToken, error) {
+	token, expiresAt, err := s.tokens.Issue(authtoken.Claims{
+		Subject:   subject,
+		Email:     email,
+		ProjectID: strings.TrimSpace(projectID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AccessToken{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+func (s *service) normalizeStr(str string) string {
+	return strings.ToLower(strings.TrimSpace(str))
+}
@@ -0,0 +1,31 @@
+package contacts
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+)
+
+type UserRepo interface {
+	UpdatedAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]domain.User, error)
+}
+
+type SyncStateRepo interface {
+	SyncedAt(ctx context.Context, name string) (time.Time, error)
+	SetSyncedAt(ctx context.Context, name string, syncedAt time.Time) error
+}
+
+// Exporter pushes user fields to the email provider contacts, rejected
+// describes contacts refused by the provider (e.g. invalid email).
+type Exporter interface {
+	ExportContacts(ctx context.Context, users []domain.User) (rejected []string, err error)
+}
+
+type Service interface {
+	// Sync exports users changed since the previous sync.
+	Sync(ctx context.Context) (int, error)
+	// Backfill exports all users.
+	Backfill(ctx context.Context) (int, error)
+	// Run syncs periodically until ctx is done.
+	Run(ctx context.Context) error
+}
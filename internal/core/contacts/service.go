@@ -0,0 +1,142 @@
+package contacts
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const syncName = "email_contacts"
+
+// overlap re-exports users updated right before the previous sync, updated_at
+// is set at transaction start, so late commits may land behind the watermark.
+const overlap = time.Minute
+
+const maxRejectedShown = 20
+
+type Config struct {
+	BatchSize int
+	// Interval between syncs, 0 disables periodic sync.
+	Interval time.Duration
+}
+
+type service struct {
+	users    UserRepo
+	state    SyncStateRepo
+	exporter Exporter
+	notif    notify.Service
+	cfg      Config
+}
+
+func NewContactsService(
+	users UserRepo,
+	state SyncStateRepo,
+	exporter Exporter,
+	notif notify.Service,
+	cfg Config,
+) Service {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+
+	return &service{
+		users:    users,
+		state:    state,
+		exporter: exporter,
+		notif:    notif,
+		cfg:      cfg,
+	}
+}
+
+func (s *service) Sync(ctx context.Context) (int, error) {
+	syncedAt, err := s.state.SyncedAt(ctx, syncName)
+	if err != nil {
+		return 0, err
+	}
+	if !syncedAt.IsZero() {
+		syncedAt = syncedAt.Add(-overlap)
+	}
+
+	return s.export(ctx, syncedAt)
+}
+
+func (s *service) Backfill(ctx context.Context) (int, error) {
+	return s.export(ctx, time.Time{})
+}
+
+func (s *service) export(ctx context.Context, since time.Time) (int, error) {
+	var (
+		afterTime = since
+		afterID   string
+		total     int
+		rejected  []string
+	)
+
+	for {
+		users, err := s.users.UpdatedAfter(ctx, afterTime, afterID, s.cfg.BatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(users) == 0 {
+			break
+		}
+
+		batchRejected, err := s.exporter.ExportContacts(ctx, users)
+		if err != nil {
+			return total, err
+		}
+		rejected = append(rejected, batchRejected...)
+		total += len(users)
+
+		last := users[len(users)-1]
+		afterTime, afterID = last.UpdatedAt, last.ID
+
+		// watermark moves only forward with completed batches
+		if err := s.state.SetSyncedAt(ctx, syncName, afterTime); err != nil {
+			return total, err
+		}
+
+		if len(users) < s.cfg.BatchSize {
+			break
+		}
+	}
+
+	if len(rejected) > 0 {
+		shown := rejected[:min(len(rejected), maxRejectedShown)]
+		s.notif.Notify(notify.Warning(
+			"contacts.rejected",
+			fmt.Sprintf("%d contacts rejected by email provider:\n%s", len(rejected), strings.Join(shown, "\n")),
+		))
+	}
+
+	return total, nil
+}
+
+func (s *service) Run(ctx context.Context) error {
+	if s.cfg.Interval <= 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := s.Sync(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.notif.Notify(notify.Error(
+				"contacts.sync_failed",
+				"error syncing contacts",
+				notify.F("err", err),
+			))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
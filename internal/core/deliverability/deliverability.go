@@ -0,0 +1,25 @@
+package deliverability
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+)
+
+type Repo interface {
+	ByEmail(ctx context.Context, email string) (*domain.EmailDeliverability, error)
+	Save(ctx context.Context, d domain.EmailDeliverability) error
+	Suppressed(ctx context.Context, limit int) ([]domain.EmailDeliverability, error)
+}
+
+type UserRepo interface {
+	ByEmail(ctx context.Context, email string) (*domain.User, error)
+}
+
+type Service interface {
+	// HandleEvents updates deliverability state of addresses from provider events.
+	HandleEvents(ctx context.Context, events []domain.EmailEvent) error
+	// Suppressed reports whether lifecycle emails must not be sent to the address.
+	Suppressed(ctx context.Context, email string) (bool, error)
+	// Invalid lists bounced and complained addresses, latest first.
+	Invalid(ctx context.Context, limit int) ([]domain.EmailDeliverability, error)
+}
@@ -0,0 +1,121 @@
+package deliverability
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"strings"
+)
+
+const maxInvalidLimit = 500
+
+type service struct {
+	repo  Repo
+	users UserRepo
+	notif notify.Service
+}
+
+func NewDeliverabilityService(repo Repo, users UserRepo, notif notify.Service) Service {
+	return &service{repo: repo, users: users, notif: notif}
+}
+
+func (s *service) HandleEvents(ctx context.Context, events []domain.EmailEvent) error {
+	for _, e := range events {
+		e.Email = strings.ToLower(strings.TrimSpace(e.Email))
+		if e.Email == "" {
+			continue
+		}
+
+		err := s.handle(ctx, e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *service) handle(ctx context.Context, e domain.EmailEvent) error {
+	current, err := s.repo.ByEmail(ctx, e.Email)
+	if err != nil {
+		return err
+	}
+	if current != nil && e.At.Before(current.UpdatedAt) {
+		return nil
+	}
+	if !transition(current, e.Status) {
+		return nil
+	}
+
+	err = s.repo.Save(ctx, domain.EmailDeliverability{
+		Email:     e.Email,
+		Status:    e.Status,
+		Reason:    e.Reason,
+		UpdatedAt: e.At,
+	})
+	if err != nil {
+		return err
+	}
+
+	if e.Status == domain.EmailStatusBounced || e.Status == domain.EmailStatusComplained {
+		s.notifyInvalid(ctx, e)
+	}
+
+	return nil
+}
+
+// transition reports whether the event changes stored state, successful
+// delivery only clears a bounce, unsubscribe never overrides a suppression.
+func transition(current *domain.EmailDeliverability, status string) bool {
+	if current != nil && current.Status == status {
+		return false
+	}
+
+	switch status {
+	case domain.EmailStatusOK:
+		return current != nil && current.Status == domain.EmailStatusBounced
+	case domain.EmailStatusUnsubscribed:
+		return current == nil || !current.Suppressed()
+	case domain.EmailStatusBounced, domain.EmailStatusComplained:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *service) notifyInvalid(ctx context.Context, e domain.EmailEvent) {
+	owner := "not a user"
+	user, err := s.users.ByEmail(ctx, e.Email)
+	if err != nil {
+		owner = fmt.Sprintf("user lookup failed: %v", err)
+	} else if user != nil {
+		owner = fmt.Sprintf("user, tariff: %s, expires at: %s", user.Tariff, user.ExpiresAt.Format("02.01.2006"))
+	}
+
+	s.notif.Notify(notify.Warning(
+		"deliverability.address_suppressed",
+		"lifecycle emails to the address are suppressed",
+		notify.F("email", e.Email),
+		notify.F("status", e.Status),
+		notify.F("reason", e.Reason),
+		notify.F("owner", owner),
+	))
+}
+
+func (s *service) Suppressed(ctx context.Context, email string) (bool, error) {
+	d, err := s.repo.ByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return false, err
+	}
+
+	return d != nil && d.Suppressed(), nil
+}
+
+func (s *service) Invalid(ctx context.Context, limit int) ([]domain.EmailDeliverability, error) {
+	if limit <= 0 || limit > maxInvalidLimit {
+		limit = maxInvalidLimit
+	}
+
+	return s.repo.Suppressed(ctx, limit)
+}
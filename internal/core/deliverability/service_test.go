@@ -0,0 +1,43 @@
+//go:build unit
+// +build unit
+
+package deliverability
+
+import (
+	"doctormakarhina/lumos/internal/core/domain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransition(t *testing.T) {
+	state := func(status string) *domain.EmailDeliverability {
+		return &domain.EmailDeliverability{Status: status}
+	}
+
+	cases := []struct {
+		name    string
+		current *domain.EmailDeliverability
+		status  string
+		want    bool
+	}{
+		{"delivery without state is not stored", nil, domain.EmailStatusOK, false},
+		{"delivery clears bounce", state(domain.EmailStatusBounced), domain.EmailStatusOK, true},
+		{"delivery keeps complaint", state(domain.EmailStatusComplained), domain.EmailStatusOK, false},
+		{"delivery keeps unsubscribe", state(domain.EmailStatusUnsubscribed), domain.EmailStatusOK, false},
+		{"first bounce", nil, domain.EmailStatusBounced, true},
+		{"repeated bounce", state(domain.EmailStatusBounced), domain.EmailStatusBounced, false},
+		{"complaint overrides unsubscribe", state(domain.EmailStatusUnsubscribed), domain.EmailStatusComplained, true},
+		{"first complaint", nil, domain.EmailStatusComplained, true},
+		{"spam folder delivery clears bounce", state(domain.EmailStatusBounced), domain.EmailStatusOK, true},
+		{"unsubscribe", nil, domain.EmailStatusUnsubscribed, true},
+		{"unsubscribe keeps bounce", state(domain.EmailStatusBounced), domain.EmailStatusUnsubscribed, false},
+		{"unknown status", nil, "soft", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.want, transition(c.current, c.status))
+		})
+	}
+}
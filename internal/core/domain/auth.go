@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+type AccessToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type MagicLink struct {
+	ID        string     `db:"id" json:"id"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	Email     string     `db:"email" json:"email"`
+	ProjectID string     `db:"project_id" json:"project_id"`
+	IP        string     `db:"ip" json:"ip"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at"`
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+const (
+	BillingEventTrial        string = "trial"
+	BillingEventPayment      string = "payment"
+	BillingEventRenewal      string = "renewal"
+	BillingEventGift         string = "gift"
+	BillingEventCancellation string = "cancellation"
+)
+
+// BillingProviderBackfill marks events recorded from users table when the ledger was created,
+// they are history for new paid and conversion checks and are not counted in reports themselves.
+const BillingProviderBackfill string = "backfill"
+
+// CurrencyRUB is the only settlement currency, providers report amounts in it.
+const CurrencyRUB string = "RUB"
+
+// BillingEvent is a ledger entry used for business reports. TransactionID dedupes
+// webhook retries, cancellations use subscription id as it.
+type BillingEvent struct {
+	Kind          string    `db:"kind"`
+	Email         string    `db:"email"`
+	Provider      string    `db:"provider"`
+	TransactionID string    `db:"transaction_id"`
+	Tariff        string    `db:"tariff"`
+	Amount        float32   `db:"amount"`
+	Currency      string    `db:"currency"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+type TariffCount struct {
+	Tariff string `db:"tariff" json:"tariff"`
+	Count  int    `db:"count" json:"count"`
+}
+
+type Revenue struct {
+	Provider string  `db:"provider" json:"provider"`
+	Currency string  `db:"currency" json:"currency"`
+	Amount   float64 `db:"amount" json:"amount"`
+	Payments int     `db:"payments" json:"payments"`
+}
+
+type BusinessReport struct {
+	Range     DateRange `json:"range"`
+	NewTrials int       `json:"new_trials"`
+	// ConvertedTrials is how many of NewTrials users have paid since
+	ConvertedTrials int           `json:"converted_trials"`
+	NewPaid         []TariffCount `json:"new_paid"`
+	Renewals        int           `json:"renewals"`
+	Cancellations   int           `json:"cancellations"`
+	Revenue         []Revenue     `json:"revenue"`
+	// ActiveSubscribers are users with not expired paid tariff at the report time
+	ActiveSubscribers int `json:"active_subscribers"`
+	ActiveAutopay     int `json:"active_autopay"`
+}
+
+// Conversion is a share of trials converted to paid, 0 without trials.
+func (r BusinessReport) Conversion() float64 {
+	if r.NewTrials == 0 {
+		return 0
+	}
+	return float64(r.ConvertedTrials) / float64(r.NewTrials)
+}
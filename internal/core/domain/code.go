@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// codeAlphabet excludes look-alike symbols (0/O, 1/I), its length divides 256
+// so random bytes map to symbols without bias.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// newCode generates human friendly code of n symbols grouped by 4, e.g. XXXX-XXXX.
+func newCode(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+
+	code := make([]byte, 0, n+n/4)
+	for i, b := range buf {
+		if i > 0 && i%4 == 0 {
+			code = append(code, '-')
+		}
+		code = append(code, codeAlphabet[int(b)%len(codeAlphabet)])
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	EmailStatusOK           string = "ok"
+	EmailStatusBounced      string = "bounced"
+	EmailStatusComplained   string = "complained"
+	EmailStatusUnsubscribed string = "unsubscribed"
+)
+
+// EmailEvent is a delivery or subscription event reported by email provider.
+type EmailEvent struct {
+	Email  string
+	Status string
+	// Reason is provider-specific status, e.g. err_user_unknown
+	Reason string
+	At     time.Time
+}
+
+type EmailDeliverability struct {
+	Email     string    `db:"email" json:"email"`
+	Status    string    `db:"status" json:"status"`
+	Reason    string    `db:"reason" json:"reason"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Suppressed reports whether lifecycle emails must not be sent to the address.
+func (d *EmailDeliverability) Suppressed() bool {
+	return d.Status == EmailStatusBounced || d.Status == EmailStatusComplained
+}
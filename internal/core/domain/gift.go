@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	PaymentProviderProdamus      string = "prodamus"
+	PaymentProviderCloudPayments string = "cloudpayments"
+)
+
+type Gift struct {
+	ID             string     `db:"id" json:"id"`
+	Code           string     `db:"code" json:"code"`
+	PayerEmail     string     `db:"payer_email" json:"payer_email"`
+	RecipientEmail string     `db:"recipient_email" json:"recipient_email"`
+	Tariff         string     `db:"tariff" json:"tariff"`
+	Price          float32    `db:"price" json:"price"`
+	Provider       string     `db:"provider" json:"provider"`
+	TransactionID  string     `db:"transaction_id" json:"transaction_id"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	RedeemedAt     *time.Time `db:"redeemed_at" json:"redeemed_at"`
+	RedeemedBy     string     `db:"redeemed_by" json:"redeemed_by"`
+}
+
+func (g *Gift) Redeemed() bool {
+	return g.RedeemedAt != nil
+}
+
+// NewGiftCode generates redemption code in form XXXX-XXXX-XXXX.
+func NewGiftCode() (string, error) {
+	return newCode(12)
+}
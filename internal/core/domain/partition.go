@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// Partition is a monthly partition of a telemetry table covering [From, To).
+type Partition struct {
+	Table string    `json:"table"`
+	Name  string    `json:"name"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+}
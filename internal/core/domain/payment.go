@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"time"
+)
+
+// Payment is an accepted charge, used to render receipts.
+type Payment struct {
+	Provider      string
+	TransactionID string
+	Tariff        string
+	Amount        float32
+	PaidAt        time.Time
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Project is a course site served by the api.
+type Project struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// AllowedTariffs are user tariffs granting access to the project, empty allows any tariff.
+	AllowedTariffs []string `json:"allowed_tariffs"`
+	CorsOrigins    []string `json:"cors_origins"`
+	// Default project access is stored on the user itself, other projects use entitlements.
+	Default bool `json:"default"`
+}
+
+func (p *Project) AllowsTariff(tariff string) bool {
+	if len(p.AllowedTariffs) == 0 {
+		return true
+	}
+
+	norm := strings.ToLower(strings.TrimSpace(tariff))
+	return slices.ContainsFunc(p.AllowedTariffs, func(t string) bool {
+		return strings.ToLower(strings.TrimSpace(t)) == norm
+	})
+}
+
+// Entitlement is user access to a non default project.
+type Entitlement struct {
+	UserID         string    `db:"user_id" json:"user_id"`
+	ProjectID      string    `db:"project_id" json:"project_id"`
+	Tariff         string    `db:"tariff" json:"tariff"`
+	ExpiresAt      time.Time `db:"expires_at" json:"expires_at"`
+	SubscriptionID string    `db:"subscription_id" json:"subscription_id"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (e *Entitlement) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+const (
+	ReferralSourceTrial   string = "trial"
+	ReferralSourcePayment string = "payment"
+)
+
+const (
+	ReferralStatusPending  string = "pending"
+	ReferralStatusRewarded string = "rewarded"
+	ReferralStatusRejected string = "rejected"
+)
+
+type Referral struct {
+	ID           string     `db:"id" json:"id"`
+	ReferrerID   string     `db:"referrer_id" json:"referrer_id"`
+	RefereeEmail string     `db:"referee_email" json:"referee_email"`
+	Source       string     `db:"source" json:"source"`
+	Status       string     `db:"status" json:"status"`
+	Reason       string     `db:"reason" json:"reason"`
+	RewardDays   int        `db:"reward_days" json:"reward_days"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	RewardedAt   *time.Time `db:"rewarded_at" json:"rewarded_at"`
+}
+
+// NewReferralCode generates referral code in form XXXX-XXXX.
+func NewReferralCode() (string, error) {
+	return newCode(8)
+}
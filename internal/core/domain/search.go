@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// DateRange is a half open [From, To) time interval.
+type DateRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Previous returns range of the same length right before the current one.
+func (r DateRange) Previous() DateRange {
+	return DateRange{From: r.From.Add(-r.To.Sub(r.From)), To: r.From}
+}
+
+// SearchQuery is a raw search query sent by the client.
+type SearchQuery struct {
+	Query string `db:"query" json:"query"`
+	// ResultsCount is nil for clients which do not report it.
+	ResultsCount *int      `db:"results_count" json:"results_count"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// QueryStat is a normalized search query with amount of searches.
+type QueryStat struct {
+	Query string `db:"query" json:"query"`
+	Count int    `db:"count" json:"count"`
+}
+
+// TrendingQuery is a search query which became more popular than in the previous period.
+type TrendingQuery struct {
+	Query     string `db:"query" json:"query"`
+	Count     int    `db:"count" json:"count"`
+	PrevCount int    `db:"prev_count" json:"prev_count"`
+}
+
+// Growth is count to previous count ratio, new queries are treated as seen once before.
+func (q TrendingQuery) Growth() float64 {
+	return float64(q.Count) / float64(q.PrevCount+1)
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+type Seat struct {
+	ID          string    `db:"id" json:"id"`
+	OwnerID     string    `db:"owner_id" json:"owner_id"`
+	MemberEmail string    `db:"member_email" json:"member_email"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// SeatUsage is a summary of seats taken under one owner subscription.
+type SeatUsage struct {
+	OwnerID    string    `db:"owner_id" json:"owner_id"`
+	OwnerEmail string    `db:"owner_email" json:"owner_email"`
+	Tariff     string    `db:"tariff" json:"tariff"`
+	ExpiresAt  time.Time `db:"expires_at" json:"expires_at"`
+	Used       int       `db:"used" json:"used"`
+	Limit      int       `db:"-" json:"limit"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// DeviceStats is a summary of distinct devices a login was used from in auth telemetry.
+type DeviceStats struct {
+	Login        string    `db:"login" json:"login"`
+	Fingerprints int       `db:"fingerprints" json:"fingerprints"`
+	IPs          int       `db:"ips" json:"ips"`
+	UserAgents   int       `db:"user_agents" json:"user_agents"`
+	LastSeenAt   time.Time `db:"last_seen_at" json:"last_seen_at"`
+}
+
+// SharingScore is a likelihood of the account being shared among several people.
+type SharingScore struct {
+	DeviceStats
+	Tariff    string    `json:"tariff"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Score     float64   `json:"score"`
+}
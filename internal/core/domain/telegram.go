@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// TelegramLink binds member telegram chat to the user, a user has a single chat.
+type TelegramLink struct {
+	UserID   string    `db:"user_id" json:"user_id"`
+	ChatID   int64     `db:"chat_id" json:"chat_id"`
+	Username string    `db:"username" json:"username"`
+	LinkedAt time.Time `db:"linked_at" json:"linked_at"`
+}
+
+// TelegramLinkCode is a one-time code the user sends to the member bot, only its hash is stored.
+type TelegramLinkCode struct {
+	CodeHash  string    `db:"code_hash"`
+	UserID    string    `db:"user_id"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// NewTelegramLinkCode generates link code in form XXXX-XXXX.
+func NewTelegramLinkCode() (string, error) {
+	return newCode(8)
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// LinkedLogin is another login seen from the same device fingerprint or ip
+// address in auth telemetry.
+type LinkedLogin struct {
+	Login              string    `db:"login" json:"login"`
+	SharedFingerprints int       `db:"shared_fingerprints" json:"shared_fingerprints"`
+	SharedIPs          int       `db:"shared_ips" json:"shared_ips"`
+	LastSeenAt         time.Time `db:"last_seen_at" json:"last_seen_at"`
+}
+
+// LinkedUser is a registered user linked to another login through auth telemetry.
+type LinkedUser struct {
+	Email              string    `json:"email"`
+	Tariff             string    `json:"tariff"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	SharedFingerprints int       `json:"shared_fingerprints"`
+	SharedIPs          int       `json:"shared_ips"`
+}
+
+type AuthLog struct {
+	Login           string    `db:"login" json:"login"`
+	IP              string    `db:"ip" json:"ip"`
+	UserAgent       string    `db:"useragent" json:"useragent"`
+	Fingerprint     string    `db:"fingerprint" json:"fingerprint"`
+	ConfidenceScore string    `db:"confidencescore" json:"confidence_score"`
+	Country         string    `db:"country" json:"country"`
+	City            string    `db:"city" json:"city"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+const (
+	TrialActionAllow   string = "allow"
+	TrialActionReview  string = "review"
+	TrialActionShorten string = "shorten"
+	TrialActionDeny    string = "deny"
+)
+
+// TrialActionWeight orders trial actions by strictness, unknown actions are treated as allow.
+func TrialActionWeight(action string) int {
+	switch action {
+	case TrialActionReview:
+		return 1
+	case TrialActionShorten:
+		return 2
+	case TrialActionDeny:
+		return 3
+	}
+
+	return 0
+}
+
+// TrialFlag is a record of suspicious trial registration kept for admin review.
+type TrialFlag struct {
+	ID           string    `db:"id" json:"id"`
+	Email        string    `db:"email" json:"email"`
+	Action       string    `db:"action" json:"action"`
+	Reasons      string    `db:"reasons" json:"reasons"`
+	LinkedLogins string    `db:"linked_logins" json:"linked_logins"`
+	Fingerprint  string    `db:"fingerprint" json:"fingerprint"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
@@ -35,6 +35,7 @@ type User struct {
 	SubscriptionStatus string    `db:"subscription_status" json:"subscription_status"`
 	LastSubPrice       float32   `db:"last_sub_price" json:"last_sub_price"`
 	LastTransactionID  string    `db:"last_transaction_id" json:"last_transaction_id"`
+	UpdatedAt          time.Time `db:"updated_at" json:"updated_at"`
 }
 
 func (u *User) SubExpired(now time.Time) bool {
@@ -67,6 +68,21 @@ func (u *User) NewSubEndedAt(now time.Time, tarrif string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("tariff %s is unknown or can not be used with subscription", tarrif)
 }
 
+// TariffMonths returns subscription length in months for the paid tariffs,
+// 0 for trial, unlimited and unknown tariffs.
+func TariffMonths(tariff string) int {
+	switch tariff {
+	case UserTariff1Month:
+		return 1
+	case UserTariff3Months:
+		return 3
+	case UserTariff6Months:
+		return 6
+	}
+
+	return 0
+}
+
 func (u *User) StatusInfo(now time.Time) string {
 	if u.Tariff == UserTariffUnlimited {
 		return "Тариф: " + u.Tariff
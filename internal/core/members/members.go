@@ -0,0 +1,75 @@
+package members
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidCode = errors.New("link code is invalid or expired")
+	ErrNotLinked   = errors.New("telegram chat is not linked")
+)
+
+type UserRepo interface {
+	ByID(ctx context.Context, id string) (*domain.User, error)
+}
+
+type LinkRepo interface {
+	CreateCode(ctx context.Context, code domain.TelegramLinkCode) error
+	ConsumeCode(ctx context.Context, codeHash string) (*domain.TelegramLinkCode, error)
+	Save(ctx context.Context, link domain.TelegramLink) error
+	DeleteByChatID(ctx context.Context, chatID int64) (bool, error)
+	ByChatID(ctx context.Context, chatID int64) (*domain.TelegramLink, error)
+	ByUserID(ctx context.Context, userID string) (*domain.TelegramLink, error)
+}
+
+// Button opens the URL, e.g. renew or cancel payment pages.
+type Button struct {
+	Label string
+	URL   string
+}
+
+// Message is a plain text message to the member chat.
+type Message struct {
+	Text    string
+	Buttons []Button
+}
+
+// Messenger delivers messages to member chats.
+type Messenger interface {
+	SendMessage(ctx context.Context, chatID int64, msg Message) error
+}
+
+type LinkCode struct {
+	Code string `json:"code"`
+	// URL opens the bot with the code, empty when bot username is not configured
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Service interface {
+	// IssueCode creates one-time code linking telegram chat to the user.
+	IssueCode(ctx context.Context, user domain.User) (*LinkCode, error)
+	// Link consumes the code and binds the chat to its user.
+	Link(ctx context.Context, code string, chatID int64, username string) (*domain.User, error)
+	Unlink(ctx context.Context, chatID int64) error
+	// Status describes access of the user linked to the chat.
+	Status(ctx context.Context, chatID int64) (Message, error)
+	// SendExpiryReminder implements reminders.Sender, users without linked chat are skipped.
+	SendExpiryReminder(ctx context.Context, user domain.User) error
+	// SendPaymentReceipt notifies linked chat of the user about accepted payment.
+	SendPaymentReceipt(ctx context.Context, user domain.User, payment domain.Payment) error
+}
+
+type noopMessenger struct{}
+
+// NewNoopMessenger is used when member bot is disabled.
+func NewNoopMessenger() Messenger {
+	return noopMessenger{}
+}
+
+func (noopMessenger) SendMessage(_ context.Context, _ int64, _ Message) error {
+	return nil
+}
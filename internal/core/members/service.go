@@ -0,0 +1,203 @@
+package members
+
+import (
+	"context"
+	"crypto/sha256"
+	"doctormakarhina/lumos/internal/core/domain"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	CodeTTL time.Duration
+	// BotUsername is used to build t.me deep links with the code
+	BotUsername string
+	// RenewURL is the payment page
+	RenewURL string
+	// CancelURL is prefix of autopayment cancel page, subscription id is appended to it
+	CancelURL string
+	// Location is used to format dates
+	Location *time.Location
+}
+
+type service struct {
+	users     UserRepo
+	links     LinkRepo
+	messenger Messenger
+	cfg       Config
+}
+
+func NewMembersService(
+	users UserRepo,
+	links LinkRepo,
+	messenger Messenger,
+	cfg Config,
+) Service {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+
+	return &service{
+		users:     users,
+		links:     links,
+		messenger: messenger,
+		cfg:       cfg,
+	}
+}
+
+func (s *service) IssueCode(ctx context.Context, user domain.User) (*LinkCode, error) {
+	code, err := domain.NewTelegramLinkCode()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.cfg.CodeTTL)
+	err = s.links.CreateCode(ctx, domain.TelegramLinkCode{
+		CodeHash:  hashCode(code),
+		UserID:    user.ID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &LinkCode{Code: code, ExpiresAt: expiresAt}
+	if s.cfg.BotUsername != "" {
+		res.URL = "https://t.me/" + strings.TrimPrefix(s.cfg.BotUsername, "@") + "?start=" + code
+	}
+
+	return res, nil
+}
+
+func (s *service) Link(ctx context.Context, code string, chatID int64, username string) (*domain.User, error) {
+	linkCode, err := s.links.ConsumeCode(ctx, hashCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if linkCode == nil {
+		return nil, ErrInvalidCode
+	}
+
+	user, err := s.users.ByID(ctx, linkCode.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCode
+	}
+
+	err = s.links.Save(ctx, domain.TelegramLink{
+		UserID:   user.ID,
+		ChatID:   chatID,
+		Username: username,
+		LinkedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *service) Unlink(ctx context.Context, chatID int64) error {
+	ok, err := s.links.DeleteByChatID(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotLinked
+	}
+
+	return nil
+}
+
+func (s *service) Status(ctx context.Context, chatID int64) (Message, error) {
+	user, err := s.userByChat(ctx, chatID)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Text:    user.Email + "\n\n" + user.StatusInfo(time.Now().In(s.cfg.Location)),
+		Buttons: s.buttons(*user),
+	}, nil
+}
+
+func (s *service) SendExpiryReminder(ctx context.Context, user domain.User) error {
+	return s.sendToUser(ctx, user, Message{
+		Text: fmt.Sprintf(
+			"Доступ к Lumos заканчивается %s.\nТариф: %s",
+			user.ExpiresAt.In(s.cfg.Location).Format("02.01.2006 15:04"),
+			user.Tariff,
+		),
+		Buttons: s.buttons(user),
+	})
+}
+
+func (s *service) SendPaymentReceipt(ctx context.Context, user domain.User, payment domain.Payment) error {
+	return s.sendToUser(ctx, user, Message{
+		Text: fmt.Sprintf(
+			"Оплата получена: %.2f ₽, тариф %s.\nДоступ открыт до %s.",
+			payment.Amount,
+			payment.Tariff,
+			user.ExpiresAt.In(s.cfg.Location).Format("02.01.2006"),
+		),
+	})
+}
+
+func (s *service) sendToUser(ctx context.Context, user domain.User, msg Message) error {
+	link, err := s.links.ByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return nil
+	}
+
+	return s.messenger.SendMessage(ctx, link.ChatID, msg)
+}
+
+func (s *service) userByChat(ctx context.Context, chatID int64) (*domain.User, error) {
+	link, err := s.links.ByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrNotLinked
+	}
+
+	user, err := s.users.ByID(ctx, link.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotLinked
+	}
+
+	return user, nil
+}
+
+// buttons offers renewal and autopayment cancel for active subscription.
+func (s *service) buttons(user domain.User) []Button {
+	if user.Tariff == domain.UserTariffUnlimited {
+		return nil
+	}
+
+	var buttons []Button
+	if s.cfg.RenewURL != "" {
+		buttons = append(buttons, Button{Label: "Продлить доступ", URL: s.cfg.RenewURL})
+	}
+	if s.cfg.CancelURL != "" && user.SubscriptionID != "" && user.SubscriptionStatus == domain.UserSubStatusActive {
+		buttons = append(buttons, Button{Label: "Отменить автоплатёж", URL: s.cfg.CancelURL + user.SubscriptionID})
+	}
+
+	return buttons
+}
+
+// only code hash is stored, so leaked table rows can not be used to link chats
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,31 @@
+//go:build unit
+// +build unit
+
+package members
+
+import (
+	"doctormakarhina/lumos/internal/core/domain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestButtons(t *testing.T) {
+	s := &service{cfg: Config{RenewURL: "https://lumos-care.ru/pay", CancelURL: "https://cp/unsubscribe/"}}
+
+	require.Equal(t,
+		[]Button{{Label: "Продлить доступ", URL: "https://lumos-care.ru/pay"}},
+		s.buttons(domain.User{Tariff: domain.UserTariff1Month}),
+	)
+	require.Len(t, s.buttons(domain.User{
+		Tariff:             domain.UserTariff1Month,
+		SubscriptionID:     "sc_1",
+		SubscriptionStatus: domain.UserSubStatusActive,
+	}), 2)
+	require.Nil(t, s.buttons(domain.User{Tariff: domain.UserTariffUnlimited}))
+}
+
+func TestHashCodeIgnoresCase(t *testing.T) {
+	require.Equal(t, hashCode("ab12cd34"), hashCode(" AB12CD34 "))
+	require.NotEqual(t, hashCode("AB12CD34"), hashCode("AB12CD35"))
+}
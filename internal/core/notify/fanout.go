@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sender delivers notification to a single target.
+type Sender interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+type FanoutConfig struct {
+	// Timeout bounds a single delivery, it is also used for the final drain
+	Timeout time.Duration
+	// QueueSize bounds amount of pending deliveries, new ones are dropped when the queue is full
+	QueueSize int
+	// Workers is amount of concurrent deliveries, so one slow target does not hold the others
+	Workers int
+}
+
+type delivery struct {
+	target string
+	sender Sender
+	n      Notification
+}
+
+type Fanout struct {
+	router     *Router
+	senders    map[string]Sender
+	cfg        FanoutConfig
+	deliveries chan delivery
+	logger     *slog.Logger
+
+	// mu guards closed together with the enqueue, so nothing is enqueued after the final drain
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// NewFanout routes every notification to its targets, deliveries are queued and sent
+// in background by Run so callers are not delayed by slow targets,
+// delivery errors are logged only since there is nobody else to notify.
+func NewFanout(router *Router, senders map[string]Sender, cfg FanoutConfig, logger *slog.Logger) *Fanout {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+
+	return &Fanout{
+		router:     router,
+		senders:    senders,
+		cfg:        cfg,
+		deliveries: make(chan delivery, cfg.QueueSize),
+		logger:     logger.With(slog.String("context", "NotifyFanout")),
+	}
+}
+
+func (r *Fanout) ForAdmin(msg string) {
+	r.Notify(FromLegacy(msg))
+}
+
+// Notify enqueues the notification for every matched target without blocking.
+func (r *Fanout) Notify(n Notification) {
+	if n.Message == "" && len(n.Fields) == 0 {
+		return
+	}
+	if n.At.IsZero() {
+		n.At = time.Now()
+	}
+
+	for _, name := range r.router.Match(n) {
+		sender, ok := r.senders[name]
+		if !ok {
+			r.logger.Error("notify target has no sender", slog.String("target", name))
+			continue
+		}
+
+		r.enqueue(delivery{target: name, sender: sender, n: n})
+	}
+}
+
+func (r *Fanout) enqueue(d delivery) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		r.drop(d.target, d.n, "queue is closed")
+		return
+	}
+	select {
+	case r.deliveries <- d:
+	default:
+		r.drop(d.target, d.n, "queue is full")
+	}
+}
+
+// Run delivers queued notifications until ctx is done, then stops accepting new ones
+// and delivers the rest of the queue within the timeout.
+func (r *Fanout) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for range r.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case d := <-r.deliveries:
+					r.deliver(context.Background(), d)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	wg.Wait()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+	defer cancel()
+	for {
+		select {
+		case d := <-r.deliveries:
+			r.deliver(drainCtx, d)
+		default:
+			return nil
+		}
+		if drainCtx.Err() != nil {
+			r.logger.Warn("notify drain timed out", slog.Int("dropped", len(r.deliveries)))
+			return nil
+		}
+	}
+}
+
+// Dropped is amount of deliveries dropped because the queue was full or closed.
+func (r *Fanout) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+func (r *Fanout) deliver(ctx context.Context, d delivery) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	if err := d.sender.Send(ctx, d.n); err != nil {
+		r.logger.Error(
+			"failed to deliver notification",
+			slog.String("err", err.Error()),
+			slog.String("target", d.target),
+			slog.String("event", d.n.Event),
+		)
+	}
+}
+
+func (r *Fanout) drop(target string, n Notification, reason string) {
+	r.dropped.Add(1)
+	r.logger.Error(
+		"notification dropped",
+		slog.String("reason", reason),
+		slog.String("target", target),
+		slog.String("event", n.Event),
+	)
+}
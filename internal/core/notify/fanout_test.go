@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+package notify
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type blockingSender struct {
+	release chan struct{}
+
+	mu   sync.Mutex
+	sent []string
+}
+
+func (s *blockingSender) Send(ctx context.Context, n Notification) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, n.Message)
+	return nil
+}
+
+func (s *blockingSender) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
+func TestFanoutNotifyDoesNotWaitForDelivery(t *testing.T) {
+	sender := &blockingSender{release: make(chan struct{})}
+	fanout := NewFanout(
+		NewRouter(nil, []string{"slow"}),
+		map[string]Sender{"slow": sender},
+		FanoutConfig{Timeout: time.Second, QueueSize: 2, Workers: 1},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- fanout.Run(ctx) }()
+
+	start := time.Now()
+	fanout.Notify(Info("test.event", "a"))
+	require.Eventually(t, func() bool { return len(fanout.deliveries) == 0 }, time.Second, time.Millisecond)
+	for _, msg := range []string{"b", "c", "d"} {
+		fanout.Notify(Info("test.event", msg))
+	}
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// one delivery is in flight, two are queued and the last one is dropped
+	require.Equal(t, uint64(1), fanout.Dropped())
+
+	close(sender.release)
+	cancel()
+	require.NoError(t, <-done)
+	require.ElementsMatch(t, []string{"a", "b", "c"}, sender.messages())
+
+	fanout.Notify(Info("test.event", "late"))
+	require.Equal(t, uint64(2), fanout.Dropped())
+}
@@ -7,3 +7,5 @@ func NewNoop() Service {
 }
 
 func (n *noopService) ForAdmin(_ string) {}
+
+func (n *noopService) Notify(_ Notification) {}
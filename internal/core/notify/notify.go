@@ -1,5 +1,208 @@
 package notify
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
 type Service interface {
+	// ForAdmin is a legacy free text notification, event and severity are derived from the message.
 	ForAdmin(msg string)
+	Notify(n Notification)
+}
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	default:
+		return SeverityInfo, fmt.Errorf("unknown severity: %s", s)
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	parsed, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+type Field struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Action is a follow-up operation offered along with notification, e.g. a chat button.
+type Action struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// Value is the action argument, it must fit into 64 bytes with Name for telegram buttons
+	Value string `json:"value"`
+}
+
+const (
+	ActionOpenUser           = "open_user"
+	ActionExtendWeek         = "extend_7d"
+	ActionCancelSubscription = "cancel_sub"
+)
+
+// UserActions are offered on payment notifications of the user,
+// extend action value carries a nonce so it is applied once per notification.
+func UserActions(userID string, withSubscription bool) []Action {
+	actions := []Action{
+		{Name: ActionOpenUser, Label: "Open user", Value: userID},
+		{Name: ActionExtendWeek, Label: "Extend 7 days", Value: userID + nonceSep + newNonce()},
+	}
+	if withSubscription {
+		actions = append(actions, Action{Name: ActionCancelSubscription, Label: "Cancel subscription", Value: userID})
+	}
+	return actions
+}
+
+const nonceSep = ":"
+
+// SplitNonce splits action value into the argument and the nonce, nonce is empty if there is none.
+func SplitNonce(value string) (arg string, nonce string) {
+	arg, nonce, _ = strings.Cut(value, nonceSep)
+	return arg, nonce
+}
+
+func newNonce() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type Notification struct {
+	Severity Severity `json:"severity"`
+	// Event is a dotted event type used for routing, e.g. payments.accepted
+	Event   string    `json:"event"`
+	Message string    `json:"message"`
+	Fields  []Field   `json:"fields,omitempty"`
+	Actions []Action  `json:"actions,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+func (n Notification) WithActions(actions ...Action) Notification {
+	n.Actions = append(n.Actions, actions...)
+	return n
+}
+
+func New(severity Severity, event string, msg string, fields ...Field) Notification {
+	return Notification{
+		Severity: severity,
+		Event:    event,
+		Message:  msg,
+		Fields:   fields,
+		At:       time.Now(),
+	}
+}
+
+func Info(event string, msg string, fields ...Field) Notification {
+	return New(SeverityInfo, event, msg, fields...)
+}
+
+func Warning(event string, msg string, fields ...Field) Notification {
+	return New(SeverityWarning, event, msg, fields...)
+}
+
+func Error(event string, msg string, fields ...Field) Notification {
+	return New(SeverityError, event, msg, fields...)
+}
+
+func F(key string, value any) Field {
+	return Field{Key: key, Value: fmt.Sprint(value)}
+}
+
+// Text renders notification as plain text for chat and email channels.
+func (n Notification) Text() string {
+	var b strings.Builder
+	if n.Severity > SeverityInfo {
+		b.WriteString(strings.ToUpper(n.Severity.String()))
+		b.WriteString(" ")
+	}
+	if n.Event != "" {
+		b.WriteString("[")
+		b.WriteString(n.Event)
+		b.WriteString("]: ")
+	}
+	b.WriteString(n.Message)
+	for _, f := range n.Fields {
+		b.WriteString("\n")
+		b.WriteString(f.Key)
+		b.WriteString(": ")
+		b.WriteString(f.Value)
+	}
+	return b.String()
+}
+
+// legacyPrefix matches "[RegisterFromTrial]: ..." prefix of ForAdmin messages.
+var legacyPrefix = regexp.MustCompile(`^\s*\[([^\]]+)\]:?\s*`)
+
+var (
+	legacyErrorWords   = []string{"error", "failed", "panic"}
+	legacyWarningWords = []string{"rejected", "unknown", "suspicious", "denied", "bounced", "complained"}
+)
+
+// FromLegacy converts ForAdmin message, the bracketed prefix becomes legacy.<prefix> event
+// and severity is guessed from well known words in the message head, the text before
+// the first interpolated value, so user data can not raise the severity.
+// Messages without the prefix are always info.
+func FromLegacy(msg string) Notification {
+	n := Info("legacy", strings.TrimSpace(msg))
+
+	m := legacyPrefix.FindStringSubmatch(msg)
+	if m == nil {
+		return n
+	}
+	n.Event = "legacy." + strings.TrimSpace(m[1])
+	n.Message = strings.TrimSpace(msg[len(m[0]):])
+
+	head := n.Message
+	if i := strings.IndexAny(head, "(:"); i >= 0 {
+		head = head[:i]
+	}
+	words := strings.Fields(strings.ToLower(head))
+	switch {
+	case slices.ContainsFunc(legacyErrorWords, func(w string) bool { return slices.Contains(words, w) }):
+		n.Severity = SeverityError
+	case slices.ContainsFunc(legacyWarningWords, func(w string) bool { return slices.Contains(words, w) }):
+		n.Severity = SeverityWarning
+	}
+
+	return n
 }
@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+)
+
+// Target describes a delivery channel, Type is telegram, email or webhook.
+type Target struct {
+	Type string `json:"type"`
+	// ChatID and ThreadID address telegram chat and optional forum topic
+	ChatID   int64 `json:"chat_id,omitempty"`
+	ThreadID int   `json:"thread_id,omitempty"`
+	// To is a list of email recipients
+	To []string `json:"to,omitempty"`
+	// URL and Secret configure webhook, body is signed with hmac sha256 when Secret is set
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// Route sends matching notifications to Targets, Events are path.Match patterns
+// over event type, e.g. "payments.*", empty Events matches any event.
+type Route struct {
+	Events      []string `json:"events"`
+	MinSeverity Severity `json:"min_severity"`
+	Targets     []string `json:"targets"`
+	// Stop skips the rest of routes after this one matched
+	Stop bool `json:"stop"`
+}
+
+type RoutesConfig struct {
+	Targets map[string]Target `json:"targets"`
+	Routes  []Route           `json:"routes"`
+	// Default targets receive notifications no route matched
+	Default []string `json:"default"`
+}
+
+func LoadRoutesFile(p string) (RoutesConfig, error) {
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		return RoutesConfig{}, fmt.Errorf("failed to read notify routes file: %w", err)
+	}
+
+	var cfg RoutesConfig
+	err = json.Unmarshal(raw, &cfg)
+	if err != nil {
+		return RoutesConfig{}, fmt.Errorf("failed to parse notify routes file: %w", err)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (r RoutesConfig) Validate() error {
+	known := func(names []string) error {
+		for _, name := range names {
+			if _, ok := r.Targets[name]; !ok {
+				return fmt.Errorf("unknown notify target: %s", name)
+			}
+		}
+		return nil
+	}
+
+	for name, t := range r.Targets {
+		switch t.Type {
+		case "telegram":
+			if t.ChatID == 0 {
+				return fmt.Errorf("notify target %s: chat_id is required", name)
+			}
+		case "email":
+			if len(t.To) == 0 {
+				return fmt.Errorf("notify target %s: to is required", name)
+			}
+		case "webhook":
+			if t.URL == "" {
+				return fmt.Errorf("notify target %s: url is required", name)
+			}
+		default:
+			return fmt.Errorf("notify target %s: unknown type %q", name, t.Type)
+		}
+	}
+
+	for i, route := range r.Routes {
+		for _, pattern := range route.Events {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("notify route %d: bad event pattern %q", i, pattern)
+			}
+		}
+		if err := known(route.Targets); err != nil {
+			return fmt.Errorf("notify route %d: %w", i, err)
+		}
+	}
+
+	return known(r.Default)
+}
+
+type Router struct {
+	routes   []Route
+	defaults []string
+}
+
+func NewRouter(routes []Route, defaults []string) *Router {
+	return &Router{routes: routes, defaults: defaults}
+}
+
+// Match returns distinct target names for the notification in routes order.
+func (r *Router) Match(n Notification) []string {
+	var out []string
+	matched := false
+
+	for _, route := range r.routes {
+		if n.Severity < route.MinSeverity || !matchEvent(route.Events, n.Event) {
+			continue
+		}
+		matched = true
+		for _, t := range route.Targets {
+			if !slices.Contains(out, t) {
+				out = append(out, t)
+			}
+		}
+		if route.Stop {
+			break
+		}
+	}
+
+	if !matched {
+		return slices.Clone(r.defaults)
+	}
+	return out
+}
+
+func matchEvent(patterns []string, event string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, event); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterMatch(t *testing.T) {
+	router := NewRouter([]Route{
+		{Events: []string{"payments.*"}, Targets: []string{"sales"}},
+		{MinSeverity: SeverityError, Targets: []string{"oncall", "sales"}, Stop: true},
+		{Events: []string{"legacy.*"}, Targets: []string{"archive"}},
+	}, []string{"admin"})
+
+	cases := []struct {
+		name string
+		n    Notification
+		want []string
+	}{
+		{"event route", Info("payments.accepted", "ok"), []string{"sales"}},
+		{"severity route stops", Error("legacy.UserInfo", "error"), []string{"oncall", "sales"}},
+		{"combined routes dedupe", Error("payments.recurrent", "failed"), []string{"sales", "oncall"}},
+		{"next route", Warning("legacy.UserInfo", "rejected"), []string{"archive"}},
+		{"default", Info("sharing.alert", "score"), []string{"admin"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.want, router.Match(c.n))
+		})
+	}
+}
+
+func TestFromLegacy(t *testing.T) {
+	n := FromLegacy("[RegisterFromTrial]: error creating user (a@b.c): boom")
+	require.Equal(t, "legacy.RegisterFromTrial", n.Event)
+	require.Equal(t, SeverityError, n.Severity)
+	require.Equal(t, "error creating user (a@b.c): boom", n.Message)
+
+	n = FromLegacy("[RegisterFromProdamus]: unknown tariff (x)")
+	require.Equal(t, SeverityWarning, n.Severity)
+
+	n = FromLegacy("[ReferralAttribute]: user (error@b.c) invited by (a@b.c), source = failed")
+	require.Equal(t, SeverityInfo, n.Severity)
+
+	n = FromLegacy("weekly digest: error rate")
+	require.Equal(t, "legacy", n.Event)
+	require.Equal(t, SeverityInfo, n.Severity)
+}
+
+func TestRoutesConfigValidate(t *testing.T) {
+	cfg := RoutesConfig{
+		Targets: map[string]Target{"admin": {Type: "telegram", ChatID: 1}},
+		Routes:  []Route{{Targets: []string{"missing"}}},
+		Default: []string{"admin"},
+	}
+	require.Error(t, cfg.Validate())
+
+	cfg.Routes[0].Targets = []string{"admin"}
+	require.NoError(t, cfg.Validate())
+}
+
+func TestUserActionsExtendNonce(t *testing.T) {
+	value := func() string {
+		for _, a := range UserActions("user-1", false) {
+			if a.Name == ActionExtendWeek {
+				return a.Value
+			}
+		}
+		t.Fatal("extend action is missing")
+		return ""
+	}
+
+	first, second := value(), value()
+	userID, nonce := SplitNonce(first)
+	require.Equal(t, "user-1", userID)
+	require.NotEmpty(t, nonce)
+	require.NotEqual(t, first, second)
+
+	userID, nonce = SplitNonce("user-1")
+	require.Equal(t, "user-1", userID)
+	require.Empty(t, nonce)
+}
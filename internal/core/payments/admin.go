@@ -0,0 +1,76 @@
+package payments
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"time"
+)
+
+func (s *service) ExtendAccess(ctx context.Context, userID string, days int) (*domain.User, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("days must be positive")
+	}
+
+	user, err := s.repo.ByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Tariff == domain.UserTariffUnlimited {
+		return user, nil
+	}
+
+	user.ExpiresAt = user.NewSubStartedAt(time.Now()).AddDate(0, 0, days)
+
+	updated, err := s.repo.UpdateSub(ctx, *user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notif.Notify(notify.Info(
+		"payments.access_extended",
+		"user access is extended by admin",
+		notify.F("email", updated.Email),
+		notify.F("days", days),
+		notify.F("expires_at", updated.ExpiresAt.Format(time.RFC3339)),
+	))
+
+	return updated, nil
+}
+
+func (s *service) CancelSubscription(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.repo.ByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.SubscriptionID == "" || user.SubscriptionStatus == domain.UserSubStatusCanceled {
+		return nil, ErrNoSubscription
+	}
+
+	err = s.cloudPayments.CancelSubscription(ctx, user.SubscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel subscription: %w", err)
+	}
+
+	err = s.repo.UpdateSubStatusBySubID(ctx, user.SubscriptionID, domain.UserSubStatusCanceled)
+	if err != nil {
+		return nil, err
+	}
+	user.SubscriptionStatus = domain.UserSubStatusCanceled
+
+	s.notif.Notify(notify.Warning(
+		"payments.subscription_cancelled",
+		"user subscription is cancelled by admin",
+		notify.F("email", user.Email),
+		notify.F("subscription_id", user.SubscriptionID),
+	))
+
+	return user, nil
+}
@@ -0,0 +1,332 @@
+package payments
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *service) RegisterGift(
+	ctx context.Context,
+	provider string,
+	subName string,
+	payerEmail string,
+	recipientEmail string,
+	price float32,
+	transactionID string,
+	subscriptionID string,
+) error {
+	payerNorm := s.normalizeStr(payerEmail)
+	recipientNorm := s.normalizeStr(recipientEmail)
+	subNorm := s.normalizeStr(subName)
+	transactionID = strings.TrimSpace(transactionID)
+	subscriptionID = strings.TrimSpace(subscriptionID)
+
+	tariffName := s.tariffBySubName(subNorm)
+	if tariffName == "" {
+		s.notif.Notify(notify.Warning(
+			"payments.unknown_tariff",
+			"unknown tariff, gift is not registered",
+			notify.F("tariff", subNorm),
+			notify.F("payer", payerNorm),
+			notify.F("recipient", recipientNorm),
+		))
+		return nil
+	}
+
+	if transactionID != "" {
+		existing, err := s.gifts.ByTransactionID(ctx, provider, transactionID)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.gift_registration_failed",
+				"error fetching gift by transaction",
+				notify.F("transaction_id", transactionID),
+				notify.F("err", err),
+			))
+			return err
+		}
+		if existing != nil {
+			s.notif.Notify(notify.Info(
+				"payments.duplicate_webhook",
+				"duplicate gift webhook, skipping",
+				notify.F("payer", payerNorm),
+				notify.F("transaction_id", transactionID),
+			))
+			return nil
+		}
+	}
+
+	code, err := domain.NewGiftCode()
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.gift_registration_failed",
+			"error generating gift code",
+			notify.F("payer", payerNorm),
+			notify.F("err", err),
+		))
+		return err
+	}
+
+	gift, err := s.gifts.Create(ctx, domain.Gift{
+		ID:             uuid.New().String(),
+		Code:           code,
+		PayerEmail:     payerNorm,
+		RecipientEmail: recipientNorm,
+		Tariff:         tariffName,
+		Price:          price,
+		Provider:       provider,
+		TransactionID:  transactionID,
+	})
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.gift_registration_failed",
+			"error creating gift",
+			notify.F("payer", payerNorm),
+			notify.F("recipient", recipientNorm),
+			notify.F("err", err),
+		))
+		return err
+	}
+
+	// gifts are one-off purchases, recurrent charges must not be made from the payer card
+	if subscriptionID != "" {
+		err := s.cloudPayments.CancelSubscription(ctx, subscriptionID)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.gift_registration_failed",
+				"error canceling subscription created for gift, cancel manually",
+				notify.F("subscription_id", subscriptionID),
+				notify.F("payer", payerNorm),
+				notify.F("err", err),
+			))
+		}
+	}
+
+	s.recordBilling(ctx, "RegisterGift", domain.BillingEvent{
+		Kind:          domain.BillingEventGift,
+		Email:         payerNorm,
+		Provider:      provider,
+		TransactionID: transactionID,
+		Tariff:        tariffName,
+		Amount:        price,
+	})
+
+	err = s.emails.ScheduleGiftPurchased(ctx, payerNorm, recipientNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error scheduling gift purchased email",
+			notify.F("email", payerNorm),
+			notify.F("err", err),
+		))
+	}
+
+	err = s.emails.ScheduleGiftReceived(ctx, recipientNorm, gift.Code)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error scheduling gift received email",
+			notify.F("email", recipientNorm),
+			notify.F("err", err),
+		))
+	}
+
+	s.notif.Notify(notify.Info(
+		"payments.gift_purchased",
+		"gift payment is accepted",
+		notify.F("payer", payerNorm),
+		notify.F("recipient", recipientNorm),
+		notify.F("tariff", tariffName),
+		notify.F("gift", gift.Code),
+	))
+
+	return nil
+}
+
+func (s *service) RedeemGift(
+	ctx context.Context,
+	code string,
+	email string,
+	name string,
+) (*domain.User, error) {
+	codeNorm := strings.ToUpper(strings.TrimSpace(code))
+	emailNorm := s.normalizeStr(email)
+
+	if codeNorm == "" || emailNorm == "" {
+		return nil, ErrGiftNotFound
+	}
+
+	gift, err := s.gifts.MarkRedeemed(ctx, codeNorm, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.gift_redemption_failed",
+			"error redeeming gift",
+			notify.F("gift", codeNorm),
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+	if gift == nil {
+		existing, err := s.gifts.ByCode(ctx, codeNorm)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.gift_redemption_failed",
+				"error fetching gift",
+				notify.F("gift", codeNorm),
+				notify.F("err", err),
+			))
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrGiftNotFound
+		}
+
+		return nil, ErrGiftAlreadyRedeemed
+	}
+
+	user, err := s.applyGift(ctx, gift, emailNorm, name)
+	if err != nil {
+		if unmarkErr := s.gifts.UnmarkRedeemed(ctx, codeNorm); unmarkErr != nil {
+			s.notif.Notify(notify.Error(
+				"payments.gift_redemption_failed",
+				"error reverting redemption of gift",
+				notify.F("gift", codeNorm),
+				notify.F("err", unmarkErr),
+			))
+		}
+		return nil, err
+	}
+
+	err = s.emails.CancelTrialExpired(ctx, user.Email)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error canceling trial expired email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+	}
+
+	s.notif.Notify(notify.Info(
+		"payments.gift_redeemed",
+		"gift is redeemed",
+		notify.F("gift", gift.Code),
+		notify.F("payer", gift.PayerEmail),
+		notify.F("email", emailNorm),
+		notify.F("tariff", user.Tariff),
+		notify.F("expires_at", user.ExpiresAt.Format(time.RFC3339)),
+	))
+
+	return user, nil
+}
+
+func (s *service) applyGift(ctx context.Context, gift *domain.Gift, emailNorm string, name string) (*domain.User, error) {
+	candidate := domain.User{
+		ID:                 uuid.New().String(),
+		Email:              emailNorm,
+		Name:               name,
+		Tariff:             gift.Tariff,
+		ExpiresAt:          time.Now().Add(-time.Minute),
+		SubscriptionID:     "",
+		SubscriptionStatus: "",
+		LastSubPrice:       0,
+	}
+
+	user, err := s.repo.FindByEmailOrCreate(ctx, candidate)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.gift_redemption_failed",
+			"error finding or creating user",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+	if user == nil {
+		s.notif.Notify(notify.Error(
+			"payments.gift_redemption_failed",
+			"user is neither found nor created",
+			notify.F("email", emailNorm),
+		))
+		return nil, fmt.Errorf("failed to find or create user")
+	}
+
+	if user.Tariff == domain.UserTariffUnlimited {
+		s.notif.Notify(notify.Warning(
+			"payments.gift_not_applied",
+			"user has unlimited tariff, gift is not applied",
+			notify.F("email", emailNorm),
+			notify.F("gift", gift.Code),
+		))
+		return nil, ErrGiftNotApplicable
+	}
+
+	newExpiresAt, err := user.NewSubEndedAt(time.Now(), gift.Tariff)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.gift_redemption_failed",
+			"error calculating new expiration date",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+
+	// user with active autopayment keeps his tariff, the next charge is moved after the gifted period
+	subMonths := domain.TariffMonths(user.Tariff)
+	subMoved := user.SubscriptionID != "" && user.SubscriptionStatus == domain.UserSubStatusActive && subMonths > 0
+	prevExpiresAt := user.ExpiresAt
+	if subMoved {
+		subStatus, err := s.cloudPayments.UpdateSubscription(ctx, user.SubscriptionID, newExpiresAt, "Month", subMonths)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.gift_redemption_failed",
+				"error moving subscription start date",
+				notify.F("email", emailNorm),
+				notify.F("err", err),
+			))
+			return nil, err
+		}
+		user.SubscriptionStatus = subStatus
+	} else {
+		user.Tariff = gift.Tariff
+	}
+	user.ExpiresAt = newExpiresAt
+
+	updated, err := s.repo.UpdateSub(ctx, *user)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.gift_redemption_failed",
+			"error updating user",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		// the gift is redeemable again, so the next charge must not stay moved by it
+		if subMoved {
+			s.restoreSubStartDate(ctx, user.SubscriptionID, prevExpiresAt, subMonths, emailNorm)
+		}
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *service) restoreSubStartDate(ctx context.Context, subscriptionID string, startDate time.Time, months int, emailNorm string) {
+	_, err := s.cloudPayments.UpdateSubscription(ctx, subscriptionID, startDate, "Month", months)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.gift_redemption_failed",
+			"error restoring subscription start date, move manually",
+			notify.F("email", emailNorm),
+			notify.F("subscription_id", subscriptionID),
+			notify.F("start_date", startDate.Format(time.RFC3339)),
+			notify.F("err", err),
+		))
+	}
+}
@@ -0,0 +1,165 @@
+//go:build unit
+// +build unit
+
+package payments
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type giftsStub struct {
+	GiftRepo
+	byTransaction *domain.Gift
+	byCode        *domain.Gift
+	redeemed      *domain.Gift
+	created       []domain.Gift
+	unmarked      []string
+}
+
+func (r *giftsStub) ByTransactionID(_ context.Context, _ string, _ string) (*domain.Gift, error) {
+	return r.byTransaction, nil
+}
+
+func (r *giftsStub) Create(_ context.Context, gift domain.Gift) (*domain.Gift, error) {
+	r.created = append(r.created, gift)
+	return &gift, nil
+}
+
+func (r *giftsStub) MarkRedeemed(_ context.Context, _ string, _ string) (*domain.Gift, error) {
+	return r.redeemed, nil
+}
+
+func (r *giftsStub) ByCode(_ context.Context, _ string) (*domain.Gift, error) {
+	return r.byCode, nil
+}
+
+func (r *giftsStub) UnmarkRedeemed(_ context.Context, code string) error {
+	r.unmarked = append(r.unmarked, code)
+	return nil
+}
+
+type giftUsersStub struct {
+	UserRepo
+	user      domain.User
+	updateErr error
+}
+
+func (r *giftUsersStub) FindByEmailOrCreate(_ context.Context, _ domain.User) (*domain.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *giftUsersStub) UpdateSub(_ context.Context, user domain.User) (*domain.User, error) {
+	if r.updateErr != nil {
+		return nil, r.updateErr
+	}
+	return &user, nil
+}
+
+type subscriptionUpdate struct {
+	id        string
+	startDate time.Time
+}
+
+type cloudPaymentsStub struct {
+	updates []subscriptionUpdate
+}
+
+func (r *cloudPaymentsStub) UpdateSubscription(_ context.Context, id string, startDate time.Time, _ string, _ int) (string, error) {
+	r.updates = append(r.updates, subscriptionUpdate{id: id, startDate: startDate})
+	return domain.UserSubStatusActive, nil
+}
+
+func (r *cloudPaymentsStub) CancelSubscription(_ context.Context, _ string) error {
+	return nil
+}
+
+func notifiedEvents(notif *notifyStub) []string {
+	events := make([]string, 0, len(notif.sent))
+	for _, n := range notif.sent {
+		events = append(events, n.Event)
+	}
+	return events
+}
+
+func TestRegisterGiftSkipsDuplicateTransaction(t *testing.T) {
+	gifts := &giftsStub{byTransaction: &domain.Gift{Code: "GIFT1"}}
+	notif := &notifyStub{}
+	s := &service{gifts: gifts, notif: notif}
+
+	err := s.RegisterGift(
+		context.Background(),
+		domain.PaymentProviderCloudPayments,
+		"Доступ на месяц",
+		"payer@example.com",
+		"recipient@example.com",
+		990,
+		"tx-1",
+		"",
+	)
+	require.NoError(t, err)
+
+	require.Empty(t, gifts.created)
+	require.Equal(t, []string{"payments.duplicate_webhook"}, notifiedEvents(notif))
+}
+
+func TestRedeemGiftAlreadyRedeemed(t *testing.T) {
+	gifts := &giftsStub{byCode: &domain.Gift{Code: "GIFT1", RedeemedBy: "other@example.com"}}
+	s := &service{gifts: gifts, notif: &notifyStub{}}
+
+	_, err := s.RedeemGift(context.Background(), "gift1", "user@example.com", "User")
+	require.ErrorIs(t, err, ErrGiftAlreadyRedeemed)
+	require.Empty(t, gifts.unmarked)
+}
+
+func TestRedeemGiftNotAppliedToUnlimitedTariff(t *testing.T) {
+	gifts := &giftsStub{redeemed: &domain.Gift{Code: "GIFT1", Tariff: domain.UserTariff1Month}}
+	notif := &notifyStub{}
+	s := &service{
+		gifts: gifts,
+		repo:  &giftUsersStub{user: domain.User{Email: "user@example.com", Tariff: domain.UserTariffUnlimited}},
+		notif: notif,
+	}
+
+	_, err := s.RedeemGift(context.Background(), "gift1", "user@example.com", "User")
+	require.ErrorIs(t, err, ErrGiftNotApplicable)
+
+	// the gift stays available for another recipient
+	require.Equal(t, []string{"GIFT1"}, gifts.unmarked)
+	require.Contains(t, notifiedEvents(notif), "payments.gift_not_applied")
+}
+
+func TestRedeemGiftRestoresSubscriptionStartDateWhenUserUpdateFails(t *testing.T) {
+	expiresAt := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second)
+	gifts := &giftsStub{redeemed: &domain.Gift{Code: "GIFT1", Tariff: domain.UserTariff1Month}}
+	cloudPayments := &cloudPaymentsStub{}
+	s := &service{
+		gifts: gifts,
+		repo: &giftUsersStub{
+			user: domain.User{
+				Email:              "user@example.com",
+				Tariff:             domain.UserTariff1Month,
+				ExpiresAt:          expiresAt,
+				SubscriptionID:     "sub-1",
+				SubscriptionStatus: domain.UserSubStatusActive,
+			},
+			updateErr: errors.New("db is down"),
+		},
+		cloudPayments: cloudPayments,
+		notif:         &notifyStub{},
+	}
+
+	_, err := s.RedeemGift(context.Background(), "gift1", "user@example.com", "User")
+	require.Error(t, err)
+
+	require.Equal(t, []string{"GIFT1"}, gifts.unmarked)
+	require.Len(t, cloudPayments.updates, 2)
+	require.True(t, cloudPayments.updates[0].startDate.After(expiresAt))
+	require.Equal(t, subscriptionUpdate{id: "sub-1", startDate: expiresAt}, cloudPayments.updates[1])
+}
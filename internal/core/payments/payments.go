@@ -14,16 +14,47 @@ type CloudPayments interface {
 type UserRepo interface {
 	FindByEmailOrCreate(ctx context.Context, user domain.User) (*domain.User, error)
 	ByEmail(ctx context.Context, email string) (*domain.User, error)
+	ByID(ctx context.Context, id string) (*domain.User, error)
 	Create(ctx context.Context, user domain.User) (*domain.User, error)
 	UpdateSub(ctx context.Context, user domain.User) (*domain.User, error)
 	UpdateSubStatusBySubID(ctx context.Context, subscriptionID string, status string) error
 }
 
+type GiftRepo interface {
+	Create(ctx context.Context, gift domain.Gift) (*domain.Gift, error)
+	ByCode(ctx context.Context, code string) (*domain.Gift, error)
+	ByTransactionID(ctx context.Context, provider string, transactionID string) (*domain.Gift, error)
+	MarkRedeemed(ctx context.Context, code string, redeemedBy string) (*domain.Gift, error)
+	UnmarkRedeemed(ctx context.Context, code string) error
+}
+
+type EntitlementRepo interface {
+	ByUserAndProject(ctx context.Context, userID string, projectID string) (*domain.Entitlement, error)
+	BySubscriptionID(ctx context.Context, subscriptionID string) (*domain.Entitlement, error)
+	Extend(ctx context.Context, userID string, projectID string, tariff string, months int, subscriptionID string) (*domain.Entitlement, error)
+}
+
+// BillingLedger keeps payments and subscription changes for business reports.
+type BillingLedger interface {
+	Record(ctx context.Context, e domain.BillingEvent) error
+}
+
+// MemberNotifier delivers payment receipts to the member telegram chat.
+type MemberNotifier interface {
+	SendPaymentReceipt(ctx context.Context, user domain.User, payment domain.Payment) error
+}
+
 type EmailsSrv interface {
 	ScheduleAfterTrialExpired(ctx context.Context, email string) error
 	CancelTrialExpired(ctx context.Context, email string) error
 	ScheduleAfterReccurrentPayment(ctx context.Context, email string) error
 	ScheduleAfterAutopaymentCancelled(ctx context.Context, email string) error
+	ScheduleGiftPurchased(ctx context.Context, payerEmail string, recipientEmail string) error
+	ScheduleGiftReceived(ctx context.Context, recipientEmail string, code string) error
+	SendPaymentReceipt(ctx context.Context, user domain.User, payment domain.Payment) error
+	SendTrialStarted(ctx context.Context, user domain.User) error
+	SendRenewalSucceeded(ctx context.Context, user domain.User, payment domain.Payment) error
+	SendPaymentFailed(ctx context.Context, user domain.User) error
 }
 
 type Service interface {
@@ -37,11 +68,18 @@ type Service interface {
 		email string,
 		projectID string,
 	) (*domain.User, error)
+	UserByID(
+		ctx context.Context,
+		userID string,
+		projectID string,
+	) (*domain.User, error)
 	RegisterFromTrial(
 		ctx context.Context,
 		email string,
 		name string,
-		trialDuration time.Duration,
+		campaign string,
+		source string,
+		fingerprint string,
 	) error
 	RegisterFromProdamus(
 		ctx context.Context,
@@ -50,6 +88,7 @@ type Service interface {
 		name string,
 		price float32,
 		orderID string,
+		projectID string,
 	) error
 	RegisterFromCloudPayments(
 		ctx context.Context,
@@ -59,6 +98,7 @@ type Service interface {
 		price float32,
 		subscriptionID string,
 		transactionID string,
+		projectID string,
 	) error
 	RegisterCloudPaymentReccurent(
 		ctx context.Context,
@@ -66,4 +106,24 @@ type Service interface {
 		email string,
 		status string,
 	) error
+	RegisterGift(
+		ctx context.Context,
+		provider string,
+		subName string,
+		payerEmail string,
+		recipientEmail string,
+		price float32,
+		transactionID string,
+		subscriptionID string,
+	) error
+	RedeemGift(
+		ctx context.Context,
+		code string,
+		email string,
+		name string,
+	) (*domain.User, error)
+	// ExtendAccess is a manual admin extension, it keeps tariff and subscription as they are
+	ExtendAccess(ctx context.Context, userID string, days int) (*domain.User, error)
+	// CancelSubscription is a manual admin cancellation of the user recurrent payments
+	CancelSubscription(ctx context.Context, userID string) (*domain.User, error)
 }
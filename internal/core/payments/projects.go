@@ -0,0 +1,180 @@
+package payments
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"time"
+)
+
+// projectAccess checks access granted by the user own subscription or by
+// the project entitlement, team seats are checked by the caller.
+func (s *service) projectAccess(ctx context.Context, user *domain.User, project domain.Project) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	if !user.SubExpired(now) && project.AllowsTariff(user.Tariff) {
+		return true, nil
+	}
+	if project.Default {
+		return false, nil
+	}
+
+	ent, err := s.entitlements.ByUserAndProject(ctx, user.ID, project.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return ent != nil && !ent.Expired(now), nil
+}
+
+// projectView returns user with tariff and expiration date of the project entitlement,
+// user is returned as is for the default project or when there is no entitlement.
+func (s *service) projectView(ctx context.Context, user *domain.User, project domain.Project) (*domain.User, error) {
+	if user == nil || project.Default {
+		return user, nil
+	}
+
+	ent, err := s.entitlements.ByUserAndProject(ctx, user.ID, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ent == nil {
+		return user, nil
+	}
+
+	view := *user
+	view.Tariff = ent.Tariff
+	view.ExpiresAt = ent.ExpiresAt
+	view.SubscriptionID = ent.SubscriptionID
+	if ent.SubscriptionID != user.SubscriptionID {
+		view.SubscriptionStatus = ""
+	}
+
+	return &view, nil
+}
+
+// registerEntitlement extends user access to the non default project after payment,
+// own user subscription stays untouched.
+func (s *service) registerEntitlement(
+	ctx context.Context,
+	logPrefix string,
+	provider string,
+	project domain.Project,
+	user *domain.User,
+	tariffName string,
+	price float32,
+	transactionID string,
+	subscriptionID string,
+) error {
+	months := domain.TariffMonths(tariffName)
+	if months == 0 {
+		s.notif.Notify(notify.Warning(
+			"payments.tariff_not_applicable",
+			"tariff can not be applied to project",
+			notify.F("tariff", tariffName),
+			notify.F("project", project.ID),
+			notify.F("email", user.Email),
+			notify.F("flow", logPrefix),
+		))
+		return nil
+	}
+
+	ent, err := s.entitlements.Extend(ctx, user.ID, project.ID, tariffName, months, subscriptionID)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.entitlement_failed",
+			"error extending project access",
+			notify.F("project", project.ID),
+			notify.F("email", user.Email),
+			notify.F("err", err),
+			notify.F("flow", logPrefix),
+		))
+		return err
+	}
+
+	if subscriptionID != "" {
+		_, err := s.cloudPayments.UpdateSubscription(ctx, subscriptionID, ent.ExpiresAt, "Month", months)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.entitlement_failed",
+				"error updating project subscription",
+				notify.F("project", project.ID),
+				notify.F("email", user.Email),
+				notify.F("err", err),
+				notify.F("flow", logPrefix),
+			))
+		}
+	}
+
+	// transaction id is kept on the user to skip duplicate webhooks
+	user.LastTransactionID = transactionID
+	_, err = s.repo.UpdateSub(ctx, *user)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.entitlement_failed",
+			"error updating user",
+			notify.F("email", user.Email),
+			notify.F("err", err),
+			notify.F("flow", logPrefix),
+		))
+		return err
+	}
+
+	err = s.referrals.OnPaidPurchase(ctx, user.Email, price)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.entitlement_failed",
+			"error rewarding referrer",
+			notify.F("email", user.Email),
+			notify.F("err", err),
+			notify.F("flow", logPrefix),
+		))
+	}
+
+	s.recordBilling(ctx, logPrefix, domain.BillingEvent{
+		Kind:          domain.BillingEventPayment,
+		Email:         user.Email,
+		Provider:      provider,
+		TransactionID: transactionID,
+		Tariff:        tariffName,
+		Amount:        price,
+	})
+
+	// receipt shows the project access, not the default one
+	receiptUser := *user
+	receiptUser.Tariff = ent.Tariff
+	receiptUser.ExpiresAt = ent.ExpiresAt
+	payment := domain.Payment{
+		Provider:      provider,
+		TransactionID: transactionID,
+		Tariff:        tariffName,
+		Amount:        price,
+		PaidAt:        time.Now(),
+	}
+	err = s.emails.SendPaymentReceipt(ctx, receiptUser, payment)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error sending receipt",
+			notify.F("email", user.Email),
+			notify.F("err", err),
+			notify.F("flow", logPrefix),
+		))
+	}
+	s.sendMemberReceipt(ctx, logPrefix, receiptUser, payment)
+
+	s.notif.Notify(notify.Info(
+		"payments.payment_accepted",
+		"user payment is accepted",
+		notify.F("email", user.Email),
+		notify.F("project", project.Name),
+		notify.F("tariff", tariffName),
+		notify.F("expires_at", ent.ExpiresAt.Format(time.RFC3339)),
+		notify.F("flow", logPrefix),
+	))
+
+	return nil
+}
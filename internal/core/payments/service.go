@@ -4,6 +4,11 @@ import (
 	"context"
 	"doctormakarhina/lumos/internal/core/domain"
 	"doctormakarhina/lumos/internal/core/notify"
+	"doctormakarhina/lumos/internal/core/projects"
+	"doctormakarhina/lumos/internal/core/referrals"
+	"doctormakarhina/lumos/internal/core/seats"
+	"doctormakarhina/lumos/internal/core/sharing"
+	"doctormakarhina/lumos/internal/core/trials"
 	"errors"
 	"fmt"
 	"slices"
@@ -15,7 +20,13 @@ import (
 
 var (
 	ErrUserAlreadyRegistered = errors.New("user already registered")
-	ErrInvalidProjectId = errors.New("invalid project ID")
+	ErrTrialDenied           = errors.New("trial registration denied")
+	ErrInvalidProjectId      = errors.New("invalid project ID")
+	ErrGiftNotFound          = errors.New("gift not found")
+	ErrGiftAlreadyRedeemed   = errors.New("gift already redeemed")
+	ErrGiftNotApplicable     = errors.New("gift can not be applied to user")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrNoSubscription        = errors.New("user has no active subscription")
 	oneMonthSubNames         = []string{
 		strings.ToLower(strings.TrimSpace("Доступ на месяц")),
 		strings.ToLower(strings.TrimSpace("Продление 1 месяц")),
@@ -31,26 +42,53 @@ var (
 )
 
 type service struct {
-	repo           UserRepo
-	emails         EmailsSrv
-	notif          notify.Service
-	cloudPayments  CloudPayments
-	tildaProjectID string
+	repo          UserRepo
+	entitlements  EntitlementRepo
+	gifts         GiftRepo
+	billing       BillingLedger
+	referrals     referrals.Service
+	seats         seats.Service
+	sharing       sharing.Service
+	trialPolicy   trials.Policy
+	trialAbuse    trials.AbuseChecker
+	emails        EmailsSrv
+	members       MemberNotifier
+	notif         notify.Service
+	cloudPayments CloudPayments
+	projects      *projects.Registry
 }
 
 func NewPaymentsService(
 	repo UserRepo,
+	entitlements EntitlementRepo,
+	gifts GiftRepo,
+	billing BillingLedger,
+	referrals referrals.Service,
+	seats seats.Service,
+	sharing sharing.Service,
+	trialPolicy trials.Policy,
+	trialAbuse trials.AbuseChecker,
 	emails EmailsSrv,
+	members MemberNotifier,
 	notif notify.Service,
 	cloudPayments CloudPayments,
-	tildaProjectID string,
+	projects *projects.Registry,
 ) Service {
 	return &service{
-		repo:           repo,
-		emails:         emails,
-		notif:          notif,
-		cloudPayments:  cloudPayments,
-		tildaProjectID: tildaProjectID,
+		repo:          repo,
+		entitlements:  entitlements,
+		gifts:         gifts,
+		billing:       billing,
+		referrals:     referrals,
+		seats:         seats,
+		sharing:       sharing,
+		trialPolicy:   trialPolicy,
+		trialAbuse:    trialAbuse,
+		emails:        emails,
+		members:       members,
+		notif:         notif,
+		cloudPayments: cloudPayments,
+		projects:      projects,
 	}
 }
 
@@ -59,7 +97,8 @@ func (s *service) IsAccessAlowed(
 	email string,
 	projectID string,
 ) (bool, error) {
-	if s.normalizeStr(projectID) != s.normalizeStr(s.tildaProjectID) {
+	project, ok := s.projects.ByID(projectID)
+	if !ok {
 		return false, nil
 	}
 
@@ -70,15 +109,56 @@ func (s *service) IsAccessAlowed(
 
 	user, err := s.repo.ByEmail(ctx, email)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[IsAccessAlowed]: error fetching user by email (%s): %v", email, err))
+		s.notif.Notify(notify.Error(
+			"payments.access_check_failed",
+			"error fetching user by email",
+			notify.F("email", email),
+			notify.F("err", err),
+		))
 		return false, err
 	}
 
-	if user == nil {
+	allowed, err := s.projectAccess(ctx, user, project)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.access_check_failed",
+			"error checking project access",
+			notify.F("project", project.ID),
+			notify.F("email", email),
+			notify.F("err", err),
+		))
+		return false, err
+	}
+	// team seats are sold for the default project only
+	if !allowed && project.Default {
+		allowed, err = s.seats.MemberAccess(ctx, email)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.access_check_failed",
+				"error checking team seat",
+				notify.F("email", email),
+				notify.F("err", err),
+			))
+			return false, err
+		}
+	}
+	if !allowed {
 		return false, nil
 	}
 
-	return !user.SubExpired(time.Now()), nil
+	exceeded, err := s.sharing.DevicesExceeded(ctx, email)
+	if err != nil {
+		// telemetry problems must not lock out paying users
+		s.notif.Notify(notify.Error(
+			"payments.access_check_failed",
+			"error checking devices",
+			notify.F("email", email),
+			notify.F("err", err),
+		))
+		return true, nil
+	}
+
+	return !exceeded, nil
 }
 
 func (s *service) User(
@@ -86,7 +166,8 @@ func (s *service) User(
 	email string,
 	projectID string,
 ) (*domain.User, error) {
-	if s.normalizeStr(projectID) != s.normalizeStr(s.tildaProjectID) {
+	project, ok := s.projects.ByID(projectID)
+	if !ok {
 		return nil, ErrInvalidProjectId
 	}
 
@@ -97,7 +178,60 @@ func (s *service) User(
 
 	user, err := s.repo.ByEmail(ctx, email)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[UserInfo]: error fetching user by email (%s): %v", email, err))
+		s.notif.Notify(notify.Error(
+			"payments.user_info_failed",
+			"error fetching user by email",
+			notify.F("email", email),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+
+	user, err = s.projectView(ctx, user, project)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.user_info_failed",
+			"error fetching project access",
+			notify.F("project", project.ID),
+			notify.F("email", email),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *service) UserByID(
+	ctx context.Context,
+	userID string,
+	projectID string,
+) (*domain.User, error) {
+	project, ok := s.projects.ByID(projectID)
+	if !ok {
+		return nil, ErrInvalidProjectId
+	}
+
+	user, err := s.repo.ByID(ctx, userID)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.user_info_failed",
+			"error fetching user by id",
+			notify.F("user_id", userID),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+
+	user, err = s.projectView(ctx, user, project)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.user_info_failed",
+			"error fetching project access",
+			notify.F("project", project.ID),
+			notify.F("user_id", userID),
+			notify.F("err", err),
+		))
 		return nil, err
 	}
 
@@ -108,20 +242,63 @@ func (s *service) RegisterFromTrial(
 	ctx context.Context,
 	email string,
 	name string,
-	trialDuration time.Duration,
+	campaign string,
+	source string,
+	fingerprint string,
 ) error {
 	emailNorm := s.normalizeStr(email)
 
+	trialDuration, err := s.trialPolicy.Duration(ctx, emailNorm, campaign, source)
+	if err != nil {
+		s.notif.Notify(notify.Warning(
+			"payments.trial_rejected",
+			"trial request rejected",
+			notify.F("email", emailNorm),
+			notify.F("reason", err),
+		))
+		return err
+	}
+
 	user, err := s.repo.ByEmail(ctx, emailNorm)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromTrial]: error fetching user by email (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.trial_registration_failed",
+			"error fetching user by email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return err
 	}
 	if user != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromTrial]: user (%s) already registered, skipping trial registration", emailNorm))
+		s.notif.Notify(notify.Info(
+			"payments.trial_already_registered",
+			"user already registered, skipping trial registration",
+			notify.F("email", emailNorm),
+		))
 		return ErrUserAlreadyRegistered
 	}
 
+	verdict, err := s.trialAbuse.Check(ctx, emailNorm, fingerprint, trialDuration)
+	if err != nil {
+		// fail open, abuse check must not block legit registrations, partial verdict is not trusted
+		verdict = trials.Verdict{Action: domain.TrialActionAllow}
+		s.notif.Notify(notify.Error(
+			"payments.trial_abuse_check_failed",
+			"trial abuse check failed, trial is allowed without the check",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+	}
+	if verdict.Action != domain.TrialActionAllow {
+		s.notif.Notify(notify.Warning("payments.trial_suspicious", s.trialVerdictMsg(emailNorm, verdict)))
+	}
+	if verdict.Action == domain.TrialActionDeny {
+		return ErrTrialDenied
+	}
+	if verdict.Duration > 0 {
+		trialDuration = verdict.Duration
+	}
+
 	user = &domain.User{
 		ID:                 uuid.New().String(),
 		Email:              emailNorm,
@@ -135,21 +312,48 @@ func (s *service) RegisterFromTrial(
 
 	_, err = s.repo.Create(ctx, *user)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromTrial]: error creating user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.trial_registration_failed",
+			"error creating user",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return err
 	}
 
 	err = s.emails.ScheduleAfterTrialExpired(ctx, emailNorm)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromTrial]: error scheduling email after trial expired for email (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error scheduling email after trial expired",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return err
 	}
 
-	s.notif.ForAdmin(
-		fmt.Sprintf("[RegisterFromTrial]: user (%s) registered for trial, duration = %d days",
-			emailNorm,
-			int(trialDuration.Hours()/24),
+	err = s.emails.SendTrialStarted(ctx, *user)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error sending trial started email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
 		))
+	}
+
+	s.recordBilling(ctx, "RegisterFromTrial", domain.BillingEvent{
+		Kind:   domain.BillingEventTrial,
+		Email:  emailNorm,
+		Tariff: domain.UserTariffTrial,
+	})
+
+	s.notif.Notify(notify.Info(
+		"payments.trial_registered",
+		"user registered for trial",
+		notify.F("email", emailNorm),
+		notify.F("days", int(trialDuration.Hours()/24)),
+	))
 
 	return nil
 }
@@ -161,21 +365,30 @@ func (s *service) RegisterFromProdamus(
 	name string,
 	price float32,
 	orderID string,
+	projectID string,
 ) error {
 	emailNorm := s.normalizeStr(email)
 	subNorm := s.normalizeStr(subName)
 
-	var tariffName string
-	if slices.Contains(oneMonthSubNames, subNorm) {
-		tariffName = domain.UserTariff1Month
-	} else if slices.Contains(threeMonthsSubNames, subNorm) {
-		tariffName = domain.UserTariff3Months
-	} else if slices.Contains(sixMonthsSubNames, subNorm) {
-		tariffName = domain.UserTariff6Months
+	tariffName := s.tariffBySubName(subNorm)
+	if tariffName == "" {
+		s.notif.Notify(notify.Warning(
+			"payments.unknown_tariff",
+			"unknown tariff, user subscription is not updated",
+			notify.F("tariff", subNorm),
+			notify.F("email", email),
+		))
+		return nil
 	}
 
-	if tariffName == "" {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromProdamus]: unknown tariff (%s), can not update user %s subscription", subNorm, email))
+	project, ok := s.projects.Resolve(projectID)
+	if !ok {
+		s.notif.Notify(notify.Warning(
+			"payments.unknown_project",
+			"unknown project, user subscription is not updated",
+			notify.F("project", projectID),
+			notify.F("email", email),
+		))
 		return nil
 	}
 
@@ -192,27 +405,55 @@ func (s *service) RegisterFromProdamus(
 
 	user, err := s.repo.FindByEmailOrCreate(ctx, candidate)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromProdamus]: error finding or creating user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.prodamus_payment_failed",
+			"error finding or creating user",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return err
 	}
 	if user == nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromProdamus]: failed to find or create user (%s)", emailNorm))
+		s.notif.Notify(notify.Error(
+			"payments.prodamus_payment_failed",
+			"user is neither found nor created",
+			notify.F("email", emailNorm),
+		))
 		return fmt.Errorf("failed to find or create user")
 	}
 
 	if orderID != "" && user.LastTransactionID == orderID {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromProdamus]: duplicate webhook for user (%s), orderID=%s, skipping", emailNorm, orderID))
+		s.notif.Notify(notify.Info(
+			"payments.duplicate_webhook",
+			"duplicate prodamus webhook, skipping",
+			notify.F("email", emailNorm),
+			notify.F("order_id", orderID),
+		))
 		return nil
 	}
 
+	if !project.Default {
+		return s.registerEntitlement(ctx, "RegisterFromProdamus", domain.PaymentProviderProdamus, project, user, tariffName, price, orderID, "")
+	}
+
 	err = s.emails.CancelTrialExpired(ctx, user.Email)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromProdamus]: error canceling trial for email (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error canceling trial expired email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 	}
 
 	newExpiresAt, err := user.NewSubEndedAt(time.Now(), tariffName)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromProdamus]: failed to calculate new expiration date for user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.prodamus_payment_failed",
+			"error calculating new expiration date",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return nil
 	}
 
@@ -225,16 +466,60 @@ func (s *service) RegisterFromProdamus(
 
 	_, err = s.repo.UpdateSub(ctx, *user)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromProdamus]: error updating user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.prodamus_payment_failed",
+			"error updating user",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return err
 	}
 
-	s.notif.ForAdmin(fmt.Sprintf(
-		"[RegisterFromProdamus]: user payment is accepted (%s), tariff: %s, expires at: %s",
-		emailNorm,
-		tariffName,
-		newExpiresAt.Format(time.RFC3339),
-	))
+	err = s.referrals.OnPaidPurchase(ctx, user.Email, price)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.prodamus_payment_failed",
+			"error rewarding referrer",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+	}
+
+	payment := domain.Payment{
+		Provider:      domain.PaymentProviderProdamus,
+		TransactionID: orderID,
+		Tariff:        tariffName,
+		Amount:        price,
+		PaidAt:        time.Now(),
+	}
+	err = s.emails.SendPaymentReceipt(ctx, *user, payment)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error sending receipt",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+	}
+	s.sendMemberReceipt(ctx, "RegisterFromProdamus", *user, payment)
+
+	s.recordBilling(ctx, "RegisterFromProdamus", domain.BillingEvent{
+		Kind:          domain.BillingEventPayment,
+		Email:         emailNorm,
+		Provider:      domain.PaymentProviderProdamus,
+		TransactionID: orderID,
+		Tariff:        tariffName,
+		Amount:        price,
+	})
+
+	s.notif.Notify(notify.Info(
+		"payments.accepted",
+		"user payment is accepted",
+		notify.F("provider", "prodamus"),
+		notify.F("email", emailNorm),
+		notify.F("tariff", tariffName),
+		notify.F("expires_at", newExpiresAt.Format(time.RFC3339)),
+	).WithActions(notify.UserActions(user.ID, false)...))
 
 	return nil
 }
@@ -247,26 +532,50 @@ func (s *service) RegisterFromCloudPayments(
 	price float32,
 	subscriptionID string,
 	transactionID string,
+	projectID string,
 ) error {
 	subscriptionID = strings.TrimSpace(subscriptionID)
 	emailNorm := s.normalizeStr(email)
 	subNorm := s.normalizeStr(subName)
 
-	var tariffName string
-	var subInterval int
-	if slices.Contains(oneMonthSubNames, subNorm) {
-		tariffName = domain.UserTariff1Month
-		subInterval = 1
-	} else if slices.Contains(threeMonthsSubNames, subNorm) {
-		tariffName = domain.UserTariff3Months
-		subInterval = 3
-	} else if slices.Contains(sixMonthsSubNames, subNorm) {
-		tariffName = domain.UserTariff6Months
-		subInterval = 6
-	}
+	tariffName := s.tariffBySubName(subNorm)
+	subInterval := domain.TariffMonths(tariffName)
 
 	if tariffName == "" || subInterval == 0 {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: unknown tariff (%s), can not update user %s subscription", subNorm, email))
+		s.notif.Notify(notify.Warning(
+			"payments.unknown_tariff",
+			"unknown tariff, user subscription is not updated",
+			notify.F("tariff", subNorm),
+			notify.F("email", email),
+		))
+		return nil
+	}
+
+	// recurrent charges come without widget data, project is resolved by the subscription then
+	if strings.TrimSpace(projectID) == "" && subscriptionID != "" {
+		ent, err := s.entitlements.BySubscriptionID(ctx, subscriptionID)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.cloudpayments_payment_failed",
+				"error fetching entitlement by subscription",
+				notify.F("subscription_id", subscriptionID),
+				notify.F("err", err),
+			))
+			return err
+		}
+		if ent != nil {
+			projectID = ent.ProjectID
+		}
+	}
+
+	project, ok := s.projects.Resolve(projectID)
+	if !ok {
+		s.notif.Notify(notify.Warning(
+			"payments.unknown_project",
+			"unknown project, user subscription is not updated",
+			notify.F("project", projectID),
+			notify.F("email", email),
+		))
 		return nil
 	}
 
@@ -282,27 +591,55 @@ func (s *service) RegisterFromCloudPayments(
 	}
 	user, err := s.repo.FindByEmailOrCreate(ctx, candidate)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: error finding or creating user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.cloudpayments_payment_failed",
+			"error finding or creating user",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return err
 	}
 	if user == nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: failed to find or create user (%s)", emailNorm))
+		s.notif.Notify(notify.Error(
+			"payments.cloudpayments_payment_failed",
+			"user is neither found nor created",
+			notify.F("email", emailNorm),
+		))
 		return fmt.Errorf("failed to find or create user")
 	}
 
 	if transactionID != "" && user.LastTransactionID == transactionID {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: duplicate webhook for user (%s), transactionID=%s, skipping", emailNorm, transactionID))
+		s.notif.Notify(notify.Info(
+			"payments.duplicate_webhook",
+			"duplicate cloudpayments webhook, skipping",
+			notify.F("email", emailNorm),
+			notify.F("transaction_id", transactionID),
+		))
 		return nil
 	}
 
+	if !project.Default {
+		return s.registerEntitlement(ctx, "RegisterFromCloudPayments", domain.PaymentProviderCloudPayments, project, user, tariffName, price, transactionID, subscriptionID)
+	}
+
 	err = s.emails.CancelTrialExpired(ctx, user.Email)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: error canceling trial for email (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error canceling trial expired email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 	}
 
 	newExpiresAt, err := user.NewSubEndedAt(time.Now(), tariffName)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: failed to calculate new expiration date for user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.cloudpayments_payment_failed",
+			"error calculating new expiration date",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return nil
 	}
 
@@ -310,7 +647,12 @@ func (s *service) RegisterFromCloudPayments(
 
 	subStatus, err := s.cloudPayments.UpdateSubscription(ctx, subscriptionID, newExpiresAt, "Month", subInterval)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: error updating subscription for user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.cloudpayments_payment_failed",
+			"error updating subscription",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return err
 	}
 
@@ -323,30 +665,98 @@ func (s *service) RegisterFromCloudPayments(
 
 	_, err = s.repo.UpdateSub(ctx, *user)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: error updating user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.cloudpayments_payment_failed",
+			"error updating user",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 		return err
 	}
 
+	err = s.referrals.OnPaidPurchase(ctx, user.Email, price)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.cloudpayments_payment_failed",
+			"error rewarding referrer",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+	}
+
 	if oldSubId != "" && oldSubId != strings.TrimSpace(subscriptionID) {
 		err := s.cloudPayments.CancelSubscription(ctx, oldSubId)
 		if err != nil {
-			s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: error canceling old subscription for user (%s): %v", emailNorm, err))
+			s.notif.Notify(notify.Error(
+				"payments.cloudpayments_payment_failed",
+				"error canceling old subscription, cancel manually",
+				notify.F("email", emailNorm),
+				notify.F("err", err),
+			))
 		}
 	}
 
+	payment := domain.Payment{
+		Provider:      domain.PaymentProviderCloudPayments,
+		TransactionID: transactionID,
+		Tariff:        tariffName,
+		Amount:        price,
+		PaidAt:        time.Now(),
+	}
+	billingKind := domain.BillingEventPayment
 	if oldSubId != "" && oldSubId == strings.TrimSpace(subscriptionID) {
+		billingKind = domain.BillingEventRenewal
+	}
+	s.recordBilling(ctx, "RegisterFromCloudPayments", domain.BillingEvent{
+		Kind:          billingKind,
+		Email:         emailNorm,
+		Provider:      domain.PaymentProviderCloudPayments,
+		TransactionID: transactionID,
+		Tariff:        tariffName,
+		Amount:        price,
+	})
+
+	if billingKind == domain.BillingEventRenewal {
 		err := s.emails.ScheduleAfterReccurrentPayment(ctx, user.Email)
 		if err != nil {
-			s.notif.ForAdmin(fmt.Sprintf("[RegisterFromCloudPayments]: error scheduling after recurrent email for user (%s): %v", emailNorm, err))
+			s.notif.Notify(notify.Error(
+				"payments.email_failed",
+				"error scheduling after recurrent payment email",
+				notify.F("email", emailNorm),
+				notify.F("err", err),
+			))
+		}
+
+		err = s.emails.SendRenewalSucceeded(ctx, *user, payment)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.email_failed",
+				"error sending renewal email",
+				notify.F("email", emailNorm),
+				notify.F("err", err),
+			))
+		}
+	} else {
+		err := s.emails.SendPaymentReceipt(ctx, *user, payment)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"payments.email_failed",
+				"error sending receipt",
+				notify.F("email", emailNorm),
+				notify.F("err", err),
+			))
 		}
 	}
+	s.sendMemberReceipt(ctx, "RegisterFromCloudPayments", *user, payment)
 
-	s.notif.ForAdmin(fmt.Sprintf(
-		"[RegisterFromCloudPayments]: user payment is accepted (%s), tariff: %s, expires at: %s",
-		emailNorm,
-		tariffName,
-		newExpiresAt.Format(time.RFC3339),
-	))
+	s.notif.Notify(notify.Info(
+		"payments.accepted",
+		"user payment is accepted",
+		notify.F("provider", "cloudpayments"),
+		notify.F("email", emailNorm),
+		notify.F("tariff", tariffName),
+		notify.F("expires_at", newExpiresAt.Format(time.RFC3339)),
+	).WithActions(notify.UserActions(user.ID, subscriptionID != "")...))
 
 	return nil
 }
@@ -362,7 +772,11 @@ func (s *service) RegisterCloudPaymentReccurent(
 	emailNorm := s.normalizeStr(email)
 
 	if subscriptionID == "" {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterCloudPaymentReccurent]: cloud payment recurrent notification for user (%s): subscription ID is empty", emailNorm))
+		s.notif.Notify(notify.Warning(
+			"payments.recurrent_without_subscription",
+			"recurrent notification has empty subscription id",
+			notify.F("email", emailNorm),
+		))
 
 		return nil
 	}
@@ -370,24 +784,141 @@ func (s *service) RegisterCloudPaymentReccurent(
 	if status == "Cancelled" {
 		err := s.emails.ScheduleAfterAutopaymentCancelled(ctx, emailNorm)
 		if err != nil {
-			s.notif.ForAdmin(fmt.Sprintf("[RegisterCloudPaymentReccurent]: error scheduling after autopayment cancelled email for user (%s): %v", emailNorm, err))
+			s.notif.Notify(notify.Error(
+				"payments.email_failed",
+				"error scheduling after autopayment cancelled email",
+				notify.F("email", emailNorm),
+				notify.F("err", err),
+			))
 		}
+
+		s.recordBilling(ctx, "RegisterCloudPaymentReccurent", domain.BillingEvent{
+			Kind:          domain.BillingEventCancellation,
+			Email:         emailNorm,
+			Provider:      domain.PaymentProviderCloudPayments,
+			TransactionID: subscriptionID,
+		})
 	}
 
 	err := s.repo.UpdateSubStatusBySubID(ctx, subscriptionID, status)
 	if err != nil {
-		s.notif.ForAdmin(fmt.Sprintf("[RegisterCloudPaymentReccurent]: error updating subscription status for user (%s): %v", emailNorm, err))
+		s.notif.Notify(notify.Error(
+			"payments.cloudpayments_recurrent_failed",
+			"error updating subscription status",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
 	}
 
-	s.notif.ForAdmin(fmt.Sprintf(
-		"[RegisterCloudPaymentReccurent]: recurrent notification accepted for user (%s), status = %s",
-		emailNorm,
-		status,
+	if status == domain.UserSubStatusPastDue || status == domain.UserSubStatusRejected {
+		s.sendPaymentFailed(ctx, emailNorm)
+	}
+
+	severity := notify.SeverityInfo
+	if status == domain.UserSubStatusPastDue || status == domain.UserSubStatusRejected {
+		severity = notify.SeverityWarning
+	}
+	s.notif.Notify(notify.New(
+		severity,
+		"payments.recurrent",
+		"recurrent notification accepted",
+		notify.F("email", emailNorm),
+		notify.F("status", status),
 	))
 
 	return nil
 }
 
+// recordBilling keeps the ledger for business reports, failures do not affect the payment.
+func (s *service) recordBilling(ctx context.Context, logPrefix string, e domain.BillingEvent) {
+	err := s.billing.Record(ctx, e)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.billing_record_failed",
+			"error recording billing event",
+			notify.F("kind", e.Kind),
+			notify.F("email", e.Email),
+			notify.F("err", err),
+			notify.F("flow", logPrefix),
+		))
+	}
+}
+
+func (s *service) sendMemberReceipt(ctx context.Context, logPrefix string, user domain.User, payment domain.Payment) {
+	err := s.members.SendPaymentReceipt(ctx, user, payment)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.telegram_receipt_failed",
+			"error sending telegram receipt",
+			notify.F("email", user.Email),
+			notify.F("err", err),
+			notify.F("flow", logPrefix),
+		))
+	}
+}
+
+func (s *service) sendPaymentFailed(ctx context.Context, emailNorm string) {
+	user, err := s.repo.ByEmail(ctx, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error fetching user for payment failed email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return
+	}
+	if user == nil {
+		return
+	}
+
+	err = s.emails.SendPaymentFailed(ctx, *user)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"payments.email_failed",
+			"error sending payment failed email",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+	}
+}
+
+func (s *service) trialVerdictMsg(emailNorm string, verdict trials.Verdict) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[RegisterFromTrial]: suspicious trial registration (%s), action = %s, reasons: %s",
+		emailNorm,
+		verdict.Action,
+		strings.Join(verdict.Reasons, "; "),
+	)
+
+	if len(verdict.Linked) > 0 {
+		b.WriteString("\nlinked accounts:")
+		for _, l := range verdict.Linked {
+			fmt.Fprintf(&b, "\n- %s, tariff: %s, expires at: %s, shared fingerprints: %d, shared ips: %d",
+				l.Email,
+				l.Tariff,
+				l.ExpiresAt.Format(time.RFC3339),
+				l.SharedFingerprints,
+				l.SharedIPs,
+			)
+		}
+	}
+
+	return b.String()
+}
+
+func (s *service) tariffBySubName(subNorm string) string {
+	if slices.Contains(oneMonthSubNames, subNorm) {
+		return domain.UserTariff1Month
+	} else if slices.Contains(threeMonthsSubNames, subNorm) {
+		return domain.UserTariff3Months
+	} else if slices.Contains(sixMonthsSubNames, subNorm) {
+		return domain.UserTariff6Months
+	}
+
+	return ""
+}
+
 func (s *service) normalizeStr(email string) string {
 	return strings.ToLower(strings.TrimSpace(email))
 }
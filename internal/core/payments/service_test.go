@@ -0,0 +1,105 @@
+//go:build unit
+// +build unit
+
+package payments
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"doctormakarhina/lumos/internal/core/trials"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type trialUsersStub struct {
+	UserRepo
+	created []domain.User
+}
+
+func (r *trialUsersStub) ByEmail(_ context.Context, _ string) (*domain.User, error) {
+	return nil, nil
+}
+
+func (r *trialUsersStub) Create(_ context.Context, user domain.User) (*domain.User, error) {
+	r.created = append(r.created, user)
+	return &user, nil
+}
+
+type trialPolicyStub struct{}
+
+func (trialPolicyStub) Duration(_ context.Context, _ string, _ string, _ string) (time.Duration, error) {
+	return 7 * 24 * time.Hour, nil
+}
+
+type trialAbuseStub struct {
+	verdict trials.Verdict
+	err     error
+}
+
+func (r trialAbuseStub) Check(_ context.Context, _ string, _ string, _ time.Duration) (trials.Verdict, error) {
+	return r.verdict, r.err
+}
+
+type trialEmailsStub struct {
+	EmailsSrv
+}
+
+func (trialEmailsStub) ScheduleAfterTrialExpired(_ context.Context, _ string) error {
+	return nil
+}
+
+func (trialEmailsStub) SendTrialStarted(_ context.Context, _ domain.User) error {
+	return nil
+}
+
+type billingStub struct{}
+
+func (billingStub) Record(_ context.Context, _ domain.BillingEvent) error {
+	return nil
+}
+
+type notifyStub struct {
+	sent []notify.Notification
+}
+
+func (r *notifyStub) ForAdmin(msg string) {
+	r.sent = append(r.sent, notify.FromLegacy(msg))
+}
+
+func (r *notifyStub) Notify(n notify.Notification) {
+	r.sent = append(r.sent, n)
+}
+
+func TestRegisterFromTrialAllowsOnAbuseCheckError(t *testing.T) {
+	users := &trialUsersStub{}
+	notif := &notifyStub{}
+	s := &service{
+		repo:        users,
+		billing:     billingStub{},
+		trialPolicy: trialPolicyStub{},
+		trialAbuse: trialAbuseStub{
+			// partial verdict returned along with the error must be ignored
+			verdict: trials.Verdict{Action: domain.TrialActionDeny, Duration: time.Hour},
+			err:     errors.New("db is down"),
+		},
+		emails: trialEmailsStub{},
+		notif:  notif,
+	}
+
+	err := s.RegisterFromTrial(context.Background(), "User@Example.com", "User", "", "", "fp")
+	require.NoError(t, err)
+
+	require.Len(t, users.created, 1)
+	require.Equal(t, "user@example.com", users.created[0].Email)
+	require.WithinDuration(t, time.Now().Add(7*24*time.Hour), users.created[0].ExpiresAt, time.Minute)
+
+	events := make([]string, 0, len(notif.sent))
+	for _, n := range notif.sent {
+		events = append(events, n.Event)
+	}
+	require.Contains(t, events, "payments.trial_abuse_check_failed")
+}
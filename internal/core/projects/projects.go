@@ -0,0 +1,113 @@
+package projects
+
+import (
+	"doctormakarhina/lumos/internal/core/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Registry is a fixed set of projects loaded on start.
+type Registry struct {
+	byID     map[string]domain.Project
+	ordered  []domain.Project
+	defaultP domain.Project
+}
+
+func NewRegistry(projects []domain.Project) (*Registry, error) {
+	if len(projects) == 0 {
+		return nil, errors.New("projects registry is empty")
+	}
+
+	r := &Registry{
+		byID:    make(map[string]domain.Project, len(projects)),
+		ordered: make([]domain.Project, 0, len(projects)),
+	}
+
+	defaults := 0
+	for _, p := range projects {
+		p.ID = strings.TrimSpace(p.ID)
+		if p.ID == "" {
+			return nil, fmt.Errorf("project %q has empty id", p.Name)
+		}
+
+		key := normalize(p.ID)
+		if _, ok := r.byID[key]; ok {
+			return nil, fmt.Errorf("duplicate project id %s", p.ID)
+		}
+
+		if p.Default {
+			defaults++
+			r.defaultP = p
+		}
+
+		r.byID[key] = p
+		r.ordered = append(r.ordered, p)
+	}
+
+	if defaults != 1 {
+		return nil, fmt.Errorf("exactly one default project is required, got %d", defaults)
+	}
+
+	return r, nil
+}
+
+// LoadFile reads projects from json file with an array of projects.
+func LoadFile(path string) ([]domain.Project, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects file: %w", err)
+	}
+
+	var projects []domain.Project
+	err = json.Unmarshal(raw, &projects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse projects file: %w", err)
+	}
+
+	return projects, nil
+}
+
+func (r *Registry) ByID(id string) (domain.Project, bool) {
+	p, ok := r.byID[normalize(id)]
+	return p, ok
+}
+
+// Resolve returns project by id, empty id resolves to the default project.
+func (r *Registry) Resolve(id string) (domain.Project, bool) {
+	if strings.TrimSpace(id) == "" {
+		return r.defaultP, true
+	}
+
+	return r.ByID(id)
+}
+
+func (r *Registry) Default() domain.Project {
+	return r.defaultP
+}
+
+func (r *Registry) All() []domain.Project {
+	return slices.Clone(r.ordered)
+}
+
+// CorsOrigins returns unique origins of all projects.
+func (r *Registry) CorsOrigins() []string {
+	origins := make([]string, 0)
+	for _, p := range r.ordered {
+		for _, o := range p.CorsOrigins {
+			o = strings.TrimSpace(o)
+			if o != "" && !slices.Contains(origins, o) {
+				origins = append(origins, o)
+			}
+		}
+	}
+
+	return origins
+}
+
+func normalize(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
@@ -0,0 +1,49 @@
+//go:build unit
+// +build unit
+
+package projects
+
+import (
+	"doctormakarhina/lumos/internal/core/domain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	r, err := NewRegistry([]domain.Project{
+		{ID: "main", Name: "Lumos", CorsOrigins: []string{"https://a.ru", "https://b.ru"}, Default: true},
+		{ID: "Second", Name: "Second", AllowedTariffs: []string{"6 месяцев"}, CorsOrigins: []string{"https://b.ru", "https://c.ru"}},
+	})
+	require.NoError(t, err)
+
+	p, ok := r.ByID(" second ")
+	require.True(t, ok)
+	require.Equal(t, "Second", p.ID)
+	require.True(t, p.AllowsTariff("6 Месяцев"))
+	require.False(t, p.AllowsTariff("1 месяц"))
+
+	_, ok = r.ByID("")
+	require.False(t, ok)
+
+	p, ok = r.Resolve("")
+	require.True(t, ok)
+	require.Equal(t, "main", p.ID)
+	require.True(t, p.AllowsTariff("anything"))
+
+	require.Equal(t, []string{"https://a.ru", "https://b.ru", "https://c.ru"}, r.CorsOrigins())
+}
+
+func TestRegistryValidation(t *testing.T) {
+	_, err := NewRegistry(nil)
+	require.Error(t, err)
+
+	_, err = NewRegistry([]domain.Project{{ID: "a"}})
+	require.Error(t, err)
+
+	_, err = NewRegistry([]domain.Project{{ID: "a", Default: true}, {ID: "A"}})
+	require.Error(t, err)
+
+	_, err = NewRegistry([]domain.Project{{ID: "a", Default: true}, {ID: "b", Default: true}})
+	require.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package referrals
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+)
+
+type UserRepo interface {
+	ByEmail(ctx context.Context, email string) (*domain.User, error)
+	ByID(ctx context.Context, id string) (*domain.User, error)
+	ExtendExpiresAt(ctx context.Context, id string, d time.Duration) (*domain.User, error)
+}
+
+// CloudPayments moves the next charge of the referrer recurrent payments after the reward.
+type CloudPayments interface {
+	UpdateSubscription(ctx context.Context, ID string, startDate time.Time, inteval string, period int) (string, error)
+}
+
+type ReferralRepo interface {
+	CodeByUserID(ctx context.Context, userID string) (string, error)
+	CreateCode(ctx context.Context, userID string, code string) (string, error)
+	UserIDByCode(ctx context.Context, code string) (string, error)
+	Create(ctx context.Context, referral domain.Referral) (bool, error)
+	PendingByRefereeEmail(ctx context.Context, email string) (*domain.Referral, error)
+	CountRewardedSince(ctx context.Context, referrerID string, since time.Time) (int, error)
+	Reject(ctx context.Context, id string, reason string) (bool, error)
+	MarkRewarded(ctx context.Context, id string, rewardDays int, reason string) (bool, error)
+}
+
+type AuthLogRepo interface {
+	LinkedLogins(ctx context.Context, login string, since time.Time) ([]domain.LinkedLogin, error)
+}
+
+type Service interface {
+	// Code returns referral code of the user, creating it on the first call.
+	Code(ctx context.Context, user domain.User) (string, error)
+	// Attribute links referee to the owner of the referral code.
+	Attribute(ctx context.Context, refereeEmail string, code string, source string) error
+	// OnPaidPurchase rewards referrer when the referee makes the first paid purchase.
+	OnPaidPurchase(ctx context.Context, refereeEmail string, price float32) error
+}
@@ -0,0 +1,350 @@
+package referrals
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const rewardsLimitPeriod = 30 * 24 * time.Hour
+
+type Config struct {
+	// RewardDays is amount of days added to referrer access.
+	RewardDays int
+	// MaxRewardsPerMonth limits rewards of a single referrer during last 30 days.
+	MaxRewardsPerMonth int
+	// FingerprintWindow is how far back auth telemetry is inspected for anti-abuse checks.
+	FingerprintWindow time.Duration
+}
+
+type service struct {
+	users         UserRepo
+	referrals     ReferralRepo
+	authLogs      AuthLogRepo
+	cloudPayments CloudPayments
+	notif         notify.Service
+	cfg           Config
+}
+
+func NewReferralsService(
+	users UserRepo,
+	referrals ReferralRepo,
+	authLogs AuthLogRepo,
+	cloudPayments CloudPayments,
+	notif notify.Service,
+	cfg Config,
+) Service {
+	return &service{
+		users:         users,
+		referrals:     referrals,
+		authLogs:      authLogs,
+		cloudPayments: cloudPayments,
+		notif:         notif,
+		cfg:           cfg,
+	}
+}
+
+func (s *service) Code(ctx context.Context, user domain.User) (string, error) {
+	code, err := s.referrals.CodeByUserID(ctx, user.ID)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.code_failed",
+			"error fetching referral code",
+			notify.F("email", user.Email),
+			notify.F("err", err),
+		))
+		return "", err
+	}
+	if code != "" {
+		return code, nil
+	}
+
+	code, err = domain.NewReferralCode()
+	if err != nil {
+		return "", err
+	}
+
+	code, err = s.referrals.CreateCode(ctx, user.ID, code)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.code_failed",
+			"error creating referral code",
+			notify.F("email", user.Email),
+			notify.F("err", err),
+		))
+		return "", err
+	}
+
+	return code, nil
+}
+
+func (s *service) Attribute(
+	ctx context.Context,
+	refereeEmail string,
+	code string,
+	source string,
+) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	emailNorm := strings.ToLower(strings.TrimSpace(refereeEmail))
+	if code == "" || emailNorm == "" {
+		return nil
+	}
+
+	referrerID, err := s.referrals.UserIDByCode(ctx, code)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.attribution_failed",
+			"error fetching referral code",
+			notify.F("code", code),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if referrerID == "" {
+		s.notif.Notify(notify.Warning(
+			"referrals.unknown_code",
+			"unknown referral code, skipping",
+			notify.F("code", code),
+			notify.F("email", emailNorm),
+		))
+		return nil
+	}
+
+	referrer, err := s.users.ByID(ctx, referrerID)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.attribution_failed",
+			"error fetching referrer",
+			notify.F("referrer_id", referrerID),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if referrer == nil {
+		return nil
+	}
+	if referrer.Email == emailNorm {
+		s.notif.Notify(notify.Info(
+			"referrals.own_code",
+			"user tried to use own referral code, skipping",
+			notify.F("email", emailNorm),
+		))
+		return nil
+	}
+
+	referee, err := s.users.ByEmail(ctx, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.attribution_failed",
+			"error fetching referee",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if referee != nil && referee.LastSubPrice > 0 {
+		s.notif.Notify(notify.Info(
+			"referrals.paying_referee",
+			"user is already a paying customer, referral skipped",
+			notify.F("email", emailNorm),
+			notify.F("referrer", referrer.Email),
+		))
+		return nil
+	}
+
+	created, err := s.referrals.Create(ctx, domain.Referral{
+		ID:           uuid.New().String(),
+		ReferrerID:   referrer.ID,
+		RefereeEmail: emailNorm,
+		Source:       source,
+		Status:       domain.ReferralStatusPending,
+	})
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.attribution_failed",
+			"error saving referral",
+			notify.F("email", emailNorm),
+			notify.F("referrer", referrer.Email),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if !created {
+		return nil
+	}
+
+	s.notif.Notify(notify.Info(
+		"referrals.attributed",
+		"user invited by referrer",
+		notify.F("email", emailNorm),
+		notify.F("referrer", referrer.Email),
+		notify.F("source", source),
+	))
+
+	return nil
+}
+
+func (s *service) OnPaidPurchase(ctx context.Context, refereeEmail string, price float32) error {
+	emailNorm := strings.ToLower(strings.TrimSpace(refereeEmail))
+	if price <= 0 || emailNorm == "" {
+		return nil
+	}
+
+	referral, err := s.referrals.PendingByRefereeEmail(ctx, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.reward_failed",
+			"error fetching referral",
+			notify.F("referee", emailNorm),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if referral == nil {
+		return nil
+	}
+
+	referrer, err := s.users.ByID(ctx, referral.ReferrerID)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.reward_failed",
+			"error fetching referrer",
+			notify.F("referrer_id", referral.ReferrerID),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if referrer == nil {
+		return s.reject(ctx, referral, "referrer not found")
+	}
+
+	now := time.Now()
+
+	linked, err := s.authLogs.LinkedLogins(ctx, emailNorm, now.Add(-s.cfg.FingerprintWindow))
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.reward_failed",
+			"error fetching linked logins",
+			notify.F("referee", emailNorm),
+			notify.F("err", err),
+		))
+		return err
+	}
+
+	reason := ""
+	i := slices.IndexFunc(linked, func(l domain.LinkedLogin) bool { return l.Login == referrer.Email })
+	if i != -1 {
+		if linked[i].SharedFingerprints > 0 {
+			return s.reject(ctx, referral, fmt.Sprintf("referee (%s) and referrer (%s) share %d device fingerprint(s)", emailNorm, referrer.Email, linked[i].SharedFingerprints))
+		}
+		// shared ip alone is common for families and offices, reward but keep a note for admins
+		reason = fmt.Sprintf("shared %d ip address(es)", linked[i].SharedIPs)
+	}
+
+	if s.cfg.MaxRewardsPerMonth > 0 {
+		rewarded, err := s.referrals.CountRewardedSince(ctx, referrer.ID, now.Add(-rewardsLimitPeriod))
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"referrals.reward_failed",
+				"error counting rewards",
+				notify.F("referrer", referrer.Email),
+				notify.F("err", err),
+			))
+			return err
+		}
+		if rewarded >= s.cfg.MaxRewardsPerMonth {
+			return s.reject(ctx, referral, fmt.Sprintf("referrer (%s) reached monthly rewards limit (%d)", referrer.Email, s.cfg.MaxRewardsPerMonth))
+		}
+	}
+
+	marked, err := s.referrals.MarkRewarded(ctx, referral.ID, s.cfg.RewardDays, reason)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.reward_failed",
+			"error marking referral as rewarded",
+			notify.F("referee", emailNorm),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if !marked {
+		return nil
+	}
+
+	updated, err := s.users.ExtendExpiresAt(ctx, referrer.ID, time.Duration(s.cfg.RewardDays)*24*time.Hour)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.reward_failed",
+			"error extending access of referrer, extend manually",
+			notify.F("referrer", referrer.Email),
+			notify.F("days", s.cfg.RewardDays),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if updated == nil {
+		s.notif.Notify(notify.Warning(
+			"referrals.reward_not_applied",
+			"referrer has unlimited tariff, reward is not applied",
+			notify.F("referrer", referrer.Email),
+		))
+		return nil
+	}
+
+	// referrer with active autopayment is charged after the rewarded period, as for gifts
+	subMonths := domain.TariffMonths(updated.Tariff)
+	if updated.SubscriptionID != "" && updated.SubscriptionStatus == domain.UserSubStatusActive && subMonths > 0 {
+		_, err = s.cloudPayments.UpdateSubscription(ctx, updated.SubscriptionID, updated.ExpiresAt, "Month", subMonths)
+		if err != nil {
+			s.notif.Notify(notify.Error(
+				"referrals.reward_failed",
+				"error moving subscription start date of referrer, move manually",
+				notify.F("referrer", referrer.Email),
+				notify.F("start_date", updated.ExpiresAt.Format(time.RFC3339)),
+				notify.F("err", err),
+			))
+			return err
+		}
+	}
+
+	fields := []notify.Field{
+		notify.F("referrer", referrer.Email),
+		notify.F("days", s.cfg.RewardDays),
+		notify.F("referee", emailNorm),
+		notify.F("expires_at", updated.ExpiresAt.Format(time.RFC3339)),
+	}
+	if reason != "" {
+		fields = append(fields, notify.F("note", reason))
+	}
+	s.notif.Notify(notify.Info("referrals.rewarded", "referrer rewarded", fields...))
+
+	return nil
+}
+
+func (s *service) reject(ctx context.Context, referral *domain.Referral, reason string) error {
+	_, err := s.referrals.Reject(ctx, referral.ID, reason)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"referrals.reward_failed",
+			"error rejecting referral",
+			notify.F("referee", referral.RefereeEmail),
+			notify.F("err", err),
+		))
+		return err
+	}
+
+	s.notif.Notify(notify.Warning(
+		"referrals.rejected",
+		"referral rejected",
+		notify.F("referee", referral.RefereeEmail),
+		notify.F("reason", reason),
+	))
+
+	return nil
+}
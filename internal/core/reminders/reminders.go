@@ -0,0 +1,28 @@
+package reminders
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+)
+
+type UserRepo interface {
+	ExpiringBetween(ctx context.Context, from time.Time, to time.Time) ([]domain.User, error)
+}
+
+type ReminderRepo interface {
+	MarkSent(ctx context.Context, userID string, expiresAt time.Time, channel string) (bool, error)
+	UnmarkSent(ctx context.Context, userID string, expiresAt time.Time, channel string) error
+}
+
+// Sender delivers expiry reminder to the user over a single channel.
+type Sender interface {
+	SendExpiryReminder(ctx context.Context, user domain.User) error
+}
+
+type Service interface {
+	// Remind sends reminders about accesses expiring within the configured period.
+	Remind(ctx context.Context) error
+	// Run reminds periodically until ctx is done.
+	Run(ctx context.Context) error
+}
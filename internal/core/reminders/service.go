@@ -0,0 +1,107 @@
+package reminders
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/notify"
+	"time"
+)
+
+type Config struct {
+	// Before is how long before expiration users are reminded, 0 disables reminders.
+	Before   time.Duration
+	Interval time.Duration
+}
+
+type service struct {
+	users     UserRepo
+	reminders ReminderRepo
+	channels  map[string]Sender
+	notif     notify.Service
+	cfg       Config
+}
+
+func NewRemindersService(
+	users UserRepo,
+	reminders ReminderRepo,
+	channels map[string]Sender,
+	notif notify.Service,
+	cfg Config,
+) Service {
+	return &service{
+		users:     users,
+		reminders: reminders,
+		channels:  channels,
+		notif:     notif,
+		cfg:       cfg,
+	}
+}
+
+func (s *service) Remind(ctx context.Context) error {
+	now := time.Now()
+	users, err := s.users.ExpiringBetween(ctx, now, now.Add(s.cfg.Before))
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		for channel, sender := range s.channels {
+			// marked before sending so concurrent replicas do not remind twice
+			ok, err := s.reminders.MarkSent(ctx, user.ID, user.ExpiresAt, channel)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+
+			err = sender.SendExpiryReminder(ctx, user)
+			if err != nil {
+				s.notif.Notify(notify.Error(
+					"reminders.send_failed",
+					"error sending expiry reminder",
+					notify.F("channel", channel),
+					notify.F("email", user.Email),
+					notify.F("err", err),
+				))
+				if err := s.reminders.UnmarkSent(ctx, user.ID, user.ExpiresAt, channel); err != nil {
+					s.notif.Notify(notify.Error(
+						"reminders.send_failed",
+						"error reverting expiry reminder",
+						notify.F("channel", channel),
+						notify.F("email", user.Email),
+						notify.F("err", err),
+					))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *service) Run(ctx context.Context) error {
+	if s.cfg.Before <= 0 || len(s.channels) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.Remind(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.notif.Notify(notify.Error(
+				"reminders.run_failed",
+				"error reminding users",
+				notify.F("err", err),
+			))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package reports
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"time"
+)
+
+type ReportRepo interface {
+	Report(ctx context.Context, rng domain.DateRange, now time.Time) (*domain.BusinessReport, error)
+}
+
+type Service interface {
+	// Report builds business report of the period: today, yesterday, week or month.
+	Report(ctx context.Context, period string) (notify.Notification, error)
+	// Run sends scheduled daily and weekly reports until ctx is done.
+	Run(ctx context.Context) error
+}
@@ -0,0 +1,202 @@
+package reports
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	PeriodToday     = "today"
+	PeriodYesterday = "yesterday"
+	PeriodWeek      = "week"
+	PeriodMonth     = "month"
+)
+
+type Config struct {
+	// DailyEnabled sends report of the previous day at Hour.
+	DailyEnabled bool
+	// WeeklyEnabled sends report of the previous 7 days on Weekday at Hour.
+	WeeklyEnabled bool
+	Weekday       time.Weekday
+	Hour          int
+	// Location defines day boundaries.
+	Location *time.Location
+}
+
+type service struct {
+	repo  ReportRepo
+	notif notify.Service
+	cfg   Config
+}
+
+func NewReportsService(
+	repo ReportRepo,
+	notif notify.Service,
+	cfg Config,
+) Service {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+
+	return &service{
+		repo:  repo,
+		notif: notif,
+		cfg:   cfg,
+	}
+}
+
+func (s *service) Report(ctx context.Context, period string) (notify.Notification, error) {
+	now := time.Now().In(s.cfg.Location)
+	rng, err := periodRange(period, now)
+	if err != nil {
+		return notify.Notification{}, err
+	}
+
+	return s.build(ctx, "reports.manual", rng, now)
+}
+
+func (s *service) Run(ctx context.Context) error {
+	if !s.cfg.DailyEnabled && !s.cfg.WeeklyEnabled {
+		<-ctx.Done()
+		return nil
+	}
+
+	for {
+		next := nextDaily(time.Now().In(s.cfg.Location), s.cfg.Hour)
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		today := startOfDay(next)
+		if s.cfg.DailyEnabled {
+			s.send(ctx, "reports.daily", domain.DateRange{From: today.AddDate(0, 0, -1), To: today})
+		}
+		if s.cfg.WeeklyEnabled && next.Weekday() == s.cfg.Weekday {
+			s.send(ctx, "reports.weekly", domain.DateRange{From: today.AddDate(0, 0, -7), To: today})
+		}
+	}
+}
+
+func (s *service) send(ctx context.Context, event string, rng domain.DateRange) {
+	reportCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	n, err := s.build(reportCtx, event, rng, time.Now())
+	if err != nil {
+		s.notif.Notify(notify.Error(event, "error building business report", notify.F("err", err)))
+		return
+	}
+
+	s.notif.Notify(n)
+}
+
+func (s *service) build(ctx context.Context, event string, rng domain.DateRange, now time.Time) (notify.Notification, error) {
+	report, err := s.repo.Report(ctx, rng, now)
+	if err != nil {
+		return notify.Notification{}, err
+	}
+
+	return Notification(event, *report), nil
+}
+
+// Notification renders report as notification fields.
+func Notification(event string, r domain.BusinessReport) notify.Notification {
+	title := "Business report " + r.Range.From.Format("02.01.2006")
+	if last := r.Range.To.Add(-time.Second); last.YearDay() != r.Range.From.YearDay() || last.Year() != r.Range.From.Year() {
+		title += " — " + last.Format("02.01.2006")
+	}
+
+	newPaid := 0
+	for _, t := range r.NewPaid {
+		newPaid += t.Count
+	}
+
+	fields := []notify.Field{
+		notify.F("New trials", r.NewTrials),
+		notify.F("Trial conversion", fmt.Sprintf("%d (%.1f%%)", r.ConvertedTrials, r.Conversion()*100)),
+		notify.F("New paid", newPaid),
+	}
+	for _, t := range r.NewPaid {
+		fields = append(fields, notify.F("New paid · "+t.Tariff, t.Count))
+	}
+	fields = append(fields,
+		notify.F("Renewals", r.Renewals),
+		notify.F("Cancellations", r.Cancellations),
+	)
+	if len(r.Revenue) == 0 {
+		fields = append(fields, notify.F("Revenue", 0))
+	}
+	for _, rev := range r.Revenue {
+		fields = append(fields, notify.F(
+			"Revenue "+rev.Provider,
+			fmt.Sprintf("%s %s (%d)", formatAmount(rev.Amount), rev.Currency, rev.Payments),
+		))
+	}
+	fields = append(fields,
+		notify.F("Active subscribers", r.ActiveSubscribers),
+		notify.F("Active autopay", r.ActiveAutopay),
+	)
+
+	return notify.Info(event, title, fields...)
+}
+
+func periodRange(period string, now time.Time) (domain.DateRange, error) {
+	today := startOfDay(now)
+
+	switch strings.ToLower(strings.TrimSpace(period)) {
+	case "", PeriodToday:
+		return domain.DateRange{From: today, To: now}, nil
+	case PeriodYesterday:
+		return domain.DateRange{From: today.AddDate(0, 0, -1), To: today}, nil
+	case PeriodWeek:
+		return domain.DateRange{From: today.AddDate(0, 0, -6), To: now}, nil
+	case PeriodMonth:
+		return domain.DateRange{From: today.AddDate(0, 0, -29), To: now}, nil
+	default:
+		return domain.DateRange{}, fmt.Errorf("unknown period %q, use today, yesterday, week or month", period)
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextDaily returns the closest moment after now at the hour.
+func nextDaily(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next
+}
+
+// formatAmount separates thousands with spaces, e.g. 12 345.00.
+func formatAmount(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+
+	sign := ""
+	if strings.HasPrefix(intPart, "-") {
+		sign, intPart = "-", intPart[1:]
+	}
+
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(c)
+	}
+
+	return sign + b.String() + frac
+}
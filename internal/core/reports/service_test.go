@@ -0,0 +1,70 @@
+//go:build unit
+// +build unit
+
+package reports
+
+import (
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriodRange(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	rng, err := periodRange("", now)
+	require.NoError(t, err)
+	require.Equal(t, domain.DateRange{From: today, To: now}, rng)
+
+	rng, err = periodRange("yesterday", now)
+	require.NoError(t, err)
+	require.Equal(t, domain.DateRange{From: today.AddDate(0, 0, -1), To: today}, rng)
+
+	rng, err = periodRange("Week", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC), rng.From)
+
+	_, err = periodRange("year", now)
+	require.Error(t, err)
+}
+
+func TestNextDaily(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	require.Equal(t, time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC), nextDaily(now, 9))
+	require.Equal(t, time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), nextDaily(now, 10))
+}
+
+func TestNotification(t *testing.T) {
+	day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	n := Notification("reports.daily", domain.BusinessReport{
+		Range:           domain.DateRange{From: day, To: day.AddDate(0, 0, 1)},
+		NewTrials:       8,
+		ConvertedTrials: 2,
+		NewPaid:         []domain.TariffCount{{Tariff: domain.UserTariff1Month, Count: 3}},
+		Renewals:        5,
+		Cancellations:   1,
+		Revenue: []domain.Revenue{
+			{Provider: domain.PaymentProviderCloudPayments, Currency: domain.CurrencyRUB, Amount: 12345.5, Payments: 7},
+		},
+		ActiveSubscribers: 120,
+		ActiveAutopay:     90,
+	})
+
+	require.Equal(t, "reports.daily", n.Event)
+	require.Equal(t, "Business report 18.10.2026", n.Message)
+	require.Contains(t, n.Fields, notify.F("Trial conversion", "2 (25.0%)"))
+	require.Contains(t, n.Fields, notify.F("New paid", 3))
+	require.Contains(t, n.Fields, notify.F("Revenue cloudpayments", "12 345.50 RUB (7)"))
+}
+
+func TestFormatAmount(t *testing.T) {
+	require.Equal(t, "0.00", formatAmount(0))
+	require.Equal(t, "999.99", formatAmount(999.99))
+	require.Equal(t, "1 000.00", formatAmount(1000))
+	require.Equal(t, "-1 234 567.10", formatAmount(-1234567.1))
+}
@@ -0,0 +1,21 @@
+package retention
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+)
+
+type PartitionRepo interface {
+	EnsureMonthly(ctx context.Context, table string, from time.Time, to time.Time) error
+	Monthly(ctx context.Context, table string) ([]domain.Partition, error)
+	RollupAndDrop(ctx context.Context, p domain.Partition) error
+}
+
+type Service interface {
+	// Maintain creates partitions ahead and rolls up and drops expired ones.
+	Maintain(ctx context.Context) error
+	// Run maintains partitions periodically until ctx is done, the first maintenance
+	// is done by the caller before serving traffic.
+	Run(ctx context.Context) error
+}
@@ -0,0 +1,121 @@
+package retention
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type Config struct {
+	Tables []string
+	// Retention is how long raw rows are kept, zero keeps them forever.
+	Retention time.Duration
+	// MonthsAhead is amount of future monthly partitions kept created.
+	MonthsAhead int
+	Interval    time.Duration
+}
+
+type service struct {
+	partitions PartitionRepo
+	notif      notify.Service
+	cfg        Config
+	logger     *slog.Logger
+}
+
+func NewRetentionService(
+	partitions PartitionRepo,
+	notif notify.Service,
+	cfg Config,
+	rootLogger *slog.Logger,
+) Service {
+	return &service{
+		partitions: partitions,
+		notif:      notif,
+		cfg:        cfg,
+		logger:     rootLogger.With(slog.String("context", "Retention")),
+	}
+}
+
+func (s *service) Maintain(ctx context.Context) error {
+	// partitions are monthly in UTC, see lumos.create_monthly_partitions
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var errs []error
+	for _, table := range s.cfg.Tables {
+		err := s.partitions.EnsureMonthly(ctx, table, monthStart, monthStart.AddDate(0, s.cfg.MonthsAhead, 0))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ensure partitions of %s: %w", table, err))
+			continue
+		}
+
+		if s.cfg.Retention <= 0 {
+			continue
+		}
+
+		err = s.dropExpired(ctx, table, now.Add(-s.cfg.Retention))
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := s.Maintain(ctx)
+			if err != nil {
+				s.notif.Notify(notify.Error(
+					"retention.maintenance_failed",
+					"telemetry partitions maintenance failed",
+					notify.F("err", err),
+				))
+			}
+		}
+	}
+}
+
+// dropExpired drops partitions with all rows older than the cutoff.
+func (s *service) dropExpired(ctx context.Context, table string, cutoff time.Time) error {
+	partitions, err := s.partitions.Monthly(ctx, table)
+	if err != nil {
+		return fmt.Errorf("list partitions of %s: %w", table, err)
+	}
+
+	for _, p := range expired(partitions, cutoff) {
+		err := s.partitions.RollupAndDrop(ctx, p)
+		if err != nil {
+			return fmt.Errorf("drop partition %s: %w", p.Name, err)
+		}
+
+		s.logger.Info("partition rolled up and dropped",
+			slog.String("table", table),
+			slog.String("partition", p.Name),
+		)
+	}
+
+	return nil
+}
+
+func expired(partitions []domain.Partition, cutoff time.Time) []domain.Partition {
+	out := make([]domain.Partition, 0)
+	for _, p := range partitions {
+		if !p.To.After(cutoff) {
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,26 @@
+//go:build unit
+// +build unit
+
+package retention
+
+import (
+	"doctormakarhina/lumos/internal/core/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpired(t *testing.T) {
+	month := func(m time.Month) domain.Partition {
+		from := time.Date(2026, m, 1, 0, 0, 0, 0, time.UTC)
+		return domain.Partition{Name: from.Format("2006_01"), From: from, To: from.AddDate(0, 1, 0)}
+	}
+	partitions := []domain.Partition{month(3), month(4), month(5), month(6)}
+
+	got := expired(partitions, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC))
+	require.Equal(t, []domain.Partition{month(3), month(4)}, got)
+
+	got = expired(partitions, time.Date(2026, 4, 15, 0, 0, 0, 0, time.UTC))
+	require.Equal(t, []domain.Partition{month(3)}, got)
+}
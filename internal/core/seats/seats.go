@@ -0,0 +1,30 @@
+package seats
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+)
+
+type UserRepo interface {
+	ByID(ctx context.Context, id string) (*domain.User, error)
+}
+
+type SeatRepo interface {
+	Members(ctx context.Context, ownerID string) ([]domain.Seat, error)
+	ByMemberEmail(ctx context.Context, email string) (*domain.Seat, error)
+	MemberPosition(ctx context.Context, email string) (*domain.Seat, int, error)
+	Add(ctx context.Context, seat domain.Seat, limit int) (bool, error)
+	Remove(ctx context.Context, ownerID string, memberEmail string) (bool, error)
+	Usage(ctx context.Context) ([]domain.SeatUsage, error)
+}
+
+type Service interface {
+	// Limit returns amount of seats available under the owner tariff.
+	Limit(owner domain.User) int
+	Members(ctx context.Context, owner domain.User) ([]domain.Seat, error)
+	Invite(ctx context.Context, owner domain.User, memberEmail string) (*domain.Seat, error)
+	Remove(ctx context.Context, owner domain.User, memberEmail string) error
+	// MemberAccess reports whether the email is a member of a team with valid owner subscription.
+	MemberAccess(ctx context.Context, email string) (bool, error)
+	Usage(ctx context.Context) ([]domain.SeatUsage, error)
+}
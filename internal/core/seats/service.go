@@ -0,0 +1,204 @@
+package seats
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoSeats          = errors.New("tariff has no seats")
+	ErrOwnerSubExpired  = errors.New("owner subscription expired")
+	ErrSeatLimitReached = errors.New("seat limit reached")
+	ErrAlreadyMember    = errors.New("email is already a team member")
+	ErrInvalidMember    = errors.New("invalid member email")
+	ErrMemberNotFound   = errors.New("team member not found")
+)
+
+type service struct {
+	users          UserRepo
+	seats          SeatRepo
+	notif          notify.Service
+	seatsPerTariff map[string]int
+}
+
+func NewSeatsService(
+	users UserRepo,
+	seats SeatRepo,
+	notif notify.Service,
+	seatsPerTariff map[string]int,
+) Service {
+	normalized := make(map[string]int, len(seatsPerTariff))
+	for tariff, limit := range seatsPerTariff {
+		normalized[strings.ToLower(strings.TrimSpace(tariff))] = limit
+	}
+
+	return &service{
+		users:          users,
+		seats:          seats,
+		notif:          notif,
+		seatsPerTariff: normalized,
+	}
+}
+
+func (s *service) Limit(owner domain.User) int {
+	return s.seatsPerTariff[strings.ToLower(strings.TrimSpace(owner.Tariff))]
+}
+
+func (s *service) Members(ctx context.Context, owner domain.User) ([]domain.Seat, error) {
+	members, err := s.seats.Members(ctx, owner.ID)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"seats.members_failed",
+			"error fetching seats of owner",
+			notify.F("owner", owner.Email),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (s *service) Invite(ctx context.Context, owner domain.User, memberEmail string) (*domain.Seat, error) {
+	emailNorm := strings.ToLower(strings.TrimSpace(memberEmail))
+	if emailNorm == "" || !strings.Contains(emailNorm, "@") || emailNorm == owner.Email {
+		return nil, ErrInvalidMember
+	}
+
+	limit := s.Limit(owner)
+	if limit <= 0 {
+		return nil, ErrNoSeats
+	}
+	if owner.SubExpired(time.Now()) {
+		return nil, ErrOwnerSubExpired
+	}
+
+	existing, err := s.seats.ByMemberEmail(ctx, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"seats.invite_failed",
+			"error fetching seat",
+			notify.F("email", emailNorm),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	seat := domain.Seat{
+		ID:          uuid.New().String(),
+		OwnerID:     owner.ID,
+		MemberEmail: emailNorm,
+	}
+
+	added, err := s.seats.Add(ctx, seat, limit)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"seats.invite_failed",
+			"error adding seat",
+			notify.F("email", emailNorm),
+			notify.F("owner", owner.Email),
+			notify.F("err", err),
+		))
+		return nil, err
+	}
+	if !added {
+		return nil, ErrSeatLimitReached
+	}
+
+	s.notif.Notify(notify.Info(
+		"seats.invited",
+		"owner invited team member",
+		notify.F("owner", owner.Email),
+		notify.F("email", emailNorm),
+		notify.F("limit", limit),
+	))
+
+	return &seat, nil
+}
+
+func (s *service) Remove(ctx context.Context, owner domain.User, memberEmail string) error {
+	emailNorm := strings.ToLower(strings.TrimSpace(memberEmail))
+
+	removed, err := s.seats.Remove(ctx, owner.ID, emailNorm)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"seats.remove_failed",
+			"error removing seat",
+			notify.F("email", emailNorm),
+			notify.F("owner", owner.Email),
+			notify.F("err", err),
+		))
+		return err
+	}
+	if !removed {
+		return ErrMemberNotFound
+	}
+
+	s.notif.Notify(notify.Info(
+		"seats.removed",
+		"owner removed team member",
+		notify.F("owner", owner.Email),
+		notify.F("email", emailNorm),
+	))
+
+	return nil
+}
+
+func (s *service) MemberAccess(ctx context.Context, email string) (bool, error) {
+	seat, position, err := s.seats.MemberPosition(ctx, email)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"seats.member_access_failed",
+			"error fetching seat",
+			notify.F("email", email),
+			notify.F("err", err),
+		))
+		return false, err
+	}
+	if seat == nil {
+		return false, nil
+	}
+
+	owner, err := s.users.ByID(ctx, seat.OwnerID)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"seats.member_access_failed",
+			"error fetching owner",
+			notify.F("email", email),
+			notify.F("err", err),
+		))
+		return false, err
+	}
+	if owner == nil {
+		return false, nil
+	}
+
+	// seats invited under a bigger tariff stay stored after downgrade, but only the first ones keep access
+	if position > s.Limit(*owner) {
+		return false, nil
+	}
+
+	return !owner.SubExpired(time.Now()), nil
+}
+
+func (s *service) Usage(ctx context.Context) ([]domain.SeatUsage, error) {
+	usage, err := s.seats.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range usage {
+		usage[i].Limit = s.seatsPerTariff[strings.ToLower(strings.TrimSpace(usage[i].Tariff))]
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,231 @@
+package sharing
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsLimit bounds amount of logins loaded from telemetry for a single report.
+const statsLimit = 500
+
+type Config struct {
+	// Window is how far back auth telemetry is inspected for the report and alerts.
+	Window time.Duration
+	// FingerprintWeight, IPWeight and UserAgentWeight are added to the score
+	// for every distinct value above the first one.
+	FingerprintWeight float64
+	IPWeight          float64
+	UserAgentWeight   float64
+	// ReportLimit is max amount of accounts in the report.
+	ReportLimit int
+	// AlertScore is min score to alert admins about, zero disables alerts.
+	AlertScore float64
+	// AlertInterval is how often alerts are evaluated.
+	AlertInterval time.Duration
+	// AlertCooldown prevents alerting about the same account again.
+	AlertCooldown time.Duration
+	// MaxConcurrentDevices caps distinct fingerprints within ConcurrentWindow
+	// for access checks, zero disables the cap.
+	MaxConcurrentDevices int
+	ConcurrentWindow     time.Duration
+}
+
+type service struct {
+	users    UserRepo
+	authLogs AuthLogRepo
+	notif    notify.Service
+	cfg      Config
+
+	mu        sync.Mutex
+	alertedAt map[string]time.Time
+}
+
+func NewSharingService(
+	users UserRepo,
+	authLogs AuthLogRepo,
+	notif notify.Service,
+	cfg Config,
+) Service {
+	return &service{
+		users:     users,
+		authLogs:  authLogs,
+		notif:     notif,
+		cfg:       cfg,
+		alertedAt: make(map[string]time.Time),
+	}
+}
+
+func (s *service) Report(ctx context.Context) ([]domain.SharingScore, error) {
+	stats, err := s.authLogs.DeviceStats(ctx, time.Now().Add(-s.cfg.Window), statsLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return []domain.SharingScore{}, nil
+	}
+
+	emails := make([]string, 0, len(stats))
+	for _, st := range stats {
+		emails = append(emails, st.Login)
+	}
+
+	users, err := s.users.ByEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	byEmail := make(map[string]domain.User, len(users))
+	for _, u := range users {
+		byEmail[strings.ToLower(strings.TrimSpace(u.Email))] = u
+	}
+
+	// telemetry of unregistered logins is not interesting, nothing is shared there
+	scores := make([]domain.SharingScore, 0, len(stats))
+	for _, st := range stats {
+		user, ok := byEmail[st.Login]
+		if !ok {
+			continue
+		}
+
+		scores = append(scores, domain.SharingScore{
+			DeviceStats: st,
+			Tariff:      user.Tariff,
+			ExpiresAt:   user.ExpiresAt,
+			Score:       s.score(st),
+		})
+	}
+
+	slices.SortStableFunc(scores, func(a, b domain.SharingScore) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if s.cfg.ReportLimit > 0 && len(scores) > s.cfg.ReportLimit {
+		scores = scores[:s.cfg.ReportLimit]
+	}
+
+	return scores, nil
+}
+
+func (s *service) DevicesExceeded(ctx context.Context, email string) (bool, error) {
+	if s.cfg.MaxConcurrentDevices <= 0 {
+		return false, nil
+	}
+
+	stats, err := s.authLogs.DeviceStatsByLogin(
+		ctx,
+		strings.ToLower(strings.TrimSpace(email)),
+		time.Now().Add(-s.cfg.ConcurrentWindow),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return stats.Fingerprints > s.cfg.MaxConcurrentDevices, nil
+}
+
+func (s *service) Alert(ctx context.Context) error {
+	if s.cfg.AlertScore <= 0 {
+		return nil
+	}
+
+	scores, err := s.Report(ctx)
+	if err != nil {
+		s.notif.Notify(notify.Error(
+			"sharing.report_failed",
+			"error building sharing report",
+			notify.F("err", err),
+		))
+		return err
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	offenders := make([]domain.SharingScore, 0)
+	for _, sc := range scores {
+		if sc.Score < s.cfg.AlertScore {
+			continue
+		}
+		if last, ok := s.alertedAt[sc.Login]; ok && now.Sub(last) < s.cfg.AlertCooldown {
+			continue
+		}
+		s.alertedAt[sc.Login] = now
+		offenders = append(offenders, sc)
+	}
+	for login, last := range s.alertedAt {
+		if now.Sub(last) >= s.cfg.AlertCooldown {
+			delete(s.alertedAt, login)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(offenders) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "accounts likely shared for the last %s:\n", s.cfg.Window)
+	for _, sc := range offenders {
+		fmt.Fprintf(
+			&b,
+			"\n%s — %s — score %.1f: fingerprints %d, ips %d, user agents %d, last seen %s",
+			sc.Login,
+			sc.Tariff,
+			sc.Score,
+			sc.Fingerprints,
+			sc.IPs,
+			sc.UserAgents,
+			sc.LastSeenAt.Format("02.01.2006 15:04"),
+		)
+	}
+	s.notif.Notify(notify.Warning("sharing.accounts_shared", b.String()))
+
+	return nil
+}
+
+func (s *service) Run(ctx context.Context) error {
+	if s.cfg.AlertScore <= 0 || s.cfg.AlertInterval <= 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(s.cfg.AlertInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// failures are reported to admins, next tick retries
+			_ = s.Alert(ctx)
+		}
+	}
+}
+
+// score grows with every device above the first one, a single person
+// usually has a couple of devices, so weights are tuned by config.
+func (s *service) score(st domain.DeviceStats) float64 {
+	extra := func(n int) float64 {
+		if n <= 1 {
+			return 0
+		}
+		return float64(n - 1)
+	}
+
+	return s.cfg.FingerprintWeight*extra(st.Fingerprints) +
+		s.cfg.IPWeight*extra(st.IPs) +
+		s.cfg.UserAgentWeight*extra(st.UserAgents)
+}
@@ -0,0 +1,28 @@
+package sharing
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+)
+
+type UserRepo interface {
+	ByEmails(ctx context.Context, emails []string) ([]domain.User, error)
+}
+
+type AuthLogRepo interface {
+	DeviceStats(ctx context.Context, since time.Time, limit int) ([]domain.DeviceStats, error)
+	DeviceStatsByLogin(ctx context.Context, login string, since time.Time) (*domain.DeviceStats, error)
+}
+
+type Service interface {
+	// Report returns registered users scored by likelihood of account sharing, highest first.
+	Report(ctx context.Context) ([]domain.SharingScore, error)
+	// DevicesExceeded reports whether the email was used from more devices than allowed
+	// by the concurrent devices cap, always false when the cap is disabled.
+	DevicesExceeded(ctx context.Context, email string) (bool, error)
+	// Alert notifies admins about the worst offenders not alerted recently.
+	Alert(ctx context.Context) error
+	// Run sends alerts periodically until ctx is done.
+	Run(ctx context.Context) error
+}
@@ -0,0 +1,232 @@
+package trials
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:embed disposable_domains.txt
+var defaultDisposableDomains []byte
+
+type AbuseConfig struct {
+	// DisposableAction is applied when email belongs to a disposable email domain.
+	DisposableAction string
+	// FingerprintLinkedAction is applied when registrant shares a device fingerprint with registered users.
+	FingerprintLinkedAction string
+	// IPLinkedAction is applied when registrant shares only ip addresses with registered users.
+	IPLinkedAction string
+	// ShortenTo is trial duration used by shorten action.
+	ShortenTo time.Duration
+	// Window is how far back auth telemetry is inspected.
+	Window time.Duration
+	// DisposableDomains extends embedded list of disposable domains.
+	DisposableDomains []string
+	// DisposableDomainsFile is optional path to file with disposable domains, one per line.
+	DisposableDomainsFile string
+}
+
+type abuseChecker struct {
+	users      UserRepo
+	authLogs   AuthLogRepo
+	flags      TrialFlagRepo
+	cfg        AbuseConfig
+	disposable map[string]struct{}
+}
+
+func NewAbuseChecker(
+	users UserRepo,
+	authLogs AuthLogRepo,
+	flags TrialFlagRepo,
+	cfg AbuseConfig,
+) (AbuseChecker, error) {
+	disposable := make(map[string]struct{})
+	addDomains(disposable, defaultDisposableDomains)
+	for _, d := range cfg.DisposableDomains {
+		addDomains(disposable, []byte(d))
+	}
+
+	if cfg.DisposableDomainsFile != "" {
+		content, err := os.ReadFile(cfg.DisposableDomainsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read disposable domains file: %w", err)
+		}
+		addDomains(disposable, content)
+	}
+
+	for _, action := range []string{cfg.DisposableAction, cfg.FingerprintLinkedAction, cfg.IPLinkedAction} {
+		switch action {
+		case domain.TrialActionAllow, domain.TrialActionReview, domain.TrialActionShorten, domain.TrialActionDeny:
+		default:
+			return nil, fmt.Errorf("unknown trial abuse action %s", action)
+		}
+	}
+
+	return &abuseChecker{
+		users:      users,
+		authLogs:   authLogs,
+		flags:      flags,
+		cfg:        cfg,
+		disposable: disposable,
+	}, nil
+}
+
+func (s *abuseChecker) Check(
+	ctx context.Context,
+	email string,
+	fingerprint string,
+	duration time.Duration,
+) (Verdict, error) {
+	verdict := Verdict{Action: domain.TrialActionAllow, Duration: duration}
+
+	if s.isDisposable(email) {
+		s.apply(&verdict, s.cfg.DisposableAction, "disposable email domain")
+	}
+
+	linked, err := s.linkedUsers(ctx, email, fingerprint)
+	if err != nil {
+		return verdict, fmt.Errorf("failed to fetch linked users: %w", err)
+	}
+	verdict.Linked = linked
+
+	for _, l := range linked {
+		if l.SharedFingerprints > 0 {
+			s.apply(&verdict, s.cfg.FingerprintLinkedAction, "device shared with "+l.Email)
+		} else {
+			s.apply(&verdict, s.cfg.IPLinkedAction, "ip address shared with "+l.Email)
+		}
+	}
+
+	if verdict.Action == domain.TrialActionShorten && s.cfg.ShortenTo < verdict.Duration {
+		verdict.Duration = s.cfg.ShortenTo
+	}
+
+	if verdict.Action == domain.TrialActionAllow {
+		return verdict, nil
+	}
+
+	linkedEmails := make([]string, 0, len(linked))
+	for _, l := range linked {
+		linkedEmails = append(linkedEmails, l.Email)
+	}
+
+	err = s.flags.Create(ctx, domain.TrialFlag{
+		ID:           uuid.New().String(),
+		Email:        email,
+		Action:       verdict.Action,
+		Reasons:      strings.Join(verdict.Reasons, "; "),
+		LinkedLogins: strings.Join(linkedEmails, ","),
+		Fingerprint:  fingerprint,
+	})
+	if err != nil {
+		return verdict, fmt.Errorf("failed to save trial flag: %w", err)
+	}
+
+	return verdict, nil
+}
+
+func (s *abuseChecker) apply(verdict *Verdict, action string, reason string) {
+	if action == domain.TrialActionAllow {
+		return
+	}
+
+	verdict.Reasons = append(verdict.Reasons, reason)
+	if domain.TrialActionWeight(action) > domain.TrialActionWeight(verdict.Action) {
+		verdict.Action = action
+	}
+}
+
+func (s *abuseChecker) linkedUsers(ctx context.Context, email string, fingerprint string) ([]domain.LinkedUser, error) {
+	since := time.Now().Add(-s.cfg.Window)
+
+	logins, err := s.authLogs.LinkedLogins(ctx, email, since)
+	if err != nil {
+		return nil, err
+	}
+
+	if fingerprint = strings.TrimSpace(fingerprint); fingerprint != "" {
+		byFingerprint, err := s.authLogs.LoginsByFingerprint(ctx, fingerprint, since)
+		if err != nil {
+			return nil, err
+		}
+		logins = append(logins, byFingerprint...)
+	}
+
+	merged := make(map[string]domain.LinkedLogin, len(logins))
+	emails := make([]string, 0, len(logins))
+	for _, l := range logins {
+		if l.Login == email {
+			continue
+		}
+		existing, ok := merged[l.Login]
+		if !ok {
+			emails = append(emails, l.Login)
+		}
+		existing.Login = l.Login
+		existing.SharedFingerprints += l.SharedFingerprints
+		existing.SharedIPs += l.SharedIPs
+		merged[l.Login] = existing
+	}
+
+	registered, err := s.users.ByEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := make([]domain.LinkedUser, 0, len(registered))
+	for _, u := range registered {
+		l := merged[u.Email]
+		linked = append(linked, domain.LinkedUser{
+			Email:              u.Email,
+			Tariff:             u.Tariff,
+			ExpiresAt:          u.ExpiresAt,
+			SharedFingerprints: l.SharedFingerprints,
+			SharedIPs:          l.SharedIPs,
+		})
+	}
+
+	return linked, nil
+}
+
+func (s *abuseChecker) isDisposable(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at == -1 {
+		return false
+	}
+
+	host := email[at+1:]
+	for host != "" {
+		if _, ok := s.disposable[host]; ok {
+			return true
+		}
+		// subdomains of disposable services are disposable too
+		dot := strings.IndexByte(host, '.')
+		if dot == -1 {
+			break
+		}
+		host = host[dot+1:]
+	}
+
+	return false
+}
+
+func addDomains(dst map[string]struct{}, content []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		for _, d := range strings.Split(scanner.Text(), ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "" || strings.HasPrefix(d, "#") {
+				continue
+			}
+			dst[d] = struct{}{}
+		}
+	}
+}
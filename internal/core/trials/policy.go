@@ -0,0 +1,105 @@
+package trials
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownCampaign  = errors.New("unknown trial campaign")
+	ErrSourceNotAllowed = errors.New("trial source is not allowed")
+)
+
+type PolicyConfig struct {
+	// DefaultDays is trial length used when the form passes no campaign.
+	DefaultDays int
+	// MaxDays is upper bound for any trial length.
+	MaxDays int
+	// AllowedSources is a list of allowed form ids, empty list allows any source.
+	AllowedSources []string
+	// Campaigns maps campaign key to trial length in days.
+	Campaigns map[string]int
+}
+
+type policy struct {
+	flags TrialFlagRepo
+	cfg   PolicyConfig
+}
+
+func NewPolicy(flags TrialFlagRepo, cfg PolicyConfig) (Policy, error) {
+	if cfg.MaxDays <= 0 {
+		return nil, fmt.Errorf("trial max days must be > 0")
+	}
+	if cfg.DefaultDays <= 0 || cfg.DefaultDays > cfg.MaxDays {
+		return nil, fmt.Errorf("trial default days must be in range 1..%d", cfg.MaxDays)
+	}
+
+	campaigns := make(map[string]int, len(cfg.Campaigns))
+	for key, days := range cfg.Campaigns {
+		if days <= 0 || days > cfg.MaxDays {
+			return nil, fmt.Errorf("trial campaign %s days must be in range 1..%d", key, cfg.MaxDays)
+		}
+		campaigns[normalizeKey(key)] = days
+	}
+	cfg.Campaigns = campaigns
+
+	sources := make([]string, 0, len(cfg.AllowedSources))
+	for _, src := range cfg.AllowedSources {
+		if src = normalizeKey(src); src != "" {
+			sources = append(sources, src)
+		}
+	}
+	cfg.AllowedSources = sources
+
+	return &policy{flags: flags, cfg: cfg}, nil
+}
+
+func (p *policy) Duration(
+	ctx context.Context,
+	email string,
+	campaign string,
+	source string,
+) (time.Duration, error) {
+	campaign = normalizeKey(campaign)
+	source = normalizeKey(source)
+
+	if len(p.cfg.AllowedSources) > 0 && !slices.Contains(p.cfg.AllowedSources, source) {
+		return 0, p.reject(ctx, email, ErrSourceNotAllowed, fmt.Sprintf("source (%s) is not allowed", source))
+	}
+
+	days := p.cfg.DefaultDays
+	if campaign != "" {
+		campaignDays, ok := p.cfg.Campaigns[campaign]
+		if !ok {
+			return 0, p.reject(ctx, email, ErrUnknownCampaign, fmt.Sprintf("campaign (%s) is unknown", campaign))
+		}
+		days = campaignDays
+	}
+
+	return time.Duration(min(days, p.cfg.MaxDays)) * 24 * time.Hour, nil
+}
+
+func (p *policy) reject(ctx context.Context, email string, reason error, details string) error {
+	err := p.flags.Create(ctx, domain.TrialFlag{
+		ID:      uuid.New().String(),
+		Email:   email,
+		Action:  domain.TrialActionDeny,
+		Reasons: details,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s, failed to save trial flag: %w", reason, details, err)
+	}
+
+	return fmt.Errorf("%w: %s", reason, details)
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
@@ -0,0 +1,40 @@
+package trials
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+)
+
+type UserRepo interface {
+	ByEmails(ctx context.Context, emails []string) ([]domain.User, error)
+}
+
+type AuthLogRepo interface {
+	LinkedLogins(ctx context.Context, login string, since time.Time) ([]domain.LinkedLogin, error)
+	LoginsByFingerprint(ctx context.Context, fingerprint string, since time.Time) ([]domain.LinkedLogin, error)
+}
+
+type TrialFlagRepo interface {
+	Create(ctx context.Context, flag domain.TrialFlag) error
+}
+
+// Verdict is a result of trial registration abuse check.
+type Verdict struct {
+	Action   string
+	Reasons  []string
+	Linked   []domain.LinkedUser
+	Duration time.Duration
+}
+
+type AbuseChecker interface {
+	// Check evaluates trial registration and returns the action to apply,
+	// non allow verdicts are stored for admin review.
+	Check(ctx context.Context, email string, fingerprint string, duration time.Duration) (Verdict, error)
+}
+
+type Policy interface {
+	// Duration resolves trial length for the campaign requested from the form,
+	// rejected requests are stored for admin review.
+	Duration(ctx context.Context, email string, campaign string, source string) (time.Duration, error)
+}
@@ -0,0 +1,24 @@
+package boot
+
+import (
+	"fmt"
+	"time"
+)
+
+// ContactsSyncConfig drives export of user fields to email provider contacts.
+type ContactsSyncConfig struct {
+	BatchSize int `env:"CONTACTS_SYNC_BATCH_SIZE" envDefault:"500"`
+	// Interval between syncs of changed users, 0 disables the sync
+	Interval time.Duration `env:"CONTACTS_SYNC_INTERVAL" envDefault:"1m"`
+}
+
+func (r *ContactsSyncConfig) Validate() error {
+	if r.BatchSize < 1 || r.BatchSize > 500 {
+		return fmt.Errorf("contacts sync batch size must be in [1, 500]")
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("contacts sync interval must not be negative")
+	}
+
+	return nil
+}
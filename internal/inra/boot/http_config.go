@@ -16,4 +16,6 @@ type HttpConfig struct {
 	AutoCertCacheDir  string        `env:"HTTP_AUTO_CERT_CACHE_DIR" envDefault:"/certificates"`
 	AutoCertEmail     string        `env:"HTTP_AUTO_CERT_EMAIL" envDefault:"local@mail.com"`
 	AutoCertHosts     string        `env:"HTTP_AUTO_CERT_HOSTS" envDefault:"localhost"`
+	// TrustedProxies are CIDRs or addresses allowed to set X-Forwarded-For and X-Real-IP
+	TrustedProxies []string `env:"HTTP_TRUSTED_PROXIES"`
 }
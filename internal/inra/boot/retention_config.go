@@ -0,0 +1,31 @@
+package boot
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetentionConfig drives monthly partitioning of telemetry tables.
+type RetentionConfig struct {
+	// Retention is how long raw telemetry is kept before rolled up into daily tables, 0 keeps it forever
+	Retention   time.Duration `env:"TELEMETRY_RETENTION" envDefault:"4320h"`
+	MonthsAhead int           `env:"TELEMETRY_PARTITIONS_AHEAD" envDefault:"2"`
+	Interval    time.Duration `env:"TELEMETRY_MAINTENANCE_INTERVAL" envDefault:"6h"`
+}
+
+func (r *RetentionConfig) Validate() error {
+	if r.Retention < 0 {
+		return fmt.Errorf("telemetry retention must not be negative")
+	}
+	if r.Retention > 0 && r.Retention < 31*24*time.Hour {
+		return fmt.Errorf("telemetry retention %s is shorter than a month partition", r.Retention)
+	}
+	if r.MonthsAhead < 1 {
+		return fmt.Errorf("at least one partition ahead is required")
+	}
+	if r.Interval <= 0 {
+		return fmt.Errorf("telemetry maintenance interval must be positive")
+	}
+
+	return nil
+}
@@ -12,6 +12,10 @@ import (
 	"time"
 
 	corepayments "doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/pkg/metrics"
+	"doctormakarhina/lumos/internal/pkg/tracing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 var _ corepayments.CloudPayments = (*CloudPaymentsClient)(nil)
@@ -194,7 +198,7 @@ func newAPIError(httpStatus int, message *string, raw string) error {
 	}
 }
 
-func (c *CloudPaymentsClient) doJSON(ctx context.Context, method, path string, req any, out any) error {
+func (c *CloudPaymentsClient) doJSON(ctx context.Context, method, path string, req any, out any) (err error) {
 	if ctx == nil {
 		return errors.New("cloudpayments: context is nil")
 	}
@@ -202,6 +206,19 @@ func (c *CloudPaymentsClient) doJSON(ctx context.Context, method, path string, r
 		return errors.New("cloudpayments: http method is empty")
 	}
 
+	ctx, span := tracing.Start(ctx, "cloudpayments "+path,
+		semconv.HTTPRequestMethodKey.String(method),
+		semconv.URLPath(path),
+	)
+	start := time.Now()
+	defer func() {
+		metrics.CloudPaymentsRequestDuration.WithLabelValues(path).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.CloudPaymentsErrors.WithLabelValues(path).Inc()
+		}
+		tracing.End(span, err)
+	}()
+
 	url := c.baseURL + path
 
 	var body io.Reader
@@ -229,6 +246,7 @@ func (c *CloudPaymentsClient) doJSON(ctx context.Context, method, path string, r
 		return fmt.Errorf("cloudpayments: request failed: %w", err)
 	}
 	defer res.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
 
 	rawBytes, readErr := io.ReadAll(res.Body)
 	if readErr != nil {
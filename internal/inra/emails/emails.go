@@ -0,0 +1,152 @@
+package emails
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Message is a single transactional email, Text is an optional plain-text alternative.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Transactional delivers a message immediately.
+type Transactional interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ListProvider manages contacts of provider-side lists, the lifecycle emails
+// are configured as list automations in the provider.
+type ListProvider interface {
+	Subscribe(ctx context.Context, email string, listTitle string, fields map[string]string) error
+	Exclude(ctx context.Context, email string, listTitle string) error
+}
+
+// Suppressions tells addresses lifecycle emails must not be sent to, e.g. bounced ones.
+type Suppressions interface {
+	Suppressed(ctx context.Context, email string) (bool, error)
+}
+
+type SrvCfg struct {
+	AfterTrialExpiredListTitle         string
+	AfterReccurrentPaymentListTitle    string
+	AfterAutopaymentCancelledListTitle string
+	GiftPurchasedListTitle             string
+	GiftReceivedListTitle              string
+	// Locale is used for all recipients until per-user locale is known
+	Locale string
+	// Enabled lists templates sent with the transactional provider, magic
+	// link is always sent, others may duplicate provider-side automations
+	Enabled []string
+}
+
+// Srv is provider-neutral email service, list subscriptions and transactional
+// sends may be handled by different providers.
+type Srv struct {
+	lists         ListProvider
+	transactional Transactional
+	renderer      *Renderer
+	suppressions  Suppressions
+	cfg           SrvCfg
+}
+
+func NewSrv(lists ListProvider, transactional Transactional, renderer *Renderer, suppressions Suppressions, cfg SrvCfg) *Srv {
+	return &Srv{lists: lists, transactional: transactional, renderer: renderer, suppressions: suppressions, cfg: cfg}
+}
+
+func (s *Srv) ScheduleAfterTrialExpired(ctx context.Context, email string) error {
+	return s.subscribe(ctx, email, s.cfg.AfterTrialExpiredListTitle, nil)
+}
+
+func (s *Srv) CancelTrialExpired(ctx context.Context, email string) error {
+	return s.lists.Exclude(ctx, email, s.cfg.AfterTrialExpiredListTitle)
+}
+
+func (s *Srv) ScheduleAfterReccurrentPayment(ctx context.Context, email string) error {
+	return s.subscribe(ctx, email, s.cfg.AfterReccurrentPaymentListTitle, nil)
+}
+
+func (s *Srv) ScheduleAfterAutopaymentCancelled(ctx context.Context, email string) error {
+	return s.subscribe(ctx, email, s.cfg.AfterAutopaymentCancelledListTitle, nil)
+}
+
+func (s *Srv) ScheduleGiftPurchased(ctx context.Context, payerEmail string, recipientEmail string) error {
+	return s.subscribe(ctx, payerEmail, s.cfg.GiftPurchasedListTitle, map[string]string{
+		"gift_recipient": recipientEmail,
+	})
+}
+
+func (s *Srv) ScheduleGiftReceived(ctx context.Context, recipientEmail string, code string) error {
+	return s.subscribe(ctx, recipientEmail, s.cfg.GiftReceivedListTitle, map[string]string{
+		"gift_code": code,
+	})
+}
+
+func (s *Srv) SendMagicLink(ctx context.Context, email string, link string) error {
+	return s.send(ctx, TemplateMagicLink, email, TemplateData{Link: link})
+}
+
+func (s *Srv) SendPaymentReceipt(ctx context.Context, user domain.User, payment domain.Payment) error {
+	return s.send(ctx, TemplateReceipt, user.Email, TemplateData{User: user, Payment: payment})
+}
+
+func (s *Srv) SendTrialStarted(ctx context.Context, user domain.User) error {
+	return s.send(ctx, TemplateTrialStarted, user.Email, TemplateData{User: user})
+}
+
+func (s *Srv) SendRenewalSucceeded(ctx context.Context, user domain.User, payment domain.Payment) error {
+	return s.send(ctx, TemplateRenewalSucceeded, user.Email, TemplateData{User: user, Payment: payment})
+}
+
+func (s *Srv) SendPaymentFailed(ctx context.Context, user domain.User) error {
+	return s.send(ctx, TemplatePaymentFailed, user.Email, TemplateData{User: user})
+}
+
+func (s *Srv) SendExpiryReminder(ctx context.Context, user domain.User) error {
+	return s.send(ctx, TemplateExpiryReminder, user.Email, TemplateData{User: user})
+}
+
+func (s *Srv) subscribe(ctx context.Context, email string, listTitle string, fields map[string]string) error {
+	suppressed, err := s.suppressions.Suppressed(ctx, email)
+	if err != nil || suppressed {
+		return err
+	}
+
+	return s.lists.Subscribe(ctx, email, listTitle, fields)
+}
+
+// send renders and sends the template, magic link is requested by the
+// address owner, so it is sent even to suppressed addresses.
+func (s *Srv) send(ctx context.Context, name string, to string, data TemplateData) error {
+	if name != TemplateMagicLink {
+		if !slices.Contains(s.cfg.Enabled, name) {
+			return nil
+		}
+		suppressed, err := s.suppressions.Suppressed(ctx, to)
+		if err != nil || suppressed {
+			return err
+		}
+	}
+
+	msg, err := s.renderer.Render(name, s.cfg.Locale, to, data)
+	if err != nil {
+		return err
+	}
+
+	return s.transactional.Send(ctx, msg)
+}
+
+// validateHeader rejects values which could inject extra headers.
+func validateHeader(name string, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("invalid %s header value", name)
+	}
+
+	return nil
+}
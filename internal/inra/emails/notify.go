@@ -0,0 +1,43 @@
+package emails
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// NotifyTarget delivers admin notifications by email through the transactional provider.
+type NotifyTarget struct {
+	transactional Transactional
+	to            []string
+}
+
+func NewNotifyTarget(transactional Transactional, to []string) *NotifyTarget {
+	return &NotifyTarget{transactional: transactional, to: to}
+}
+
+func (r *NotifyTarget) Send(ctx context.Context, n notify.Notification) error {
+	subject := fmt.Sprintf("[Lumos %s] %s", n.Severity, n.Event)
+	text := n.Text()
+	body := "<pre>" + html.EscapeString(text) + "</pre>"
+
+	var errs []string
+	for _, to := range r.to {
+		err := r.transactional.Send(ctx, Message{
+			To:      to,
+			Subject: subject,
+			HTML:    body,
+			Text:    text,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", to, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to email notification: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+//go:build unit
+// +build unit
+
+package emails
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type smtpEnvelope struct {
+	from string
+	rcpt []string
+	data string
+}
+
+// startSMTPStandIn accepts a single plain-text SMTP session and reports what was received.
+func startSMTPStandIn(t *testing.T) (string, int, <-chan smtpEnvelope) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	out := make(chan smtpEnvelope, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var env smtpEnvelope
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = io.WriteString(conn, s+"\r\n") }
+
+		reply("220 localhost ESMTP stand-in")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				env.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				env.from = strings.SplitN(env.from, ">", 2)[0]
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				env.rcpt = append(env.rcpt, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 end with <CRLF>.<CRLF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(l, "."))
+				}
+				env.data = data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				out <- env
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	return host, portNum, out
+}
+
+func TestSMTPSend(t *testing.T) {
+	host, port, received := startSMTPStandIn(t)
+
+	srv, err := NewSMTP(SMTPConfig{
+		Host:      host,
+		Port:      port,
+		TLS:       SMTPTLSNone,
+		FromName:  "Lumos",
+		FromEmail: "noreply@lumos.test",
+		Timeout:   5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	renderer, err := NewRenderer(LocaleRU, time.UTC)
+	require.NoError(t, err)
+	msg, err := renderer.Render(TemplateMagicLink, LocaleRU, "user@example.com", TemplateData{Link: "https://lumos.test/login?token=a&b"})
+	require.NoError(t, err)
+	require.NoError(t, srv.Send(context.Background(), msg))
+
+	var env smtpEnvelope
+	select {
+	case env = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stand-in did not receive message")
+	}
+
+	require.Equal(t, "noreply@lumos.test", env.from)
+	require.Equal(t, []string{"user@example.com"}, env.rcpt)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(env.data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	require.Equal(t, "Вход в Lumos", subject)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	bodies := map[string]string{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+		ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[ct] = string(content)
+	}
+
+	require.Contains(t, bodies["text/plain"], "https://lumos.test/login?token=a&b")
+	require.Contains(t, bodies["text/html"], `href="https://lumos.test/login?token=a&amp;b"`)
+}
+
+func TestSMTPRejectsHeaderInjection(t *testing.T) {
+	srv, err := NewSMTP(SMTPConfig{
+		Host:      "127.0.0.1",
+		Port:      25,
+		TLS:       SMTPTLSNone,
+		FromEmail: "noreply@lumos.test",
+	})
+	require.NoError(t, err)
+
+	err = srv.Send(context.Background(), Message{To: "user@example.com", Subject: "hi\r\nBcc: x@example.com", HTML: "<p>hi</p>"})
+	require.Error(t, err)
+}
@@ -0,0 +1,208 @@
+package emails
+
+import (
+	"bytes"
+	"doctormakarhina/lumos/internal/core/domain"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	TemplateMagicLink        = "magic_link"
+	TemplateReceipt          = "receipt"
+	TemplateTrialStarted     = "trial_started"
+	TemplateRenewalSucceeded = "renewal_succeeded"
+	TemplatePaymentFailed    = "payment_failed"
+	TemplateExpiryReminder   = "expiry_reminder"
+)
+
+const (
+	LocaleRU = "ru"
+	LocaleEN = "en"
+)
+
+var (
+	Templates = []string{
+		TemplateMagicLink,
+		TemplateReceipt,
+		TemplateTrialStarted,
+		TemplateRenewalSucceeded,
+		TemplatePaymentFailed,
+		TemplateExpiryReminder,
+	}
+	Locales = []string{LocaleRU, LocaleEN}
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// TemplateData is passed to every template, Locale is set by the renderer.
+type TemplateData struct {
+	Locale  string
+	User    domain.User
+	Payment domain.Payment
+	Link    string
+}
+
+type templateKey struct {
+	name   string
+	locale string
+}
+
+type compiledTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// Renderer renders embedded html and plain-text templates, every template
+// must exist in every locale, it is checked on construction.
+type Renderer struct {
+	templates     map[templateKey]compiledTemplate
+	defaultLocale string
+}
+
+func NewRenderer(defaultLocale string, loc *time.Location) (*Renderer, error) {
+	if !slices.Contains(Locales, defaultLocale) {
+		return nil, fmt.Errorf("unknown email locale %q", defaultLocale)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	layout, err := templatesFS.ReadFile("templates/layout.html")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{templates: make(map[templateKey]compiledTemplate), defaultLocale: defaultLocale}
+	for _, locale := range Locales {
+		funcs := templateFuncs(locale, loc)
+		for _, name := range Templates {
+			htmlSrc, err := templatesFS.ReadFile(fmt.Sprintf("templates/%s/%s.html", locale, name))
+			if err != nil {
+				return nil, err
+			}
+			textSrc, err := templatesFS.ReadFile(fmt.Sprintf("templates/%s/%s.txt", locale, name))
+			if err != nil {
+				return nil, err
+			}
+
+			html, err := htmltemplate.New("layout").Funcs(htmltemplate.FuncMap(funcs)).Parse(string(layout))
+			if err == nil {
+				_, err = html.Parse(string(htmlSrc))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s/%s.html: %w", locale, name, err)
+			}
+			text, err := texttemplate.New(name).Funcs(funcs).Parse(string(textSrc))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s/%s.txt: %w", locale, name, err)
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("template %s/%s.txt has no subject", locale, name)
+			}
+
+			r.templates[templateKey{name: name, locale: locale}] = compiledTemplate{html: html, text: text}
+		}
+	}
+
+	return r, nil
+}
+
+// Render renders the template, unknown locale falls back to the default one.
+func (r *Renderer) Render(name string, locale string, to string, data TemplateData) (Message, error) {
+	if !slices.Contains(Locales, locale) {
+		locale = r.defaultLocale
+	}
+	tpl, ok := r.templates[templateKey{name: name, locale: locale}]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+	data.Locale = locale
+
+	var subject, html, text bytes.Buffer
+	if err := tpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tpl.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := tpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
+
+// Preview renders the template with sample data.
+func (r *Renderer) Preview(name string, locale string) (Message, error) {
+	return r.Render(name, locale, "user@example.com", SampleTemplateData())
+}
+
+func SampleTemplateData() TemplateData {
+	paidAt := time.Date(2025, time.March, 14, 12, 30, 0, 0, time.UTC)
+
+	return TemplateData{
+		User: domain.User{
+			Email:              "user@example.com",
+			Name:               "Анна",
+			Tariff:             domain.UserTariff3Months,
+			ExpiresAt:          paidAt.AddDate(0, 3, 0),
+			SubscriptionStatus: domain.UserSubStatusActive,
+			LastSubPrice:       2990,
+		},
+		Payment: domain.Payment{
+			Provider:      domain.PaymentProviderCloudPayments,
+			TransactionID: "1234567890",
+			Tariff:        domain.UserTariff3Months,
+			Amount:        2990,
+			PaidAt:        paidAt,
+		},
+		Link: "https://lumos.example.com/login?token=sample&source=email",
+	}
+}
+
+var tariffNamesEN = map[string]string{
+	domain.UserTariffUnlimited: "Unlimited",
+	domain.UserTariffTrial:     "Trial",
+	domain.UserTariff1Month:    "1 month",
+	domain.UserTariff3Months:   "3 months",
+	domain.UserTariff6Months:   "6 months",
+}
+
+func templateFuncs(locale string, loc *time.Location) texttemplate.FuncMap {
+	switch locale {
+	case LocaleEN:
+		return texttemplate.FuncMap{
+			"date": func(t time.Time) string { return t.In(loc).Format("January 2, 2006") },
+			"money": func(amount float32) string {
+				return strconv.FormatFloat(float64(amount), 'f', 2, 32) + " RUB"
+			},
+			"tariff": func(tariff string) string {
+				if name, ok := tariffNamesEN[tariff]; ok {
+					return name
+				}
+				return tariff
+			},
+		}
+	default:
+		return texttemplate.FuncMap{
+			"date": func(t time.Time) string { return t.In(loc).Format("02.01.2006") },
+			"money": func(amount float32) string {
+				return strings.Replace(strconv.FormatFloat(float64(amount), 'f', 2, 32), ".", ",", 1) + " ₽"
+			},
+			"tariff": func(tariff string) string { return tariff },
+		}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build unit
+// +build unit
+
+package emails
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func TestTemplatesGolden(t *testing.T) {
+	msk, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	renderer, err := NewRenderer(LocaleRU, msk)
+	require.NoError(t, err)
+
+	for _, locale := range Locales {
+		for _, name := range Templates {
+			t.Run(locale+"/"+name, func(t *testing.T) {
+				msg, err := renderer.Preview(name, locale)
+				require.NoError(t, err)
+				require.NotEmpty(t, msg.Subject)
+
+				got := "Subject: " + msg.Subject + "\n\n--- text ---\n" + msg.Text + "\n--- html ---\n" + msg.HTML
+				path := filepath.Join("testdata", "golden", locale, name+".golden")
+
+				if *updateGolden {
+					require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+					require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+				}
+
+				want, err := os.ReadFile(path)
+				require.NoError(t, err)
+				require.Equal(t, string(want), got)
+			})
+		}
+	}
+}
+
+func TestRenderUnknownLocaleFallsBack(t *testing.T) {
+	renderer, err := NewRenderer(LocaleEN, time.UTC)
+	require.NoError(t, err)
+
+	msg, err := renderer.Render(TemplateMagicLink, "de", "user@example.com", TemplateData{Link: "https://lumos.test"})
+	require.NoError(t, err)
+	require.Equal(t, "Sign in to Lumos", msg.Subject)
+}
@@ -2,23 +2,47 @@ package emails
 
 import (
 	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/pkg/tracing"
 	"fmt"
-	"slices"
+	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/sergeyandreenko/unisender"
-	"github.com/sergeyandreenko/unisender/lists"
+	"github.com/sergeyandreenko/unisender/contacts"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// importContacts accepts at most 500 contacts per call
+const maxImportBatch = 500
+
+// contact fields must be created in UniSender with the same names
+const (
+	fieldName               = "Name"
+	fieldTariff             = "tariff"
+	fieldExpiresAt          = "expires_at"
+	fieldSubscriptionStatus = "subscription_status"
+	fieldLastPrice          = "last_price"
 )
 
 type UniSenderSrvCfg struct {
-	AfterTrialExpiredListTitle         string
-	AfterReccurrentPaymentListTitle    string
-	AfterAutopaymentCancelledListTitle string
+	// TransactionalListTitle is the list required by UniSender for single sends
+	TransactionalListTitle string
+	SenderName             string
+	SenderEmail            string
+	// HTTPClient overrides default client, used by tests to reach a stub server
+	HTTPClient *http.Client
 }
 
+// UniSenderSrv is UniSender implementation of ListProvider and Transactional.
 type UniSenderSrv struct {
 	client *unisender.UniSender
 	cfg    UniSenderSrvCfg
+
+	mu      sync.Mutex
+	listIDs map[string]int64
 }
 
 func NewUniSenderSrv(
@@ -26,69 +50,139 @@ func NewUniSenderSrv(
 	cfg UniSenderSrvCfg,
 ) *UniSenderSrv {
 	client := unisender.New(apiKey)
-	return &UniSenderSrv{client: client, cfg: cfg}
+	if cfg.HTTPClient != nil {
+		client.SetClient(cfg.HTTPClient)
+	}
+	return &UniSenderSrv{client: client, cfg: cfg, listIDs: make(map[string]int64)}
 }
 
-func (r *UniSenderSrv) ScheduleAfterTrialExpired(ctx context.Context, email string) error {
-	return r.subsribeToList(ctx, email, r.cfg.AfterTrialExpiredListTitle)
-}
+func (r *UniSenderSrv) Subscribe(ctx context.Context, email string, listTitle string, fields map[string]string) (err error) {
+	_, span := tracing.Start(ctx, "unisender subscribe")
+	defer func() { tracing.End(span, err) }()
 
-func (r *UniSenderSrv) CancelTrialExpired(ctx context.Context, email string) error {
-	return r.excludeFromList(ctx, email, r.cfg.AfterTrialExpiredListTitle)
-}
+	listID, err := r.listID(listTitle)
+	if err != nil {
+		return err
+	}
 
-func (r *UniSenderSrv) ScheduleAfterReccurrentPayment(ctx context.Context, email string) error {
-	return r.subsribeToList(ctx, email, r.cfg.AfterReccurrentPaymentListTitle)
-}
+	req := r.client.Subscribe(listID).
+		Email(email).
+		DoubleOptinConfirmed()
+	if len(fields) == 0 {
+		req = req.DoNotOverwrite()
+	} else {
+		// fields carry per-event values (e.g. gift code), so they must replace previous ones
+		req = req.OverwritePartially()
+		for name, value := range fields {
+			req = req.Field(name, value)
+		}
+	}
 
-func (r *UniSenderSrv) ScheduleAfterAutopaymentCancelled(ctx context.Context, email string) error {
-	return r.subsribeToList(ctx, email, r.cfg.AfterAutopaymentCancelledListTitle)
-}
+	_, err = req.Execute()
 
-func (r *UniSenderSrv) subsribeToList(ctx context.Context, email string, listTitle string) error {
-	lists, err := r.client.GetLists().Execute()
 	if err != nil {
 		return err
 	}
-	list := r.findListWithTitle(listTitle, lists)
-	if list == nil {
-		return fmt.Errorf("failed to find list with title %s", listTitle)
-	}
 
-	_, err = r.client.Subscribe(list.ID).
-		Email(email).
-		DoNotOverwrite().
-		DoubleOptinConfirmed().
-		Execute()
+	return nil
+}
 
+func (r *UniSenderSrv) Exclude(ctx context.Context, email string, listTitle string) (err error) {
+	_, span := tracing.Start(ctx, "unisender exclude")
+	defer func() { tracing.End(span, err) }()
+
+	listID, err := r.listID(listTitle)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.client.Exclude(email).ContactTypeEmail().ListIDs(listID).Execute()
 }
 
-func (r *UniSenderSrv) excludeFromList(ctx context.Context, email string, listTitle string) error {
-	lists, err := r.client.GetLists().Execute()
+func (r *UniSenderSrv) Send(ctx context.Context, msg Message) (err error) {
+	_, span := tracing.Start(ctx, "unisender send email")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateHeader("to", msg.To); err != nil {
+		return err
+	}
+
+	listID, err := r.listID(r.cfg.TransactionalListTitle)
 	if err != nil {
 		return err
 	}
-	list := r.findListWithTitle(listTitle, lists)
-	if list == nil {
-		return fmt.Errorf("failed to find list with title %s", listTitle)
+
+	_, err = r.client.SendEmail(msg.To).
+		SenderName(r.cfg.SenderName).
+		SenderEmail(r.cfg.SenderEmail).
+		Subject(msg.Subject).
+		Body(msg.HTML).
+		ListID(listID).
+		Execute()
+
+	return err
+}
+
+// ExportContacts updates contact fields with importContacts, list
+// subscriptions and statuses are not touched.
+func (r *UniSenderSrv) ExportContacts(ctx context.Context, users []domain.User) (_ []string, err error) {
+	_, span := tracing.Start(ctx, "unisender import contacts", attribute.Int("contacts", len(users)))
+	defer func() { tracing.End(span, err) }()
+
+	var rejected []string
+	for start := 0; start < len(users); start += maxImportBatch {
+		batch := users[start:min(start+maxImportBatch, len(users))]
+
+		collection := contacts.NewImportContactsCollection()
+		for _, u := range batch {
+			collection.Email(u.Email).
+				SetField(fieldName, u.Name).
+				SetField(fieldTariff, u.Tariff).
+				SetField(fieldExpiresAt, u.ExpiresAt.Format("02.01.2006")).
+				SetField(fieldSubscriptionStatus, u.SubscriptionStatus).
+				SetField(fieldLastPrice, strconv.FormatFloat(float64(u.LastSubPrice), 'f', 2, 32))
+		}
+
+		res, err := r.client.ImportContacts(collection).Execute()
+		if err != nil {
+			return rejected, err
+		}
+		for _, l := range res.Log {
+			email := ""
+			if l.Index >= 0 && l.Index < len(batch) {
+				email = batch[l.Index].Email
+			}
+			rejected = append(rejected, fmt.Sprintf("%s: %s (%s)", email, l.Message, l.Code))
+		}
 	}
 
-	return r.client.Exclude(email).ContactTypeEmail().ListIDs(list.ID).Execute()
+	return rejected, nil
 }
 
-func (r *UniSenderSrv) findListWithTitle(targetTitle string, all []lists.GetListsResult) *lists.GetListsResult {
-	norm := strings.ToLower(strings.TrimSpace(targetTitle))
-	i := slices.IndexFunc(all, func(l lists.GetListsResult) bool {
-		return strings.ToLower(strings.TrimSpace(l.Title)) == norm
-	})
+// listID resolves list by title, lists are refetched only on cache miss
+// since they are created in UniSender UI and never renamed by us.
+func (r *UniSenderSrv) listID(title string) (int64, error) {
+	norm := strings.ToLower(strings.TrimSpace(title))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if i == -1 {
-		return nil
+	if id, ok := r.listIDs[norm]; ok {
+		return id, nil
+	}
+
+	all, err := r.client.GetLists().Execute()
+	if err != nil {
+		return 0, err
 	}
-	return &all[i]
+	for _, l := range all {
+		r.listIDs[strings.ToLower(strings.TrimSpace(l.Title))] = l.ID
+	}
+
+	id, ok := r.listIDs[norm]
+	if !ok {
+		return 0, fmt.Errorf("failed to find list with title %s", title)
+	}
+
+	return id, nil
 }
@@ -0,0 +1,140 @@
+//go:build unit
+// +build unit
+
+package emails
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// uniSenderStub serves UniSender API methods by name and records requests.
+type uniSenderStub struct {
+	mu       sync.Mutex
+	requests map[string][]url.Values
+	handlers map[string]func(form url.Values) string
+}
+
+func newUniSenderStub(t *testing.T, handlers map[string]func(form url.Values) string) (*uniSenderStub, *http.Client) {
+	t.Helper()
+
+	stub := &uniSenderStub{requests: map[string][]url.Values{}, handlers: handlers}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+		stub.mu.Lock()
+		stub.requests[method] = append(stub.requests[method], r.PostForm)
+		stub.mu.Unlock()
+
+		handler, ok := stub.handlers[method]
+		if !ok {
+			_, _ = fmt.Fprint(w, `{"error":"unknown method","code":"unknown_method"}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, handler(r.PostForm))
+	}))
+	t.Cleanup(srv.Close)
+
+	target, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: rewriteHost{target: target}}
+
+	return stub, client
+}
+
+func (s *uniSenderStub) calls(method string) []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[method]
+}
+
+type rewriteHost struct {
+	target *url.URL
+}
+
+func (t rewriteHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestUniSenderExportContactsBatches(t *testing.T) {
+	stub, client := newUniSenderStub(t, map[string]func(url.Values) string{
+		"importContacts": func(form url.Values) string {
+			if form.Get("data[0][0]") == "bad-email" {
+				return `{"result":{"total":1,"invalid":1,"log":[{"index":0,"code":"invalid_email","message":"invalid email"}]}}`
+			}
+			return `{"result":{"total":1,"updated":1,"log":[]}}`
+		},
+	})
+
+	srv := NewUniSenderSrv("key", UniSenderSrvCfg{HTTPClient: client})
+
+	users := make([]domain.User, 0, maxImportBatch+1)
+	for i := 0; i < maxImportBatch; i++ {
+		users = append(users, domain.User{Email: fmt.Sprintf("user%d@example.com", i)})
+	}
+	users = append(users, domain.User{
+		Email:              "bad-email",
+		Name:               "Анна",
+		Tariff:             domain.UserTariff1Month,
+		ExpiresAt:          time.Date(2025, time.March, 14, 0, 0, 0, 0, time.UTC),
+		SubscriptionStatus: domain.UserSubStatusActive,
+		LastSubPrice:       990,
+	})
+
+	rejected, err := srv.ExportContacts(context.Background(), users)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bad-email: invalid email (invalid_email)"}, rejected)
+
+	calls := stub.calls("importContacts")
+	require.Len(t, calls, 2)
+	require.Equal(t, "key", calls[0].Get("api_key"))
+	require.Equal(t, "user0@example.com", calls[0].Get("data[0][0]"))
+
+	last := calls[1]
+	values := map[string]string{}
+	for i := 0; last.Get(fmt.Sprintf("field_names[%d]", i)) != ""; i++ {
+		values[last.Get(fmt.Sprintf("field_names[%d]", i))] = last.Get(fmt.Sprintf("data[0][%d]", i))
+	}
+	require.Equal(t, map[string]string{
+		"email":               "bad-email",
+		"Name":                "Анна",
+		"tariff":              domain.UserTariff1Month,
+		"expires_at":          "14.03.2025",
+		"subscription_status": domain.UserSubStatusActive,
+		"last_price":          "990.00",
+	}, values)
+}
+
+func TestUniSenderCachesListIDs(t *testing.T) {
+	stub, client := newUniSenderStub(t, map[string]func(url.Values) string{
+		"getLists":  func(url.Values) string { return `{"result":[{"id":7,"title":"Lumos вход"}]}` },
+		"subscribe": func(url.Values) string { return `{"result":{"person_id":1}}` },
+		"exclude":   func(url.Values) string { return `{"result":{}}` },
+	})
+
+	srv := NewUniSenderSrv("key", UniSenderSrvCfg{HTTPClient: client})
+
+	require.NoError(t, srv.Subscribe(context.Background(), "a@example.com", "Lumos вход", nil))
+	require.NoError(t, srv.Exclude(context.Background(), "a@example.com", " lumos ВХОД "))
+	require.Error(t, srv.Subscribe(context.Background(), "a@example.com", "missing", nil))
+
+	require.Len(t, stub.calls("getLists"), 2)
+	require.Equal(t, "7", stub.calls("subscribe")[0].Get("list_ids"))
+}
@@ -0,0 +1,23 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// adminOnly allows requests with the admin bearer token only.
+func adminOnly(token string, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeJSON(w, logger, 401, ErrMsgRes{Message: "unauthorized"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package httpapi
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/pkg/authtoken"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type TokenVerifier interface {
+	Verify(token string) (*authtoken.Claims, error)
+}
+
+// Authenticator resolves the caller of user facing routes from the bearer token.
+// Legacy mode additionally accepts email and project_id search params, it is kept
+// for clients which are not migrated to tokens yet and will be removed.
+type Authenticator struct {
+	verifier        TokenVerifier
+	legacyEmailMode bool
+	logger          *slog.Logger
+}
+
+type identity struct {
+	UserID    string
+	Email     string
+	ProjectID string
+	Claims    *authtoken.Claims
+}
+
+func NewAuthenticator(verifier TokenVerifier, legacyEmailMode bool, logger *slog.Logger) *Authenticator {
+	return &Authenticator{
+		verifier:        verifier,
+		legacyEmailMode: legacyEmailMode,
+		logger:          logger.With(slog.String("context", "Authenticator")),
+	}
+}
+
+// identify writes error response and returns false if the caller can not be resolved.
+func (a *Authenticator) identify(w http.ResponseWriter, r *http.Request) (*identity, bool) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if found {
+		claims, err := a.verifier.Verify(token)
+		if err != nil {
+			msg := "invalid token"
+			if errors.Is(err, authtoken.ErrExpiredToken) {
+				msg = "token expired"
+			}
+			writeJSON(w, a.logger, 401, ErrMsgRes{Message: msg})
+			return nil, false
+		}
+
+		return &identity{
+			UserID:    claims.Subject,
+			Email:     claims.Email,
+			ProjectID: claims.ProjectID,
+			Claims:    claims,
+		}, true
+	}
+
+	if !a.legacyEmailMode {
+		writeJSON(w, a.logger, 401, ErrMsgRes{Message: "missing bearer token"})
+		return nil, false
+	}
+
+	email := r.URL.Query().Get("email")
+	projectID := r.URL.Query().Get("project_id")
+	if email == "" || projectID == "" {
+		writeJSON(w, a.logger, 400, ErrMsgRes{Message: "missing email or project_id in search params"})
+		return nil, false
+	}
+
+	w.Header().Set("Deprecation", "true")
+	a.logger.Debug("deprecated email search params auth is used", slog.String("path", r.URL.Path))
+
+	return &identity{Email: email, ProjectID: projectID}, true
+}
+
+// identifyUser resolves the caller account, writes error response and returns false on failure.
+func (a *Authenticator) identifyUser(w http.ResponseWriter, r *http.Request, srv payments.Service) (*domain.User, bool) {
+	id, ok := a.identify(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	return a.resolveUser(w, r, srv, id)
+}
+
+// identifyTokenUser is identifyUser which accepts only a verified bearer token,
+// it guards routes which must not be reachable with legacy email search params.
+func (a *Authenticator) identifyTokenUser(w http.ResponseWriter, r *http.Request, srv payments.Service) (*domain.User, bool) {
+	id, ok := a.identify(w, r)
+	if !ok {
+		return nil, false
+	}
+	if id.Claims == nil {
+		writeJSON(w, a.logger, 401, ErrMsgRes{Message: "missing bearer token"})
+		return nil, false
+	}
+
+	return a.resolveUser(w, r, srv, id)
+}
+
+func (a *Authenticator) resolveUser(w http.ResponseWriter, r *http.Request, srv payments.Service, id *identity) (*domain.User, bool) {
+	user, err := a.user(r.Context(), srv, id)
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidProjectId) {
+			writeJSON(w, a.logger, 403, ErrMsgRes{Message: "forbidden"})
+			return nil, false
+		}
+
+		writeJSON(w, a.logger, 500, ErrMsgRes{Message: "internal server error"})
+		return nil, false
+	}
+
+	if user == nil {
+		writeJSON(w, a.logger, 404, ErrMsgRes{Message: "not found"})
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (a *Authenticator) user(ctx context.Context, srv payments.Service, id *identity) (*domain.User, error) {
+	if id.UserID != "" {
+		return srv.UserByID(ctx, id.UserID, id.ProjectID)
+	}
+
+	return srv.User(ctx, id.Email, id.ProjectID)
+}
@@ -0,0 +1,34 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/core/deliverability"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type adminDeliverability struct {
+	srv    deliverability.Service
+	logger *slog.Logger
+}
+
+func (s *adminDeliverability) HandleInvalid(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+
+	invalid, err := s.srv.Invalid(r.Context(), limit)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "Invalid failed", slog.String("err", err.Error()))
+		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		return
+	}
+
+	writeJSON(w, s.logger, 200, InvalidEmailsRes{Emails: invalid})
+}
@@ -0,0 +1,60 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/inra/emails"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type EmailPreviewer interface {
+	Preview(name string, locale string) (emails.Message, error)
+}
+
+type adminEmails struct {
+	previewer     EmailPreviewer
+	defaultLocale string
+	logger        *slog.Logger
+}
+
+// HandlePreview renders the template with sample data, format is html (default) or text.
+func (s *adminEmails) HandlePreview(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "template")
+	if !slices.Contains(emails.Templates, name) {
+		writeJSON(w, s.logger, 404, ErrMsgRes{Message: "unknown template"})
+		return
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = s.defaultLocale
+	}
+	if !slices.Contains(emails.Locales, locale) {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "unknown locale"})
+		return
+	}
+
+	msg, err := s.previewer.Preview(name, locale)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "email preview failed", slog.String("template", name), slog.String("err", err.Error()))
+		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		return
+	}
+
+	w.Header().Set("X-Email-Subject", msg.Subject)
+	switch r.URL.Query().Get("format") {
+	case "", "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, msg.HTML)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, msg.Text)
+	default:
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "unknown format"})
+	}
+}
@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/core/analytics"
+	"doctormakarhina/lumos/internal/core/domain"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultAnalyticsRange = 7 * 24 * time.Hour
+
+type adminSearch struct {
+	srv    analytics.Service
+	logger *slog.Logger
+}
+
+func (s *adminSearch) HandleTop(w http.ResponseWriter, r *http.Request) {
+	rng, limit, ok := s.parseParams(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := s.srv.TopQueries(r.Context(), rng, limit)
+	if err != nil {
+		s.writeErr(w, "TopQueries", err)
+		return
+	}
+
+	writeJSON(w, s.logger, 200, SearchQueriesRes{From: rng.From, To: rng.To, Queries: stats})
+}
+
+func (s *adminSearch) HandleTrending(w http.ResponseWriter, r *http.Request) {
+	rng, limit, ok := s.parseParams(w, r)
+	if !ok {
+		return
+	}
+
+	trending, err := s.srv.TrendingQueries(r.Context(), rng, limit)
+	if err != nil {
+		s.writeErr(w, "TrendingQueries", err)
+		return
+	}
+
+	res := TrendingQueriesRes{From: rng.From, To: rng.To, Queries: make([]TrendingQueryRes, 0, len(trending))}
+	for _, q := range trending {
+		res.Queries = append(res.Queries, TrendingQueryRes{
+			Query:     q.Query,
+			Count:     q.Count,
+			PrevCount: q.PrevCount,
+			Growth:    q.Growth(),
+		})
+	}
+
+	writeJSON(w, s.logger, 200, res)
+}
+
+func (s *adminSearch) HandleZeroResults(w http.ResponseWriter, r *http.Request) {
+	rng, limit, ok := s.parseParams(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := s.srv.ZeroResultQueries(r.Context(), rng, limit)
+	if err != nil {
+		s.writeErr(w, "ZeroResultQueries", err)
+		return
+	}
+
+	writeJSON(w, s.logger, 200, SearchQueriesRes{From: rng.From, To: rng.To, Queries: stats})
+}
+
+// parseParams reads from, to and limit search params, from and to accept
+// either a date (to date is inclusive) or RFC3339 time, last week is used by default.
+func (s *adminSearch) parseParams(w http.ResponseWriter, r *http.Request) (domain.DateRange, int, bool) {
+	q := r.URL.Query()
+	now := time.Now()
+	rng := domain.DateRange{From: now.Add(-defaultAnalyticsRange), To: now}
+
+	if raw := q.Get("from"); raw != "" {
+		from, _, err := parseTimeParam(raw)
+		if err != nil {
+			writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid from"})
+			return rng, 0, false
+		}
+		rng.From = from
+	}
+	if raw := q.Get("to"); raw != "" {
+		to, dateOnly, err := parseTimeParam(raw)
+		if err != nil {
+			writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid to"})
+			return rng, 0, false
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		rng.To = to
+	}
+	if !rng.From.Before(rng.To) {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "from must be before to"})
+		return rng, 0, false
+	}
+
+	limit := 0
+	if raw := q.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid limit"})
+			return rng, 0, false
+		}
+		limit = parsed
+	}
+
+	return rng, limit, true
+}
+
+func (s *adminSearch) writeErr(w http.ResponseWriter, op string, err error) {
+	s.logger.Error(fmt.Sprintf("%s failed", op), slog.String("err", err.Error()))
+	writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+}
+
+func parseTimeParam(raw string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, raw, time.Local); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	return t, false, err
+}
@@ -0,0 +1,25 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/pkg/ingest"
+	"log/slog"
+	"net/http"
+)
+
+type QueueStats interface {
+	Stats() ingest.Stats
+}
+
+type adminTelemetry struct {
+	queues map[string]QueueStats
+	logger *slog.Logger
+}
+
+func (s *adminTelemetry) HandleStats(w http.ResponseWriter, r *http.Request) {
+	res := make(map[string]ingest.Stats, len(s.queues))
+	for name, q := range s.queues {
+		res[name] = q.Stats()
+	}
+
+	writeJSON(w, s.logger, 200, res)
+}
@@ -1,82 +1,72 @@
 package httpapi
 
 import (
-	"context"
+	"doctormakarhina/lumos/internal/core/domain"
 	"doctormakarhina/lumos/internal/pkg/errs"
-	"encoding/json"
+	"doctormakarhina/lumos/internal/pkg/geoip"
+	"doctormakarhina/lumos/internal/pkg/httpx"
 	"log/slog"
 	"net/http"
-	"strings"
-
-	"github.com/jmoiron/sqlx"
+	"time"
 )
 
+type AuthLogQueue interface {
+	Enqueue(items ...domain.AuthLog) int
+}
+
 type authLogs struct {
-	db     *sqlx.DB
+	queue  AuthLogQueue
+	geo    geoip.Locator
 	logger *slog.Logger
 }
 
+// Handle accepts a single telemetry object or an array of them.
 func (s *authLogs) Handle(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusCreated)
-
-	var req SaveAuthTelemetryRequestBody
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	reqs, err := decodeBatch[SaveAuthTelemetryRequestBody](w, r)
+	if err != nil {
 		err = errs.WrapErrorf(err, errs.ErrCodeParsingFailed, "failed to parse request body")
-		s.logger.Error(
+		s.logger.ErrorContext(r.Context(),
 			"failed to parse request",
 			slog.String("err", err.Error()),
 		)
-
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid request body"})
 		return
 	}
 
-	err := s.saveAuthTelemetry(
-		r.Context(),
-		req.Login,
-		r.UserAgent(),
-		r.RemoteAddr,
-		req.Fingerprint,
-		req.ConfidenceScore,
-	)
+	ipAddr := authLogIP(r)
+	loc := s.geo.Lookup(ipAddr)
+	now := time.Now()
 
-	if err != nil {
-		s.logger.Error(
-			"failed to SaveAuthTelemetry",
-			slog.String("err", err.Error()),
-		)
-	}
-}
-
-func (s *authLogs) saveAuthTelemetry(
-	ctx context.Context,
-	login string,
-	userAgent string,
-	ipAddrRaw string,
-	fingerprintId string,
-	fingerprintScore string,
-) error {
-	if login == "" {
-		return nil
-	}
-
-	ipAddr := "unknown"
-	if ipAddrRaw != "" {
-		parts := strings.Split(ipAddrRaw, ":")
-		if len(parts) > 0 {
-			ipAddr = parts[0]
-		} else {
-			ipAddr = ipAddrRaw
+	logs := make([]domain.AuthLog, 0, len(reqs))
+	for _, req := range reqs {
+		if req.Login == "" {
+			continue
 		}
+
+		logs = append(logs, domain.AuthLog{
+			Login:           req.Login,
+			IP:              ipAddr,
+			UserAgent:       r.UserAgent(),
+			Fingerprint:     req.Fingerprint,
+			ConfidenceScore: req.ConfidenceScore,
+			Country:         loc.Country,
+			City:            loc.City,
+			CreatedAt:       now,
+		})
 	}
 
-	query := `
-		INSERT INTO lumos.auth_logs
-		(login, ip, fingerprint, confidencescore, useragent)
-		VALUES
-		($1, $2, $3, $4, $5);
-    `
+	// telemetry is best effort, dropped items are counted by the queue
+	s.queue.Enqueue(logs...)
 
-	_, err := s.db.ExecContext(ctx, query, login, ipAddr, fingerprintId, fingerprintScore, userAgent)
+	w.WriteHeader(http.StatusCreated)
+}
 
-	return err
+// authLogIP is client ip written to auth logs by every login source,
+// "unknown" when it is not known, ip based queries skip it.
+func authLogIP(r *http.Request) string {
+	ipAddr := httpx.ClientIPFrom(r.Context())
+	if ipAddr == "" {
+		return "unknown"
+	}
+	return ipAddr
 }
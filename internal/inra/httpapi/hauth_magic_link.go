@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/core/auth"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/pkg/geoip"
+	"doctormakarhina/lumos/internal/pkg/httpx"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+type magicLink struct {
+	srv    auth.Service
+	geo    geoip.Locator
+	logger *slog.Logger
+}
+
+func (s *magicLink) HandleRequest(w http.ResponseWriter, r *http.Request) {
+	var req MagicLinkRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid request body"})
+		return
+	}
+
+	if req.Email == "" || req.ProjectID == "" {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "missing email or project_id"})
+		return
+	}
+
+	err := s.srv.RequestMagicLink(r.Context(), req.Email, req.ProjectID, httpx.ClientIPFrom(r.Context()))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidProjectID):
+			writeJSON(w, s.logger, 403, ErrMsgRes{Message: "forbidden"})
+		case errors.Is(err, auth.ErrUnknownUser):
+			writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid email"})
+		case errors.Is(err, auth.ErrRateLimited):
+			writeJSON(w, s.logger, 429, ErrMsgRes{Message: "too many requests"})
+		default:
+			s.logger.ErrorContext(r.Context(), "RequestMagicLink failed",
+				slog.String("email", req.Email),
+				slog.String("err", err.Error()),
+			)
+			writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *magicLink) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	var req MagicLinkVerifyRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid request body"})
+		return
+	}
+
+	if req.Token == "" {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "missing token"})
+		return
+	}
+
+	ipAddr := authLogIP(r)
+	loc := s.geo.Lookup(ipAddr)
+	token, err := s.srv.VerifyMagicLink(r.Context(), req.Token, domain.AuthLog{
+		IP:              ipAddr,
+		UserAgent:       r.UserAgent(),
+		Fingerprint:     req.Fingerprint,
+		ConfidenceScore: req.ConfidenceScore,
+		Country:         loc.Country,
+		City:            loc.City,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidMagicLink):
+			writeJSON(w, s.logger, 401, ErrMsgRes{Message: "invalid or expired link"})
+		case errors.Is(err, auth.ErrInvalidProjectID):
+			writeJSON(w, s.logger, 403, ErrMsgRes{Message: "forbidden"})
+		case errors.Is(err, auth.ErrUnknownUser):
+			writeJSON(w, s.logger, 404, ErrMsgRes{Message: "not found"})
+		default:
+			s.logger.ErrorContext(r.Context(), "VerifyMagicLink failed", slog.String("err", err.Error()))
+			writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		}
+		return
+	}
+
+	writeJSON(w, s.logger, 200, AccessTokenRes{Token: token.Token, ExpiresAt: token.ExpiresAt})
+}
@@ -0,0 +1,45 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/core/auth"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+type authToken struct {
+	srv    auth.Service
+	auth   *Authenticator
+	logger *slog.Logger
+}
+
+func (s *authToken) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.auth.identify(w, r)
+	if !ok {
+		return
+	}
+	if id.Claims == nil {
+		writeJSON(w, s.logger, 401, ErrMsgRes{Message: "missing bearer token"})
+		return
+	}
+
+	token, err := s.srv.RefreshToken(r.Context(), *id.Claims)
+	if err != nil {
+		s.writeErr(w, err)
+		return
+	}
+
+	writeJSON(w, s.logger, 200, AccessTokenRes{Token: token.Token, ExpiresAt: token.ExpiresAt})
+}
+
+func (s *authToken) writeErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidProjectID):
+		writeJSON(w, s.logger, 403, ErrMsgRes{Message: "forbidden"})
+	case errors.Is(err, auth.ErrUnknownUser):
+		writeJSON(w, s.logger, 404, ErrMsgRes{Message: "not found"})
+	default:
+		s.logger.Error("failed to issue token", slog.String("err", err.Error()))
+		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+	}
+}
@@ -1,22 +1,26 @@
 package httpapi
 
 import (
+	"doctormakarhina/lumos/internal/core/domain"
 	"doctormakarhina/lumos/internal/core/notify"
 	"doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/core/referrals"
 	"fmt"
 	"log/slog"
 	"net/http"
 )
 
 type cloudPaymentsPayNotification struct {
-	logger  *slog.Logger
-	srv     payments.Service
-	notifer notify.Service
+	logger      *slog.Logger
+	srv         payments.Service
+	referralSrv referrals.Service
+	notifer     notify.Service
 }
 
 func (s *cloudPaymentsPayNotification) Handle(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		s.notifer.ForAdmin("[CloudPaymentsPayHandler] recieve invalid form")
+		observeWebhook(webhookProviderCloudPayments, webhookResultInvalid)
 		http.Error(w, "invalid form", http.StatusBadRequest)
 		return
 	}
@@ -28,15 +32,18 @@ func (s *cloudPaymentsPayNotification) Handle(w http.ResponseWriter, r *http.Req
 	subscriptionID := r.FormValue("SubscriptionId")
 	opType := r.FormValue("OperationType")
 	transactionID := r.FormValue("TransactionId")
+	data := parseCloudPaymentsData(r.FormValue("Data"))
 
 	if opType != "Payment" {
 		s.notifer.ForAdmin(fmt.Sprintf("[CloudPaymentsPayHandler] recieve payment notification with invalid operation type = %s. email = %s, tariff = %s", opType, email, tariff))
+		observeWebhook(webhookProviderCloudPayments, webhookResultIgnored)
 		writeJSON(w, s.logger, 200, CloudPaymentsNotificationRes{Code: 0})
 		return
 	}
 
 	if email == "" || tariff == "" {
 		s.notifer.ForAdmin(fmt.Sprintf("[CloudPaymentsPayHandler] recieve payment notification with empty required fields email = %s, tariff = %s", email, tariff))
+		observeWebhook(webhookProviderCloudPayments, webhookResultInvalid)
 		writeJSON(w, s.logger, 200, CloudPaymentsNotificationRes{Code: 0})
 		return
 	}
@@ -44,10 +51,43 @@ func (s *cloudPaymentsPayNotification) Handle(w http.ResponseWriter, r *http.Req
 	priceParsed, err := parsePrice(priceStr)
 	if err != nil {
 		s.notifer.ForAdmin(fmt.Sprintf("[CloudPaymentsPayHandler] recieve payment notification with invalid price = %s. email = %s, tariff = %s", priceStr, email, tariff))
+		observeWebhook(webhookProviderCloudPayments, webhookResultInvalid)
 		writeJSON(w, s.logger, 200, CloudPaymentsNotificationRes{Code: 0})
 		return
 	}
 
+	if isGiftPurchase(email, data.GiftEmail) {
+		err = s.srv.RegisterGift(
+			r.Context(),
+			domain.PaymentProviderCloudPayments,
+			tariff,
+			email,
+			data.GiftEmail,
+			float32(priceParsed),
+			transactionID,
+			subscriptionID,
+		)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "RegisterGift failed",
+				slog.String("email", email),
+				slog.String("giftEmail", data.GiftEmail),
+				slog.String("tariff", tariff),
+				slog.String("transactionID", transactionID),
+				slog.String("err", err.Error()),
+			)
+			observeWebhook(webhookProviderCloudPayments, webhookResultError)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		observeWebhook(webhookProviderCloudPayments, webhookResultGift)
+		writeJSON(w, s.logger, 200, CloudPaymentsNotificationRes{Code: 0})
+		return
+	}
+
+	// attribution failures are reported to admins by the service and must not fail the payment
+	_ = s.referralSrv.Attribute(r.Context(), email, data.ReferralCode, domain.ReferralSourcePayment)
+
 	err = s.srv.RegisterFromCloudPayments(
 		r.Context(),
 		tariff,
@@ -56,19 +96,22 @@ func (s *cloudPaymentsPayNotification) Handle(w http.ResponseWriter, r *http.Req
 		float32(priceParsed),
 		subscriptionID,
 		transactionID,
+		data.ProjectID,
 	)
 	if err != nil {
-		s.logger.Error("RegisterFromCloudPayments failed",
+		s.logger.ErrorContext(r.Context(), "RegisterFromCloudPayments failed",
 			slog.String("email", email),
 			slog.String("tariff", tariff),
 			slog.String("subscriptionID", subscriptionID),
 			slog.String("transactionID", transactionID),
 			slog.String("err", err.Error()),
 		)
+		observeWebhook(webhookProviderCloudPayments, webhookResultError)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 
+	observeWebhook(webhookProviderCloudPayments, webhookResultOK)
 	writeJSON(w, s.logger, 200, CloudPaymentsNotificationRes{Code: 0})
 
 	//  TransactionId 2147332467
@@ -104,6 +147,7 @@ func (s *cloudPaymentsPayNotification) Handle(w http.ResponseWriter, r *http.Req
 	// Status Completed
 	// GatewayName Tbank
 	// DataLong String
+	// Data {"giftEmail": "...", "referralCode": "...", "projectId": "..."} (optional, gift recipient email, referral code and project id)
 	// TotalFee 3.90
 	// CardProduct TKN
 	// PaymentMethod TinkoffPay
@@ -17,6 +17,7 @@ type cloudPaymentReccurentNotif struct {
 func (s *cloudPaymentReccurentNotif) Handle(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		s.notifer.ForAdmin("[CloudPaymentsPayHandler] recieve invalid form")
+		observeWebhook(webhookProviderCloudPaymentsRecurrent, webhookResultInvalid)
 		http.Error(w, "invalid form", http.StatusBadRequest)
 		return
 	}
@@ -27,22 +28,25 @@ func (s *cloudPaymentReccurentNotif) Handle(w http.ResponseWriter, r *http.Reque
 
 	if subscriptionID == "" || email == "" {
 		s.notifer.ForAdmin(fmt.Sprintf("[cloudPaymentReccurentNotif] recieve form without required fields: subscriptionID=%s, email=%s", subscriptionID, email))
+		observeWebhook(webhookProviderCloudPaymentsRecurrent, webhookResultInvalid)
 		writeJSON(w, s.logger, 200, CloudPaymentsNotificationRes{Code: 0})
 		return
 	}
 
 	err := s.srv.RegisterCloudPaymentReccurent(r.Context(), subscriptionID, email, status)
 	if err != nil {
-		s.logger.Error("RegisterCloudPaymentReccurent failed",
+		s.logger.ErrorContext(r.Context(), "RegisterCloudPaymentReccurent failed",
 			slog.String("email", email),
 			slog.String("subscriptionID", subscriptionID),
 			slog.String("status", status),
 			slog.String("err", err.Error()),
 		)
+		observeWebhook(webhookProviderCloudPaymentsRecurrent, webhookResultError)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 
+	observeWebhook(webhookProviderCloudPaymentsRecurrent, webhookResultOK)
 	writeJSON(w, s.logger, 200, CloudPaymentsNotificationRes{Code: 0})
 
 	//    Id caflksdjfklasjfaskl0
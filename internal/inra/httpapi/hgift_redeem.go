@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/core/payments"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+type giftRedeem struct {
+	srv    payments.Service
+	logger *slog.Logger
+}
+
+func (s *giftRedeem) Handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid form"})
+		return
+	}
+
+	code := r.FormValue("code")
+	email := r.FormValue("email")
+	name := r.FormValue("name")
+
+	if code == "" || email == "" {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "missing code or email in form"})
+		return
+	}
+
+	user, err := s.srv.RedeemGift(r.Context(), code, email, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, payments.ErrGiftNotFound):
+			writeJSON(w, s.logger, 404, ErrMsgRes{Message: "gift not found"})
+		case errors.Is(err, payments.ErrGiftAlreadyRedeemed):
+			writeJSON(w, s.logger, 409, ErrMsgRes{Message: "gift already redeemed"})
+		case errors.Is(err, payments.ErrGiftNotApplicable):
+			writeJSON(w, s.logger, 409, ErrMsgRes{Message: "gift can not be applied"})
+		default:
+			s.logger.ErrorContext(r.Context(), "RedeemGift failed",
+				slog.String("email", email),
+				slog.String("err", err.Error()),
+			)
+			writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		}
+		return
+	}
+
+	writeJSON(w, s.logger, 200, GiftRedeemRes{
+		Email:     user.Email,
+		Tariff:    user.Tariff,
+		ExpiresAt: user.ExpiresAt,
+	})
+}
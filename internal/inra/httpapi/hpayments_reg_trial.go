@@ -1,22 +1,24 @@
 package httpapi
 
 import (
+	"doctormakarhina/lumos/internal/core/domain"
 	"doctormakarhina/lumos/internal/core/notify"
 	"doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/core/referrals"
 	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 )
 
 type paymentRegTrial struct {
-	srv     payments.Service
-	notifer notify.Service
+	srv         payments.Service
+	referralSrv referrals.Service
+	notifer     notify.Service
 }
 
 func (s *paymentRegTrial) Handle(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		s.notifer.ForAdmin("[TrialFormHanlder] recieve invalid form")
+		observeWebhook(webhookProviderTrial, webhookResultInvalid)
 		http.Error(w, "invalid form", http.StatusBadRequest)
 		return
 	}
@@ -24,32 +26,32 @@ func (s *paymentRegTrial) Handle(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	email := r.FormValue("email")
 	// phone := r.FormValue("phone")
-	daysDuration := r.FormValue("daysDuration")
+	// trial length is resolved from the campaign on server side, client supplied duration is ignored
+	campaign := r.FormValue("campaign")
+	source := r.FormValue("formid")
+	referralCode := r.FormValue("referral")
+	fingerprint := r.FormValue("fingerprint")
 
 	if email == "" {
 		s.notifer.ForAdmin(fmt.Sprintf("[TrialFormHandler] recieve email form without email field specified, name = %s", name))
+		observeWebhook(webhookProviderTrial, webhookResultInvalid)
 		w.WriteHeader(http.StatusCreated)
 		// http.Error(w, "missing required fields", http.StatusBadRequest)
 		return
 	}
 
-	parsedDuration, err := strconv.Atoi(daysDuration)
-	if err != nil {
-		s.notifer.ForAdmin(fmt.Sprintf("[TrialFormHanlder] recieve email form without valid duration field specified, email = %s, duration = %s", email, daysDuration))
-		w.WriteHeader(http.StatusCreated)
-		// http.Error(w, "invalid duration", http.StatusBadRequest)
-		return
-	}
-
-	trialDuration := time.Duration(parsedDuration) * time.Hour * 24
-
-	err = s.srv.RegisterFromTrial(r.Context(), email, name, trialDuration)
+	err := s.srv.RegisterFromTrial(r.Context(), email, name, campaign, source, fingerprint)
 	if err != nil {
+		observeWebhook(webhookProviderTrial, webhookResultError)
 		// s.notifer.ForAdmin(fmt.Sprintf("[TrialFormHandler] failed to register user from trial, email = %s, name = %s, phone = %s, duration = %d", email, name, phone, trialDuration))
 		w.WriteHeader(http.StatusCreated)
 		// http.Error(w, "failed to register", http.StatusInternalServerError)
 		return
 	}
 
+	// attribution failures are reported to admins by the service and must not fail registration
+	_ = s.referralSrv.Attribute(r.Context(), email, referralCode, domain.ReferralSourceTrial)
+
+	observeWebhook(webhookProviderTrial, webhookResultOK)
 	w.WriteHeader(http.StatusCreated)
 }
@@ -1,22 +1,26 @@
 package httpapi
 
 import (
+	"doctormakarhina/lumos/internal/core/domain"
 	"doctormakarhina/lumos/internal/core/notify"
 	"doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/core/referrals"
 	"fmt"
 	"log/slog"
 	"net/http"
 )
 
 type prodamusPayNotification struct {
-	logger  *slog.Logger
-	srv     payments.Service
-	notifer notify.Service
+	logger      *slog.Logger
+	srv         payments.Service
+	referralSrv referrals.Service
+	notifer     notify.Service
 }
 
 func (s *prodamusPayNotification) Handle(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		s.notifer.ForAdmin("[ProdamusPayHandler] recieve invalid form")
+		observeWebhook(webhookProviderProdamus, webhookResultInvalid)
 		http.Error(w, "invalid form", http.StatusBadRequest)
 		return
 	}
@@ -28,15 +32,20 @@ func (s *prodamusPayNotification) Handle(w http.ResponseWriter, r *http.Request)
 	name := r.FormValue("_param_name")
 	paymentStatus := r.FormValue("payment_status")
 	orderID := r.FormValue("order_id")
+	giftEmail := r.FormValue("_param_gift_email")
+	referralCode := r.FormValue("_param_referral")
+	projectID := r.FormValue("_param_project")
 
 	if paymentStatus != "success" {
 		s.notifer.ForAdmin(fmt.Sprintf("[ProdamusPayHandler] recieve payment notification with not success status = %s, email = %s, tariff = %s, price = %s, name = %s", paymentStatus, email, tariff, price, name))
+		observeWebhook(webhookProviderProdamus, webhookResultIgnored)
 		writeJSON(w, s.logger, 200, ProdamusPayNotificationRes{Success: true})
 		return
 	}
 
 	if email == "" || tariff == "" {
 		s.notifer.ForAdmin(fmt.Sprintf("[ProdamusPayHandler] recieve payment notification with empty required fields email = %s, tariff = %s", email, tariff))
+		observeWebhook(webhookProviderProdamus, webhookResultInvalid)
 		writeJSON(w, s.logger, 200, ProdamusPayNotificationRes{Success: true})
 		return
 	}
@@ -44,10 +53,43 @@ func (s *prodamusPayNotification) Handle(w http.ResponseWriter, r *http.Request)
 	priceParsed, err := parsePrice(price)
 	if err != nil {
 		s.notifer.ForAdmin(fmt.Sprintf("[ProdamusPayHandler] recieve payment notification with invalid price = %s. email = %s, tariff = %s", price, email, tariff))
+		observeWebhook(webhookProviderProdamus, webhookResultInvalid)
 		writeJSON(w, s.logger, 200, ProdamusPayNotificationRes{Success: true})
 		return
 	}
 
+	if isGiftPurchase(email, giftEmail) {
+		err = s.srv.RegisterGift(
+			r.Context(),
+			domain.PaymentProviderProdamus,
+			tariff,
+			email,
+			giftEmail,
+			float32(priceParsed),
+			orderID,
+			"",
+		)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "RegisterGift failed",
+				slog.String("email", email),
+				slog.String("giftEmail", giftEmail),
+				slog.String("tariff", tariff),
+				slog.String("orderID", orderID),
+				slog.String("err", err.Error()),
+			)
+			observeWebhook(webhookProviderProdamus, webhookResultError)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		observeWebhook(webhookProviderProdamus, webhookResultGift)
+		writeJSON(w, s.logger, 200, ProdamusPayNotificationRes{Success: true})
+		return
+	}
+
+	// attribution failures are reported to admins by the service and must not fail the payment
+	_ = s.referralSrv.Attribute(r.Context(), email, referralCode, domain.ReferralSourcePayment)
+
 	err = s.srv.RegisterFromProdamus(
 		r.Context(),
 		tariff,
@@ -55,18 +97,21 @@ func (s *prodamusPayNotification) Handle(w http.ResponseWriter, r *http.Request)
 		name,
 		float32(priceParsed),
 		orderID,
+		projectID,
 	)
 	if err != nil {
-		s.logger.Error("RegisterFromProdamus failed",
+		s.logger.ErrorContext(r.Context(), "RegisterFromProdamus failed",
 			slog.String("email", email),
 			slog.String("tariff", tariff),
 			slog.String("orderID", orderID),
 			slog.String("err", err.Error()),
 		)
+		observeWebhook(webhookProviderProdamus, webhookResultError)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 
+	observeWebhook(webhookProviderProdamus, webhookResultOK)
 	writeJSON(w, s.logger, 200, ProdamusPayNotificationRes{Success: true})
 
 	// date 2026-02-07T20:27:09+03:00
@@ -86,6 +131,9 @@ func (s *prodamusPayNotification) Handle(w http.ResponseWriter, r *http.Request)
 	//    attempt 1
 	//    sys tilda
 	//    _param_name Любовь
+	//    _param_gift_email (optional, recipient email for gift purchases)
+	//    _param_referral (optional, referral code of the inviting user)
+	//    _param_project (optional, project id for non default course sites)
 	//    productsArray 1Collection
 	//            name Продление 3 месяца
 	//            price 2290.00
@@ -1,58 +1,53 @@
 package httpapi
 
 import (
-	"context"
+	"doctormakarhina/lumos/internal/core/domain"
 	"doctormakarhina/lumos/internal/pkg/errs"
-	"encoding/json"
 	"log/slog"
 	"net/http"
+	"time"
 	"unicode/utf8"
-
-	"github.com/jmoiron/sqlx"
 )
 
+type SearchQueryQueue interface {
+	Enqueue(items ...domain.SearchQuery) int
+}
+
 type searchLogs struct {
-	db     *sqlx.DB
+	queue  SearchQueryQueue
 	logger *slog.Logger
 }
 
+// Handle accepts a single telemetry object or an array of them.
 func (s *searchLogs) Handle(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusCreated)
-
-	var req SaveSearchTelemetryRequestBody
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	reqs, err := decodeBatch[SaveSearchTelemetryRequestBody](w, r)
+	if err != nil {
 		err = errs.WrapErrorf(err, errs.ErrCodeParsingFailed, "failed to parse request body")
-		s.logger.Error(
+		s.logger.ErrorContext(r.Context(),
 			"failed to parse request",
 			slog.String("err", err.Error()),
 		)
-
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid request body"})
 		return
 	}
 
-	err := s.saveLogsQuery(r.Context(), req.Query)
-	if err != nil {
-		s.logger.Error(
-			"failed to SaveLogsQuery",
-			slog.String("err", err.Error()),
-		)
-	}
-}
+	now := time.Now()
 
-func (s *searchLogs) saveLogsQuery(
-	ctx context.Context,
-	searchQuery string,
-) error {
-	if searchQuery == "" {
-		return nil
-	}
-	if utf8.RuneCountInString(searchQuery) <= 2 {
-		return nil
-	}
+	queries := make([]domain.SearchQuery, 0, len(reqs))
+	for _, req := range reqs {
+		if utf8.RuneCountInString(req.Query) <= 2 {
+			continue
+		}
 
-	query := `INSERT INTO lumos.search_queries (query) VALUES ($1)`
+		queries = append(queries, domain.SearchQuery{
+			Query:        req.Query,
+			ResultsCount: req.ResultsCount,
+			CreatedAt:    now,
+		})
+	}
 
-	_, err := s.db.ExecContext(ctx, query, searchQuery)
+	// telemetry is best effort, dropped items are counted by the queue
+	s.queue.Enqueue(queries...)
 
-	return err
+	w.WriteHeader(http.StatusCreated)
 }
@@ -1,15 +1,21 @@
 package httpapi
 
 import (
+	"doctormakarhina/lumos/internal/core/analytics"
+	"doctormakarhina/lumos/internal/core/auth"
+	"doctormakarhina/lumos/internal/core/deliverability"
+	"doctormakarhina/lumos/internal/core/members"
 	"doctormakarhina/lumos/internal/core/notify"
 	"doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/core/referrals"
+	"doctormakarhina/lumos/internal/core/seats"
+	"doctormakarhina/lumos/internal/pkg/geoip"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/jmoiron/sqlx"
 )
 
 func NewRouter() *chi.Mux {
@@ -32,26 +38,125 @@ func RegInHealthz(r chi.Router, logger *slog.Logger) {
 	})
 }
 
-func RegInAuthLogs(r chi.Router, db *sqlx.DB, rootLogger *slog.Logger) {
-	srv := &authLogs{db: db, logger: rootLogger.With(slog.String("context", "AuthLogs"))}
+func RegInAuthLogs(r chi.Router, queue AuthLogQueue, geo geoip.Locator, rootLogger *slog.Logger) {
+	srv := &authLogs{queue: queue, geo: geo, logger: rootLogger.With(slog.String("context", "AuthLogs"))}
 
 	r.Post("/auth/telemetry", srv.Handle)
 }
 
-func RegInSearchLogs(r chi.Router, db *sqlx.DB, rootLogger *slog.Logger) {
-	srv := &searchLogs{db: db, logger: rootLogger.With(slog.String("context", "SearchLogs"))}
+func RegInSearchLogs(r chi.Router, queue SearchQueryQueue, rootLogger *slog.Logger) {
+	srv := &searchLogs{queue: queue, logger: rootLogger.With(slog.String("context", "SearchLogs"))}
 
 	r.Post("/search/telemetry", srv.Handle)
 }
 
+// RegInAdminSearchRoutes registers search analytics routes, they are not
+// registered at all when admin token is not configured.
+func RegInAdminSearchRoutes(
+	r chi.Router,
+	adminToken string,
+	srv analytics.Service,
+	rootLogger *slog.Logger,
+) {
+	if adminToken == "" {
+		return
+	}
+
+	logger := rootLogger.With(slog.String("context", "AdminSearchHandler"))
+	adminSearchSrv := adminSearch{srv: srv, logger: logger}
+
+	r.Group(func(r chi.Router) {
+		r.Use(adminOnly(adminToken, logger))
+		r.Get("/admin/search/top", adminSearchSrv.HandleTop)
+		r.Get("/admin/search/trending", adminSearchSrv.HandleTrending)
+		r.Get("/admin/search/zero-results", adminSearchSrv.HandleZeroResults)
+	})
+}
+
+// RegInAdminTelemetryRoutes registers ingestion queues stats route, it is not
+// registered when admin token is not configured.
+func RegInAdminTelemetryRoutes(
+	r chi.Router,
+	adminToken string,
+	queues map[string]QueueStats,
+	rootLogger *slog.Logger,
+) {
+	if adminToken == "" {
+		return
+	}
+
+	logger := rootLogger.With(slog.String("context", "AdminTelemetryHandler"))
+	adminTelemetrySrv := adminTelemetry{queues: queues, logger: logger}
+
+	r.With(adminOnly(adminToken, logger)).Get("/admin/telemetry/stats", adminTelemetrySrv.HandleStats)
+}
+
+// RegInAdminEmailRoutes registers email templates preview, it is not
+// registered when admin token is not configured.
+func RegInAdminEmailRoutes(
+	r chi.Router,
+	adminToken string,
+	previewer EmailPreviewer,
+	defaultLocale string,
+	rootLogger *slog.Logger,
+) {
+	if adminToken == "" {
+		return
+	}
+
+	logger := rootLogger.With(slog.String("context", "AdminEmailsHandler"))
+	adminEmailsSrv := adminEmails{previewer: previewer, defaultLocale: defaultLocale, logger: logger}
+
+	r.With(adminOnly(adminToken, logger)).Get("/admin/emails/preview/{template}", adminEmailsSrv.HandlePreview)
+}
+
+// RegInUniSenderWebhook registers UniSender event notifications route,
+// requests are verified with the api key.
+func RegInUniSenderWebhook(
+	r chi.Router,
+	apiKey string,
+	srv deliverability.Service,
+	rootLogger *slog.Logger,
+) {
+	webhookSrv := uniSenderWebhook{
+		apiKey: apiKey,
+		srv:    srv,
+		logger: rootLogger.With(slog.String("context", "UniSenderWebhookHandler")),
+	}
+
+	r.Get("/emails/unisender/webhook", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Post("/emails/unisender/webhook", webhookSrv.Handle)
+}
+
+// RegInAdminDeliverabilityRoutes registers invalid emails listing, it is not
+// registered when admin token is not configured.
+func RegInAdminDeliverabilityRoutes(
+	r chi.Router,
+	adminToken string,
+	srv deliverability.Service,
+	rootLogger *slog.Logger,
+) {
+	if adminToken == "" {
+		return
+	}
+
+	logger := rootLogger.With(slog.String("context", "AdminDeliverabilityHandler"))
+	adminDeliverabilitySrv := adminDeliverability{srv: srv, logger: logger}
+
+	r.With(adminOnly(adminToken, logger)).Get("/admin/emails/invalid", adminDeliverabilitySrv.HandleInvalid)
+}
+
 func RegInTrialPayments(
 	r chi.Router,
 	routeHash string,
 	srv payments.Service,
+	referralSrv referrals.Service,
 	notifer notify.Service,
 
 ) {
-	trialSrv := paymentRegTrial{srv: srv, notifer: notifer}
+	trialSrv := paymentRegTrial{srv: srv, referralSrv: referralSrv, notifer: notifer}
 
 	r.Post("/payments/trial/"+routeHash, trialSrv.Handle)
 }
@@ -60,13 +165,15 @@ func RegInProdamusPayWebHook(
 	r chi.Router,
 	routeHash string,
 	srv payments.Service,
+	referralSrv referrals.Service,
 	notifer notify.Service,
 	logger *slog.Logger,
 ) {
 	prodamusSrv := prodamusPayNotification{
-		srv:     srv,
-		notifer: notifer,
-		logger:  logger.With(slog.String("context", "ProdamusPayNotificationHandler")),
+		srv:         srv,
+		referralSrv: referralSrv,
+		notifer:     notifer,
+		logger:      logger.With(slog.String("context", "ProdamusPayNotificationHandler")),
 	}
 
 	r.Post("/payments/prodamus/webhook/pay/"+routeHash, prodamusSrv.Handle)
@@ -76,13 +183,15 @@ func RegInCloudPaymentsPayHook(
 	r chi.Router,
 	routeHash string,
 	srv payments.Service,
+	referralSrv referrals.Service,
 	notifer notify.Service,
 	logger *slog.Logger,
 ) {
 	cpSrv := cloudPaymentsPayNotification{
-		srv:     srv,
-		notifer: notifer,
-		logger:  logger.With(slog.String("context", "CloudPaymentsPayNotificationHandler")),
+		srv:         srv,
+		referralSrv: referralSrv,
+		notifer:     notifer,
+		logger:      logger.With(slog.String("context", "CloudPaymentsPayNotificationHandler")),
 	}
 
 	r.Post("/payments/cloudpayment/webhook/pay/"+routeHash, cpSrv.Handle)
@@ -107,9 +216,10 @@ func RegInCloudPaymentsReccurentNotif(
 func RegInUserAccessRoute(
 	r chi.Router,
 	srv payments.Service,
+	auth *Authenticator,
 	logger *slog.Logger,
 ) {
-	userAccessSrv := userAccess{srv: srv, logger: logger.With(slog.String("context", "UserAccessHandler"))}
+	userAccessSrv := userAccess{srv: srv, auth: auth, logger: logger.With(slog.String("context", "UserAccessHandler"))}
 
 	r.Get("/payments/user/access", userAccessSrv.Handle)
 }
@@ -117,9 +227,113 @@ func RegInUserAccessRoute(
 func RegInUserInfoRoute(
 	r chi.Router,
 	srv payments.Service,
+	auth *Authenticator,
 	logger *slog.Logger,
 ) {
-	userInfoSrv := userInfo{srv: srv, logger: logger.With(slog.String("context", "UserInfoHandler"))}
+	userInfoSrv := userInfo{srv: srv, auth: auth, logger: logger.With(slog.String("context", "UserInfoHandler"))}
 
 	r.Get("/payments/user/info", userInfoSrv.Handle)
 }
+
+func RegInGiftRedeemRoute(
+	r chi.Router,
+	srv payments.Service,
+	logger *slog.Logger,
+) {
+	giftRedeemSrv := giftRedeem{srv: srv, logger: logger.With(slog.String("context", "GiftRedeemHandler"))}
+
+	r.Post("/payments/gift/redeem", giftRedeemSrv.Handle)
+}
+
+func RegInUserReferralRoute(
+	r chi.Router,
+	srv payments.Service,
+	referralSrv referrals.Service,
+	auth *Authenticator,
+	logger *slog.Logger,
+) {
+	userReferralSrv := userReferral{
+		srv:         srv,
+		referralSrv: referralSrv,
+		auth:        auth,
+		logger:      logger.With(slog.String("context", "UserReferralHandler")),
+	}
+
+	r.Get("/payments/user/referral", userReferralSrv.Handle)
+}
+
+// TelegramWebhookPath is route of the bot webhook, relative to the api prefix.
+func TelegramWebhookPath(bot string, pathSecret string) string {
+	return "/tgbot/webhook/" + bot + "/" + pathSecret
+}
+
+func RegInTelegramWebhook(r chi.Router, bot string, pathSecret string, handler http.Handler) {
+	r.Method(http.MethodPost, TelegramWebhookPath(bot, pathSecret), handler)
+}
+
+func RegInUserTelegramLinkRoute(
+	r chi.Router,
+	srv payments.Service,
+	membersSrv members.Service,
+	auth *Authenticator,
+	logger *slog.Logger,
+) {
+	userTelegramLinkSrv := userTelegramLink{
+		srv:        srv,
+		membersSrv: membersSrv,
+		auth:       auth,
+		logger:     logger.With(slog.String("context", "UserTelegramLinkHandler")),
+	}
+
+	r.Post("/payments/user/telegram/link", userTelegramLinkSrv.Handle)
+}
+
+func RegInUserSeatsRoutes(
+	r chi.Router,
+	srv payments.Service,
+	seatsSrv seats.Service,
+	auth *Authenticator,
+	logger *slog.Logger,
+) {
+	userSeatsSrv := userSeats{
+		srv:      srv,
+		seatsSrv: seatsSrv,
+		auth:     auth,
+		logger:   logger.With(slog.String("context", "UserSeatsHandler")),
+	}
+
+	r.Get("/payments/user/seats", userSeatsSrv.HandleList)
+	r.Post("/payments/user/seats", userSeatsSrv.HandleInvite)
+	r.Delete("/payments/user/seats", userSeatsSrv.HandleRemove)
+}
+
+func RegInAuthTokenRoutes(
+	r chi.Router,
+	srv auth.Service,
+	authenticator *Authenticator,
+	logger *slog.Logger,
+) {
+	authTokenSrv := authToken{
+		srv:    srv,
+		auth:   authenticator,
+		logger: logger.With(slog.String("context", "AuthTokenHandler")),
+	}
+
+	r.Post("/auth/token/refresh", authTokenSrv.HandleRefresh)
+}
+
+func RegInMagicLinkRoutes(
+	r chi.Router,
+	srv auth.Service,
+	geo geoip.Locator,
+	logger *slog.Logger,
+) {
+	magicLinkSrv := magicLink{
+		srv:    srv,
+		geo:    geo,
+		logger: logger.With(slog.String("context", "MagicLinkHandler")),
+	}
+
+	r.Post("/auth/magic-link", magicLinkSrv.HandleRequest)
+	r.Post("/auth/magic-link/verify", magicLinkSrv.HandleVerify)
+}
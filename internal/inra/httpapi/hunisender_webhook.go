@@ -0,0 +1,165 @@
+package httpapi
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"doctormakarhina/lumos/internal/core/deliverability"
+	"doctormakarhina/lumos/internal/core/domain"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const maxUniSenderWebhookBytes = 5 << 20
+
+// uniSenderEventTime is UTC time format of UniSender events
+const uniSenderEventTime = "2006-01-02 15:04:05"
+
+var uniSenderAuthRe = regexp.MustCompile(`"auth"\s*:\s*"([0-9a-fA-F]*)"`)
+
+type uniSenderWebhookBody struct {
+	EventsByUser []struct {
+		Events []uniSenderEvent `json:"events"`
+	} `json:"events_by_user"`
+}
+
+type uniSenderEvent struct {
+	EventName string `json:"event_name"`
+	EventTime string `json:"event_time"`
+	EventData struct {
+		Email  string `json:"email"`
+		Status string `json:"status"`
+	} `json:"event_data"`
+}
+
+type uniSenderWebhook struct {
+	apiKey string
+	srv    deliverability.Service
+	logger *slog.Logger
+}
+
+func (s *uniSenderWebhook) Handle(w http.ResponseWriter, r *http.Request) {
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUniSenderWebhookBytes))
+	if err != nil {
+		observeWebhook(webhookProviderUniSender, webhookResultInvalid)
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid request body"})
+		return
+	}
+	// notify_url may be configured to post the json in data form field
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(raw))
+		if err != nil {
+			observeWebhook(webhookProviderUniSender, webhookResultInvalid)
+			writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid form"})
+			return
+		}
+		raw = []byte(form.Get("data"))
+	}
+	// UniSender checks the url with an empty request when it is set up
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		observeWebhook(webhookProviderUniSender, webhookResultIgnored)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := verifyUniSenderAuth(raw, s.apiKey); err != nil {
+		s.logger.WarnContext(r.Context(), "UniSender webhook rejected", slog.String("err", err.Error()))
+		observeWebhook(webhookProviderUniSender, webhookResultUnauthorized)
+		writeJSON(w, s.logger, 401, ErrMsgRes{Message: "unauthorized"})
+		return
+	}
+
+	var body uniSenderWebhookBody
+	if err := json.Unmarshal(raw, &body); err != nil {
+		observeWebhook(webhookProviderUniSender, webhookResultInvalid)
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid request body"})
+		return
+	}
+
+	events := make([]domain.EmailEvent, 0)
+	for _, u := range body.EventsByUser {
+		for _, e := range u.Events {
+			event, ok := uniSenderToEmailEvent(e)
+			if ok {
+				events = append(events, event)
+			}
+		}
+	}
+
+	err = s.srv.HandleEvents(r.Context(), events)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "HandleEvents failed", slog.Int("events", len(events)), slog.String("err", err.Error()))
+		// non 200 response makes UniSender retry the notification
+		observeWebhook(webhookProviderUniSender, webhookResultError)
+		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		return
+	}
+
+	observeWebhook(webhookProviderUniSender, webhookResultOK)
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifyUniSenderAuth checks md5 of the raw body where auth value is replaced with the api key.
+func verifyUniSenderAuth(raw []byte, apiKey string) error {
+	m := uniSenderAuthRe.FindSubmatchIndex(raw)
+	if m == nil {
+		return errors.New("missing auth")
+	}
+
+	signed := make([]byte, 0, len(raw)+len(apiKey))
+	signed = append(signed, raw[:m[2]]...)
+	signed = append(signed, apiKey...)
+	signed = append(signed, raw[m[3]:]...)
+
+	sum := md5.Sum(signed)
+	expected := hex.EncodeToString(sum[:])
+	got := strings.ToLower(string(raw[m[2]:m[3]]))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(got)) != 1 {
+		return errors.New("auth mismatch")
+	}
+
+	return nil
+}
+
+// uniSenderToEmailEvent maps UniSender statuses, soft failures are skipped
+// since the provider retries them itself. Only feedback loop reports are complaints,
+// delivery to spam folder is a delivery and spam rejections are soft failures.
+func uniSenderToEmailEvent(e uniSenderEvent) (domain.EmailEvent, bool) {
+	at, err := time.ParseInLocation(uniSenderEventTime, e.EventTime, time.UTC)
+	if err != nil {
+		at = time.Now()
+	}
+	event := domain.EmailEvent{Email: e.EventData.Email, Reason: e.EventData.Status, At: at}
+
+	switch e.EventName {
+	case "email_status":
+		switch e.EventData.Status {
+		case "ok_delivered", "ok_read", "ok_link_visited", "ok_spam_folder":
+			event.Status = domain.EmailStatusOK
+		case "err_user_unknown", "err_user_inactive", "err_domain_inactive", "err_dest_invalid":
+			event.Status = domain.EmailStatusBounced
+		case "ok_fbl":
+			event.Status = domain.EmailStatusComplained
+		case "ok_unsubscribed", "err_unsubscribed":
+			event.Status = domain.EmailStatusUnsubscribed
+		default:
+			return event, false
+		}
+	case "subscribe_status":
+		if e.EventData.Status != "unsubscribed" {
+			return event, false
+		}
+		event.Status = domain.EmailStatusUnsubscribed
+	default:
+		return event, false
+	}
+
+	return event, true
+}
@@ -0,0 +1,70 @@
+//go:build unit
+// +build unit
+
+package httpapi
+
+import (
+	"crypto/md5"
+	"doctormakarhina/lumos/internal/core/domain"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func signUniSender(body string, apiKey string) string {
+	sum := md5.Sum([]byte(strings.Replace(body, `"auth":""`, `"auth":"`+apiKey+`"`, 1)))
+	return strings.Replace(body, `"auth":""`, `"auth":"`+hex.EncodeToString(sum[:])+`"`, 1)
+}
+
+func TestVerifyUniSenderAuth(t *testing.T) {
+	body := `{"auth":"","events_by_user":[{"login":"lumos","events":[{"event_name":"email_status","event_time":"2025-03-14 10:00:00","event_data":{"email":"a@example.com","status":"err_user_unknown"}}]}]}`
+	signed := signUniSender(body, "secret")
+
+	require.NoError(t, verifyUniSenderAuth([]byte(signed), "secret"))
+	require.Error(t, verifyUniSenderAuth([]byte(signed), "other"))
+	require.Error(t, verifyUniSenderAuth([]byte(strings.Replace(signed, "a@example.com", "b@example.com", 1)), "secret"))
+	require.Error(t, verifyUniSenderAuth([]byte(`{"events_by_user":[]}`), "secret"))
+}
+
+func TestUniSenderToEmailEvent(t *testing.T) {
+	event := func(name string, status string) uniSenderEvent {
+		e := uniSenderEvent{EventName: name, EventTime: "2025-03-14 10:00:00"}
+		e.EventData.Email = "a@example.com"
+		e.EventData.Status = status
+		return e
+	}
+
+	got, ok := uniSenderToEmailEvent(event("email_status", "err_user_unknown"))
+	require.True(t, ok)
+	require.Equal(t, domain.EmailEvent{
+		Email:  "a@example.com",
+		Status: domain.EmailStatusBounced,
+		Reason: "err_user_unknown",
+		At:     time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC),
+	}, got)
+
+	got, ok = uniSenderToEmailEvent(event("subscribe_status", "unsubscribed"))
+	require.True(t, ok)
+	require.Equal(t, domain.EmailStatusUnsubscribed, got.Status)
+
+	for status, want := range map[string]string{
+		"ok_spam_folder":    domain.EmailStatusOK,
+		"err_spam_folder":   "",
+		"err_spam_rejected": "",
+		"ok_fbl":            domain.EmailStatusComplained,
+	} {
+		got, ok = uniSenderToEmailEvent(event("email_status", status))
+		require.Equal(t, want != "", ok, status)
+		if ok {
+			require.Equal(t, want, got.Status, status)
+		}
+	}
+
+	_, ok = uniSenderToEmailEvent(event("email_status", "err_will_retry"))
+	require.False(t, ok)
+	_, ok = uniSenderToEmailEvent(event("subscribe_status", "active"))
+	require.False(t, ok)
+}
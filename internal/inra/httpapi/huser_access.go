@@ -8,19 +8,17 @@ import (
 
 type userAccess struct {
 	srv    payments.Service
+	auth   *Authenticator
 	logger *slog.Logger
 }
 
 func (s *userAccess) Handle(w http.ResponseWriter, r *http.Request) {
-	email := r.URL.Query().Get("email")
-	projectID := r.URL.Query().Get("project_id")
-
-	if email == "" || projectID == "" {
-		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "missing email or project_id in search params"})
+	id, ok := s.auth.identify(w, r)
+	if !ok {
 		return
 	}
 
-	allowed, err := s.srv.IsAccessAlowed(r.Context(), email, projectID)
+	allowed, err := s.srv.IsAccessAlowed(r.Context(), id.Email, id.ProjectID)
 	if err != nil {
 		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal error"})
 		return
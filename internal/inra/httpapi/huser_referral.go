@@ -0,0 +1,30 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/core/referrals"
+	"log/slog"
+	"net/http"
+)
+
+type userReferral struct {
+	srv         payments.Service
+	referralSrv referrals.Service
+	auth        *Authenticator
+	logger      *slog.Logger
+}
+
+func (s *userReferral) Handle(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.auth.identifyUser(w, r, s.srv)
+	if !ok {
+		return
+	}
+
+	code, err := s.referralSrv.Code(r.Context(), *user)
+	if err != nil {
+		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		return
+	}
+
+	writeJSON(w, s.logger, 200, UserReferralRes{Code: code})
+}
@@ -0,0 +1,118 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/payments"
+	"doctormakarhina/lumos/internal/core/seats"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// userSeats routes require bearer token even in legacy mode, otherwise anyone knowing
+// an owner email could invite themselves to the paid team or remove its members.
+type userSeats struct {
+	srv      payments.Service
+	seatsSrv seats.Service
+	auth     *Authenticator
+	logger   *slog.Logger
+}
+
+func (s *userSeats) HandleList(w http.ResponseWriter, r *http.Request) {
+	owner, ok := s.auth.identifyTokenUser(w, r, s.srv)
+	if !ok {
+		return
+	}
+
+	s.writeSeats(w, r, *owner)
+}
+
+func (s *userSeats) HandleInvite(w http.ResponseWriter, r *http.Request) {
+	owner, ok := s.auth.identifyTokenUser(w, r, s.srv)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid form"})
+		return
+	}
+
+	memberEmail := r.FormValue("member_email")
+	if memberEmail == "" {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "missing member_email"})
+		return
+	}
+
+	_, err := s.seatsSrv.Invite(r.Context(), *owner, memberEmail)
+	if err != nil {
+		switch {
+		case errors.Is(err, seats.ErrInvalidMember):
+			writeJSON(w, s.logger, 400, ErrMsgRes{Message: "invalid member email"})
+		case errors.Is(err, seats.ErrNoSeats), errors.Is(err, seats.ErrOwnerSubExpired):
+			writeJSON(w, s.logger, 403, ErrMsgRes{Message: "forbidden"})
+		case errors.Is(err, seats.ErrSeatLimitReached):
+			writeJSON(w, s.logger, 409, ErrMsgRes{Message: "seat limit reached"})
+		case errors.Is(err, seats.ErrAlreadyMember):
+			writeJSON(w, s.logger, 409, ErrMsgRes{Message: "already a team member"})
+		default:
+			writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		}
+		return
+	}
+
+	s.writeSeats(w, r, *owner)
+}
+
+func (s *userSeats) HandleRemove(w http.ResponseWriter, r *http.Request) {
+	owner, ok := s.auth.identifyTokenUser(w, r, s.srv)
+	if !ok {
+		return
+	}
+
+	memberEmail := r.URL.Query().Get("member_email")
+	if memberEmail == "" {
+		writeJSON(w, s.logger, 400, ErrMsgRes{Message: "missing member_email in search params"})
+		return
+	}
+
+	err := s.seatsSrv.Remove(r.Context(), *owner, memberEmail)
+	if err != nil {
+		if errors.Is(err, seats.ErrMemberNotFound) {
+			writeJSON(w, s.logger, 404, ErrMsgRes{Message: "not found"})
+			return
+		}
+
+		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		return
+	}
+
+	s.writeSeats(w, r, *owner)
+}
+
+func (s *userSeats) writeSeats(w http.ResponseWriter, r *http.Request, owner domain.User) {
+	members, err := s.seatsSrv.Members(r.Context(), owner)
+	if err != nil {
+		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		return
+	}
+
+	limit := s.seatsSrv.Limit(owner)
+	expired := owner.SubExpired(time.Now())
+
+	res := UserSeatsRes{
+		Limit:   limit,
+		Used:    len(members),
+		Members: make([]UserSeatRes, 0, len(members)),
+	}
+	for i, m := range members {
+		res.Members = append(res.Members, UserSeatRes{
+			Email:     m.MemberEmail,
+			InvitedAt: m.CreatedAt,
+			Active:    !expired && i < limit,
+		})
+	}
+
+	writeJSON(w, s.logger, 200, res)
+}
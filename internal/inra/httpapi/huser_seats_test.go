@@ -0,0 +1,40 @@
+//go:build unit
+// +build unit
+
+package httpapi
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserSeatsRequireBearerToken(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// services are nil, the handlers must reject the request before touching them
+	h := &userSeats{
+		auth:   NewAuthenticator(nil, true, logger),
+		logger: logger,
+	}
+
+	cases := []struct {
+		method string
+		target string
+		handle http.HandlerFunc
+	}{
+		{http.MethodGet, "/payments/user/seats?email=owner@example.com&project_id=p1", h.HandleList},
+		{http.MethodPost, "/payments/user/seats?email=owner@example.com&project_id=p1&member_email=me@example.com", h.HandleInvite},
+		{http.MethodDelete, "/payments/user/seats?email=owner@example.com&project_id=p1&member_email=member@example.com", h.HandleRemove},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		c.handle(rec, httptest.NewRequest(c.method, c.target, nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code, c.method)
+		require.JSONEq(t, `{"message":"missing bearer token"}`, rec.Body.String(), c.method)
+	}
+}
@@ -0,0 +1,38 @@
+package httpapi
+
+import (
+	"doctormakarhina/lumos/internal/core/members"
+	"doctormakarhina/lumos/internal/core/payments"
+	"log/slog"
+	"net/http"
+)
+
+type userTelegramLink struct {
+	srv        payments.Service
+	membersSrv members.Service
+	auth       *Authenticator
+	logger     *slog.Logger
+}
+
+// Handle issues one time code, user sends it to the member bot to link the chat.
+// Linking requires bearer token even in legacy mode, otherwise anyone knowing
+// an email could take over the chat link of the account.
+func (s *userTelegramLink) Handle(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.auth.identifyTokenUser(w, r, s.srv)
+	if !ok {
+		return
+	}
+
+	code, err := s.membersSrv.IssueCode(r.Context(), *user)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to issue telegram link code", slog.String("err", err.Error()), slog.String("user_id", user.ID))
+		writeJSON(w, s.logger, 500, ErrMsgRes{Message: "internal server error"})
+		return
+	}
+
+	writeJSON(w, s.logger, 200, UserTelegramLinkRes{
+		Code:      code.Code,
+		URL:       code.URL,
+		ExpiresAt: code.ExpiresAt,
+	})
+}
@@ -0,0 +1,30 @@
+//go:build unit
+// +build unit
+
+package httpapi
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserTelegramLinkRequiresBearerToken(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// services are nil, the handler must reject the request before touching them
+	h := &userTelegramLink{
+		auth:   NewAuthenticator(nil, true, logger),
+		logger: logger,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/user/telegram/link?email=victim@example.com&project_id=p1", nil)
+	rec := httptest.NewRecorder()
+	h.Handle(rec, req)
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.JSONEq(t, `{"message":"missing bearer token"}`, rec.Body.String())
+}
@@ -1,11 +1,18 @@
 package httpapi
 
 import (
+	"bufio"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 )
 
+const (
+	maxBatchBodyBytes = 1 << 20
+	maxBatchItems     = 100
+)
+
 func writeJSON(w http.ResponseWriter, logger *slog.Logger, status int, data any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
@@ -18,3 +25,45 @@ func writeJSON(w http.ResponseWriter, logger *slog.Logger, status int, data any)
 func writeJsonError(w http.ResponseWriter, logger *slog.Logger, status int, msg string) {
 	writeJSON(w, logger, status, ErrorRes{Error: msg})
 }
+
+// decodeBatch decodes request body with either a single object or an array of objects.
+func decodeBatch[T any](w http.ResponseWriter, r *http.Request) ([]T, error) {
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+
+	first, err := peekNonSpace(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if first != '[' {
+		var item T
+		if err := json.NewDecoder(body).Decode(&item); err != nil {
+			return nil, err
+		}
+		return []T{item}, nil
+	}
+
+	var items []T
+	if err := json.NewDecoder(body).Decode(&items); err != nil {
+		return nil, err
+	}
+	if len(items) > maxBatchItems {
+		return nil, fmt.Errorf("batch of %d items exceeds limit of %d", len(items), maxBatchItems)
+	}
+
+	return items, nil
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
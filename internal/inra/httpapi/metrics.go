@@ -0,0 +1,26 @@
+package httpapi
+
+import "doctormakarhina/lumos/internal/pkg/metrics"
+
+const (
+	webhookProviderProdamus               = "prodamus"
+	webhookProviderCloudPayments          = "cloudpayments"
+	webhookProviderCloudPaymentsRecurrent = "cloudpayments_recurrent"
+	webhookProviderUniSender              = "unisender"
+	webhookProviderTrial                  = "trial"
+)
+
+const (
+	webhookResultOK = "ok"
+	// webhookResultGift is a processed gift purchase
+	webhookResultGift = "gift"
+	// webhookResultIgnored is acknowledged notification which is not processed, e.g. failed payment
+	webhookResultIgnored      = "ignored"
+	webhookResultInvalid      = "invalid"
+	webhookResultUnauthorized = "unauthorized"
+	webhookResultError        = "error"
+)
+
+func observeWebhook(provider string, result string) {
+	metrics.WebhookResults.WithLabelValues(provider, result).Inc()
+}
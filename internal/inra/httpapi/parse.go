@@ -1,10 +1,35 @@
 package httpapi
 
 import (
+	"encoding/json"
 	"strconv"
 	"strings"
 )
 
+// isGiftPurchase reports whether payment is made for another person.
+func isGiftPurchase(payerEmail string, recipientEmail string) bool {
+	recipient := strings.ToLower(strings.TrimSpace(recipientEmail))
+	if recipient == "" {
+		return false
+	}
+
+	return recipient != strings.ToLower(strings.TrimSpace(payerEmail))
+}
+
+// parseCloudPaymentsData extracts custom payment data passed from the widget,
+// CloudPayments sends it as JSON string in the Data field.
+func parseCloudPaymentsData(raw string) cloudPaymentsData {
+	var data cloudPaymentsData
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return data
+	}
+
+	_ = json.Unmarshal([]byte(raw), &data)
+
+	return data
+}
+
 func parsePrice(p string) (float64, error) {
 	s := strings.TrimSpace(p)
 	if s == "" {
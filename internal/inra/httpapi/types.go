@@ -1,6 +1,9 @@
 package httpapi
 
-import "time"
+import (
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+)
 
 type ErrorRes struct {
 	Error string `json:"error"`
@@ -18,12 +21,20 @@ type SaveAuthTelemetryRequestBody struct {
 
 type SaveSearchTelemetryRequestBody struct {
 	Query string `json:"query"`
+	// ResultsCount is optional, older clients do not send it
+	ResultsCount *int `json:"results_count"`
 }
 
 type ProdamusPayNotificationRes struct {
 	Success bool `json:"success"`
 }
 
+type cloudPaymentsData struct {
+	GiftEmail    string `json:"giftEmail"`
+	ReferralCode string `json:"referralCode"`
+	ProjectID    string `json:"projectId"`
+}
+
 type CloudPaymentsNotificationRes struct {
 	Code int `json:"code"`
 }
@@ -41,6 +52,75 @@ type UserInfoRes struct {
 	SubscriptionsId string    `json:"sub_id"`
 }
 
+type GiftRedeemRes struct {
+	Email     string    `json:"email"`
+	Tariff    string    `json:"tariff"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type UserReferralRes struct {
+	Code string `json:"code"`
+}
+
+type UserTelegramLinkRes struct {
+	Code      string    `json:"code"`
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type UserSeatsRes struct {
+	Limit   int           `json:"limit"`
+	Used    int           `json:"used"`
+	Members []UserSeatRes `json:"members"`
+}
+
+type UserSeatRes struct {
+	Email     string    `json:"email"`
+	InvitedAt time.Time `json:"invited_at"`
+	Active    bool      `json:"active"`
+}
+
+type MagicLinkRequestBody struct {
+	Email     string `json:"email"`
+	ProjectID string `json:"project_id"`
+}
+
+// MagicLinkVerifyRequestBody carries the same fingerprint fields as SaveAuthTelemetryRequestBody,
+// they are recorded to auth logs on successful login.
+type MagicLinkVerifyRequestBody struct {
+	Token           string `json:"token"`
+	Fingerprint     string `json:"fingerprint"`
+	ConfidenceScore string `json:"confidenceScore"`
+}
+
+type AccessTokenRes struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type ErrMsgRes struct {
 	Message string `json:"message"`
 }
+
+type SearchQueriesRes struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Queries []domain.QueryStat `json:"queries"`
+}
+
+type TrendingQueryRes struct {
+	Query     string  `json:"query"`
+	Count     int     `json:"count"`
+	PrevCount int     `json:"prev_count"`
+	Growth    float64 `json:"growth"`
+}
+
+type TrendingQueriesRes struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Queries []TrendingQueryRes `json:"queries"`
+}
+
+type InvalidEmailsRes struct {
+	Emails []domain.EmailDeliverability `json:"emails"`
+}
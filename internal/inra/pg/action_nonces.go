@@ -0,0 +1,35 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ActionNonceRepo struct {
+	db *sqlx.DB
+}
+
+func NewActionNonceRepo(db *sqlx.DB) *ActionNonceRepo {
+	return &ActionNonceRepo{db: db}
+}
+
+// Use marks the nonce used, false means it was used before.
+func (r *ActionNonceRepo) Use(ctx context.Context, nonce string) (bool, error) {
+	const q = `
+  INSERT INTO lumos.tg_action_nonces (nonce)
+  VALUES ($1)
+  ON CONFLICT (nonce) DO NOTHING
+ `
+
+	res, err := r.db.ExecContext(ctx, q, nonce)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
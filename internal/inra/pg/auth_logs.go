@@ -0,0 +1,162 @@
+package pg
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuthLogRepo struct {
+	db *sqlx.DB
+}
+
+func NewAuthLogRepo(db *sqlx.DB) *AuthLogRepo {
+	return &AuthLogRepo{db: db}
+}
+
+func (r *AuthLogRepo) Save(ctx context.Context, log domain.AuthLog) error {
+	const q = `
+		INSERT INTO lumos.auth_logs
+		(login, ip, fingerprint, confidencescore, useragent, country, city)
+		VALUES
+		($1, $2, $3, $4, $5, $6, $7);
+    `
+
+	_, err := r.db.ExecContext(ctx, q, log.Login, log.IP, log.Fingerprint, log.ConfidenceScore, log.UserAgent, log.Country, log.City)
+
+	return err
+}
+
+// SaveBatch stores auth logs collected by the ingestion queue.
+func (r *AuthLogRepo) SaveBatch(ctx context.Context, logs []domain.AuthLog) error {
+	rows := make([][]any, 0, len(logs))
+	for _, l := range logs {
+		rows = append(rows, []any{l.Login, l.IP, l.Fingerprint, l.ConfidenceScore, l.UserAgent, l.Country, l.City, l.CreatedAt})
+	}
+
+	return copyFrom(
+		ctx,
+		r.db,
+		pgx.Identifier{"lumos", "auth_logs"},
+		[]string{"login", "ip", "fingerprint", "confidencescore", "useragent", "country", "city", "created_at"},
+		rows,
+	)
+}
+
+// LinkedLogins returns other logins which shared a device fingerprint or an ip
+// address with the given login since the provided time.
+func (r *AuthLogRepo) LinkedLogins(ctx context.Context, login string, since time.Time) ([]domain.LinkedLogin, error) {
+	const q = `
+  WITH own AS (
+    SELECT DISTINCT fingerprint, ip
+    FROM lumos.auth_logs
+    WHERE lower(trim(login)) = $1 AND created_at >= $2
+  ),
+  own_fingerprints AS (
+    SELECT fingerprint FROM own WHERE coalesce(fingerprint, '') <> ''
+  ),
+  own_ips AS (
+    SELECT ip FROM own WHERE coalesce(ip, '') NOT IN ('', 'unknown')
+  )
+  SELECT
+    lower(trim(l.login)) AS login,
+    COUNT(DISTINCT l.fingerprint) FILTER (WHERE l.fingerprint IN (SELECT fingerprint FROM own_fingerprints)) AS shared_fingerprints,
+    COUNT(DISTINCT l.ip) FILTER (WHERE l.ip IN (SELECT ip FROM own_ips)) AS shared_ips,
+    MAX(l.created_at) AS last_seen_at
+  FROM lumos.auth_logs l
+  WHERE l.created_at >= $2
+    AND lower(trim(l.login)) <> $1
+    AND (
+      l.fingerprint IN (SELECT fingerprint FROM own_fingerprints)
+      OR l.ip IN (SELECT ip FROM own_ips)
+    )
+  GROUP BY lower(trim(l.login))
+  ORDER BY shared_fingerprints DESC, shared_ips DESC, last_seen_at DESC
+  LIMIT 50
+ `
+
+	linked := make([]domain.LinkedLogin, 0)
+	err := r.db.SelectContext(ctx, &linked, q, login, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return linked, nil
+}
+
+// LoginsByFingerprint returns logins seen with the device fingerprint since the provided time.
+func (r *AuthLogRepo) LoginsByFingerprint(ctx context.Context, fingerprint string, since time.Time) ([]domain.LinkedLogin, error) {
+	const q = `
+  SELECT
+    lower(trim(login)) AS login,
+    1 AS shared_fingerprints,
+    0 AS shared_ips,
+    MAX(created_at) AS last_seen_at
+  FROM lumos.auth_logs
+  WHERE fingerprint = $1 AND created_at >= $2 AND coalesce(login, '') <> ''
+  GROUP BY lower(trim(login))
+  ORDER BY last_seen_at DESC
+  LIMIT 50
+ `
+
+	linked := make([]domain.LinkedLogin, 0)
+	err := r.db.SelectContext(ctx, &linked, q, fingerprint, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return linked, nil
+}
+
+// DeviceStats returns per login counts of distinct fingerprints, ips and user agents
+// since the provided time, only logins seen from more than one device are returned.
+func (r *AuthLogRepo) DeviceStats(ctx context.Context, since time.Time, limit int) ([]domain.DeviceStats, error) {
+	const q = `
+  SELECT
+    lower(trim(login)) AS login,
+    COUNT(DISTINCT fingerprint) FILTER (WHERE coalesce(fingerprint, '') <> '') AS fingerprints,
+    COUNT(DISTINCT ip) FILTER (WHERE coalesce(ip, '') NOT IN ('', 'unknown')) AS ips,
+    COUNT(DISTINCT useragent) FILTER (WHERE coalesce(useragent, '') <> '') AS user_agents,
+    MAX(created_at) AS last_seen_at
+  FROM lumos.auth_logs
+  WHERE created_at >= $1 AND coalesce(login, '') <> ''
+  GROUP BY lower(trim(login))
+  HAVING COUNT(DISTINCT fingerprint) > 1 OR COUNT(DISTINCT ip) > 1
+  ORDER BY fingerprints DESC, ips DESC, user_agents DESC
+  LIMIT $2
+ `
+
+	stats := make([]domain.DeviceStats, 0)
+	err := r.db.SelectContext(ctx, &stats, q, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// DeviceStatsByLogin returns counts of distinct devices the login was used from since the provided time.
+// It runs on every access check, login must be matched as lower(trim(login)) to use the login_norm index.
+func (r *AuthLogRepo) DeviceStatsByLogin(ctx context.Context, login string, since time.Time) (*domain.DeviceStats, error) {
+	const q = `
+  SELECT
+    $1 AS login,
+    COUNT(DISTINCT fingerprint) FILTER (WHERE coalesce(fingerprint, '') <> '') AS fingerprints,
+    COUNT(DISTINCT ip) FILTER (WHERE coalesce(ip, '') NOT IN ('', 'unknown')) AS ips,
+    COUNT(DISTINCT useragent) FILTER (WHERE coalesce(useragent, '') <> '') AS user_agents,
+    coalesce(MAX(created_at), 'epoch'::timestamptz) AS last_seen_at
+  FROM lumos.auth_logs
+  WHERE lower(trim(login)) = $1 AND created_at >= $2
+ `
+
+	var stats domain.DeviceStats
+	err := r.db.GetContext(ctx, &stats, q, login, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
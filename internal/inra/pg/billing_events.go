@@ -0,0 +1,131 @@
+package pg
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type BillingEventRepo struct {
+	db *sqlx.DB
+}
+
+func NewBillingEventRepo(db *sqlx.DB) *BillingEventRepo {
+	return &BillingEventRepo{db: db}
+}
+
+// Record saves the event, repeated events with the same transaction are ignored.
+func (r *BillingEventRepo) Record(ctx context.Context, e domain.BillingEvent) error {
+	const q = `
+  INSERT INTO lumos.billing_events (kind, email, provider, transaction_id, tariff, amount, currency, created_at)
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+  ON CONFLICT (kind, provider, transaction_id) WHERE transaction_id <> '' DO NOTHING
+ `
+
+	if e.Currency == "" {
+		e.Currency = domain.CurrencyRUB
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.ExecContext(ctx, q, e.Kind, e.Email, e.Provider, e.TransactionID, e.Tariff, e.Amount, e.Currency, e.CreatedAt)
+
+	return err
+}
+
+// Report computes business metrics of the range, active subscribers are counted at now.
+func (r *BillingEventRepo) Report(ctx context.Context, rng domain.DateRange, now time.Time) (*domain.BusinessReport, error) {
+	report := domain.BusinessReport{Range: rng}
+
+	const trialsQ = `
+  SELECT
+    count(*) AS trials,
+    count(*) FILTER (WHERE EXISTS (
+      SELECT 1 FROM lumos.billing_events p
+      WHERE p.email = t.email AND p.kind IN ($3, $4) AND p.created_at >= t.created_at
+    )) AS converted
+  FROM lumos.billing_events t
+  WHERE t.kind = $5 AND t.created_at >= $1 AND t.created_at < $2 AND t.provider <> $6
+ `
+	err := r.db.QueryRowxContext(ctx, trialsQ,
+		rng.From, rng.To,
+		domain.BillingEventPayment, domain.BillingEventRenewal, domain.BillingEventTrial,
+		domain.BillingProviderBackfill,
+	).Scan(&report.NewTrials, &report.ConvertedTrials)
+	if err != nil {
+		return nil, err
+	}
+
+	// new paid users are ones without earlier payments, backfilled payments of customers
+	// existing before the ledger are such earlier payments
+	const newPaidQ = `
+  SELECT e.tariff, count(DISTINCT e.email) AS count
+  FROM lumos.billing_events e
+  WHERE e.kind = $3 AND e.created_at >= $1 AND e.created_at < $2 AND e.provider <> $5
+    AND NOT EXISTS (
+      SELECT 1 FROM lumos.billing_events p
+      WHERE p.email = e.email AND p.kind IN ($3, $4) AND p.created_at < e.created_at
+    )
+  GROUP BY e.tariff
+  ORDER BY count DESC, e.tariff
+ `
+	report.NewPaid = make([]domain.TariffCount, 0)
+	err = r.db.SelectContext(ctx, &report.NewPaid, newPaidQ,
+		rng.From, rng.To,
+		domain.BillingEventPayment, domain.BillingEventRenewal,
+		domain.BillingProviderBackfill,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	const countsQ = `
+  SELECT
+    count(*) FILTER (WHERE kind = $3) AS renewals,
+    count(*) FILTER (WHERE kind = $4) AS cancellations
+  FROM lumos.billing_events
+  WHERE created_at >= $1 AND created_at < $2
+ `
+	err = r.db.QueryRowxContext(ctx, countsQ,
+		rng.From, rng.To,
+		domain.BillingEventRenewal, domain.BillingEventCancellation,
+	).Scan(&report.Renewals, &report.Cancellations)
+	if err != nil {
+		return nil, err
+	}
+
+	const revenueQ = `
+  SELECT provider, currency, sum(amount)::float8 AS amount, count(*) AS payments
+  FROM lumos.billing_events
+  WHERE kind IN ($3, $4, $5) AND created_at >= $1 AND created_at < $2 AND provider <> $6
+  GROUP BY provider, currency
+  ORDER BY amount DESC
+ `
+	report.Revenue = make([]domain.Revenue, 0)
+	err = r.db.SelectContext(ctx, &report.Revenue, revenueQ,
+		rng.From, rng.To,
+		domain.BillingEventPayment, domain.BillingEventRenewal, domain.BillingEventGift,
+		domain.BillingProviderBackfill,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	const activeQ = `
+  SELECT
+    count(*) FILTER (WHERE tariff NOT IN ($1, $2) AND expires_at > $3) AS subscribers,
+    count(*) FILTER (WHERE subscription_status = $4) AS autopay
+  FROM lumos.users
+ `
+	err = r.db.QueryRowxContext(ctx, activeQ,
+		domain.UserTariffTrial, domain.UserTariffUnlimited, now, domain.UserSubStatusActive,
+	).Scan(&report.ActiveSubscribers, &report.ActiveAutopay)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
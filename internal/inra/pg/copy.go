@@ -0,0 +1,29 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// copyFrom bulk inserts rows with COPY protocol using the underlying pgx connection.
+func copyFrom(ctx context.Context, db *sqlx.DB, table pgx.Identifier, columns []string, rows [][]any) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+
+		_, err := stdConn.Conn().CopyFrom(ctx, table, columns, pgx.CopyFromRows(rows))
+		return err
+	})
+}
@@ -0,0 +1,69 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type EmailDeliverabilityRepo struct {
+	db *sqlx.DB
+}
+
+func NewEmailDeliverabilityRepo(db *sqlx.DB) *EmailDeliverabilityRepo {
+	return &EmailDeliverabilityRepo{db: db}
+}
+
+func (r *EmailDeliverabilityRepo) ByEmail(ctx context.Context, email string) (*domain.EmailDeliverability, error) {
+	const q = `
+  SELECT email, status, reason, updated_at
+  FROM lumos.email_deliverability
+  WHERE email = $1
+ `
+
+	var d domain.EmailDeliverability
+	err := r.db.GetContext(ctx, &d, q, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (r *EmailDeliverabilityRepo) Save(ctx context.Context, d domain.EmailDeliverability) error {
+	const q = `
+  INSERT INTO lumos.email_deliverability (email, status, reason, updated_at)
+  VALUES ($1, $2, $3, $4)
+  ON CONFLICT (email) DO UPDATE
+  SET status = EXCLUDED.status, reason = EXCLUDED.reason, updated_at = EXCLUDED.updated_at
+ `
+
+	_, err := r.db.ExecContext(ctx, q, d.Email, d.Status, d.Reason, d.UpdatedAt)
+
+	return err
+}
+
+// Suppressed returns addresses lifecycle emails are not sent to, latest first.
+func (r *EmailDeliverabilityRepo) Suppressed(ctx context.Context, limit int) ([]domain.EmailDeliverability, error) {
+	const q = `
+  SELECT email, status, reason, updated_at
+  FROM lumos.email_deliverability
+  WHERE status IN ($1, $2)
+  ORDER BY updated_at DESC
+  LIMIT $3
+ `
+
+	res := make([]domain.EmailDeliverability, 0)
+	err := r.db.SelectContext(ctx, &res, q, domain.EmailStatusBounced, domain.EmailStatusComplained, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,87 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const entitlementColumns = `user_id, project_id, tariff, expires_at, subscription_id, created_at, updated_at`
+
+type EntitlementRepo struct {
+	db *sqlx.DB
+}
+
+func NewEntitlementRepo(db *sqlx.DB) *EntitlementRepo {
+	return &EntitlementRepo{db: db}
+}
+
+func (r *EntitlementRepo) ByUserAndProject(ctx context.Context, userID string, projectID string) (*domain.Entitlement, error) {
+	const q = `
+  SELECT ` + entitlementColumns + `
+  FROM lumos.entitlements
+  WHERE user_id = $1 AND project_id = $2
+ `
+
+	var ent domain.Entitlement
+	err := r.db.GetContext(ctx, &ent, q, userID, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &ent, nil
+}
+
+func (r *EntitlementRepo) BySubscriptionID(ctx context.Context, subscriptionID string) (*domain.Entitlement, error) {
+	const q = `
+  SELECT ` + entitlementColumns + `
+  FROM lumos.entitlements
+  WHERE subscription_id = $1 AND subscription_id <> ''
+  LIMIT 1
+ `
+
+	var ent domain.Entitlement
+	err := r.db.GetContext(ctx, &ent, q, subscriptionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &ent, nil
+}
+
+// Extend adds months to the project access counting from the current expiration date
+// if it is in the future, otherwise from now.
+func (r *EntitlementRepo) Extend(
+	ctx context.Context,
+	userID string,
+	projectID string,
+	tariff string,
+	months int,
+	subscriptionID string,
+) (*domain.Entitlement, error) {
+	const q = `
+  INSERT INTO lumos.entitlements (user_id, project_id, tariff, expires_at, subscription_id)
+  VALUES ($1, $2, $3, NOW() + make_interval(months => $4), $5)
+  ON CONFLICT (user_id, project_id) DO UPDATE
+  SET tariff = EXCLUDED.tariff,
+      expires_at = GREATEST(lumos.entitlements.expires_at, NOW()) + make_interval(months => $4),
+      subscription_id = EXCLUDED.subscription_id
+  RETURNING ` + entitlementColumns
+
+	var ent domain.Entitlement
+	err := r.db.GetContext(ctx, &ent, q, userID, projectID, tariff, months, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ent, nil
+}
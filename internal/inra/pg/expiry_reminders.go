@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ExpiryReminderRepo struct {
+	db *sqlx.DB
+}
+
+func NewExpiryReminderRepo(db *sqlx.DB) *ExpiryReminderRepo {
+	return &ExpiryReminderRepo{db: db}
+}
+
+// MarkSent records reminder about the expiration, returns false if it was
+// already recorded for the channel.
+func (r *ExpiryReminderRepo) MarkSent(ctx context.Context, userID string, expiresAt time.Time, channel string) (bool, error) {
+	const q = `
+  INSERT INTO lumos.expiry_reminders (user_id, expires_at, channel)
+  VALUES ($1, $2, $3)
+  ON CONFLICT DO NOTHING
+ `
+
+	res, err := r.db.ExecContext(ctx, q, userID, expiresAt, channel)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// UnmarkSent reverts MarkSent when the reminder delivery has failed.
+func (r *ExpiryReminderRepo) UnmarkSent(ctx context.Context, userID string, expiresAt time.Time, channel string) error {
+	const q = `
+  DELETE FROM lumos.expiry_reminders
+  WHERE user_id = $1 AND expires_at = $2 AND channel = $3
+ `
+
+	_, err := r.db.ExecContext(ctx, q, userID, expiresAt, channel)
+
+	return err
+}
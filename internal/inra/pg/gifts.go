@@ -0,0 +1,122 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const giftColumns = `id, code, payer_email, recipient_email, tariff, price, provider, transaction_id, created_at, redeemed_at, redeemed_by`
+
+type GiftRepo struct {
+	db *sqlx.DB
+}
+
+func NewGiftRepo(db *sqlx.DB) *GiftRepo {
+	return &GiftRepo{db: db}
+}
+
+func (r *GiftRepo) Create(ctx context.Context, gift domain.Gift) (*domain.Gift, error) {
+	const q = `
+  INSERT INTO lumos.gifts (id, code, payer_email, recipient_email, tariff, price, provider, transaction_id)
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+  RETURNING ` + giftColumns
+
+	var created domain.Gift
+	err := r.db.GetContext(
+		ctx,
+		&created,
+		q,
+		gift.ID,
+		gift.Code,
+		gift.PayerEmail,
+		gift.RecipientEmail,
+		gift.Tariff,
+		gift.Price,
+		gift.Provider,
+		gift.TransactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *GiftRepo) ByTransactionID(ctx context.Context, provider string, transactionID string) (*domain.Gift, error) {
+	const q = `
+  SELECT ` + giftColumns + `
+  FROM lumos.gifts
+  WHERE provider = $1 AND transaction_id = $2
+  LIMIT 1
+ `
+
+	var gift domain.Gift
+	err := r.db.GetContext(ctx, &gift, q, provider, transactionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &gift, nil
+}
+
+func (r *GiftRepo) ByCode(ctx context.Context, code string) (*domain.Gift, error) {
+	const q = `
+  SELECT ` + giftColumns + `
+  FROM lumos.gifts
+  WHERE code = $1
+  LIMIT 1
+ `
+
+	var gift domain.Gift
+	err := r.db.GetContext(ctx, &gift, q, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &gift, nil
+}
+
+// MarkRedeemed atomically marks gift as redeemed, returns nil if the gift
+// does not exist or was already redeemed.
+func (r *GiftRepo) MarkRedeemed(ctx context.Context, code string, redeemedBy string) (*domain.Gift, error) {
+	const q = `
+  UPDATE lumos.gifts
+  SET redeemed_at = NOW(), redeemed_by = $1
+  WHERE code = $2 AND redeemed_at IS NULL
+  RETURNING ` + giftColumns
+
+	var gift domain.Gift
+	err := r.db.GetContext(ctx, &gift, q, redeemedBy, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &gift, nil
+}
+
+// UnmarkRedeemed reverts MarkRedeemed, used when the subscription update
+// after redemption has failed.
+func (r *GiftRepo) UnmarkRedeemed(ctx context.Context, code string) error {
+	const q = `
+  UPDATE lumos.gifts
+  SET redeemed_at = NULL, redeemed_by = ''
+  WHERE code = $1
+ `
+
+	_, err := r.db.ExecContext(ctx, q, code)
+
+	return err
+}
@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"context"
+	"database/sql/driver"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const leaderCheckInterval = 30 * time.Second
+
+// Leader runs singleton jobs on one replica at a time, leadership is a session
+// advisory lock held on a dedicated connection, so it is released when the replica dies.
+type Leader struct {
+	db     *sqlx.DB
+	logger *slog.Logger
+}
+
+func NewLeader(db *sqlx.DB, rootLogger *slog.Logger) *Leader {
+	return &Leader{
+		db:     db,
+		logger: rootLogger.With(slog.String("context", "Leader")),
+	}
+}
+
+// Run calls fn while the replica holds the lock of the name and retries to take
+// the lock otherwise, fn ctx is cancelled when the lock connection is lost.
+func (r *Leader) Run(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	for {
+		err := r.lead(ctx, name, fn)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error("leader job failed", slog.String("job", name), slog.String("err", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(leaderCheckInterval):
+		}
+	}
+}
+
+func (r *Leader) lead(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	const lockQ = `SELECT pg_try_advisory_lock(hashtext($1))`
+	const unlockQ = `SELECT pg_advisory_unlock(hashtext($1))`
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, lockQ, "lumos."+name).Scan(&locked)
+	if err != nil || !locked {
+		return err
+	}
+	r.logger.Info("leadership acquired", slog.String("job", name))
+
+	defer func() {
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, unlockQ, "lumos."+name); err != nil {
+			// the lock lives as long as the session, so the connection must not return to the pool
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(jobCtx)
+	}()
+
+	ticker := time.NewTicker(leaderCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			if err := conn.PingContext(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Warn("leadership lost", slog.String("job", name), slog.String("err", err.Error()))
+				cancel()
+				<-done
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type MagicLinkRepo struct {
+	db *sqlx.DB
+}
+
+func NewMagicLinkRepo(db *sqlx.DB) *MagicLinkRepo {
+	return &MagicLinkRepo{db: db}
+}
+
+func (r *MagicLinkRepo) Create(ctx context.Context, link domain.MagicLink) error {
+	const q = `
+  INSERT INTO lumos.magic_links (id, token_hash, email, project_id, ip, expires_at)
+  VALUES ($1, $2, $3, $4, $5, $6)
+ `
+
+	_, err := r.db.ExecContext(ctx, q, link.ID, link.TokenHash, link.Email, link.ProjectID, link.IP, link.ExpiresAt)
+
+	return err
+}
+
+func (r *MagicLinkRepo) CountByEmailSince(ctx context.Context, email string, since time.Time) (int, error) {
+	const q = `SELECT COUNT(*) FROM lumos.magic_links WHERE email = $1 AND created_at >= $2`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, q, email, since)
+
+	return count, err
+}
+
+func (r *MagicLinkRepo) CountByIPSince(ctx context.Context, ip string, since time.Time) (int, error) {
+	const q = `SELECT COUNT(*) FROM lumos.magic_links WHERE ip = $1 AND created_at >= $2`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, q, ip, since)
+
+	return count, err
+}
+
+// Consume atomically marks not expired link as used, returns nil if the link
+// does not exist, has expired or was already used.
+func (r *MagicLinkRepo) Consume(ctx context.Context, tokenHash string) (*domain.MagicLink, error) {
+	const q = `
+  UPDATE lumos.magic_links
+  SET used_at = NOW()
+  WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+  RETURNING id, token_hash, email, project_id, ip, created_at, expires_at, used_at
+ `
+
+	var link domain.MagicLink
+	err := r.db.GetContext(ctx, &link, q, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &link, nil
+}
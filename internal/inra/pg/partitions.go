@@ -0,0 +1,147 @@
+package pg
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// rollups aggregate partition rows into daily tables by UTC day before the partition is dropped,
+// event counts are added to the existing ones since rows of a day may come from the default partition
+// or an earlier rollup, distinct counts can not be added and keep the larger value,
+// the rollup and the drop share a transaction so a failed drop can be retried.
+var rollups = map[string]string{
+	"auth_logs": `
+  INSERT INTO lumos.auth_logs_daily (day, login, events, fingerprints, ips)
+  SELECT
+    (created_at AT TIME ZONE 'UTC')::date,
+    lower(trim(login)),
+    COUNT(*),
+    COUNT(DISTINCT fingerprint) FILTER (WHERE coalesce(fingerprint, '') <> ''),
+    COUNT(DISTINCT ip) FILTER (WHERE coalesce(ip, '') NOT IN ('', 'unknown'))
+  FROM lumos.%s
+  WHERE coalesce(login, '') <> ''
+  GROUP BY 1, 2
+  ON CONFLICT (day, login) DO UPDATE
+  SET
+    events = auth_logs_daily.events + EXCLUDED.events,
+    fingerprints = GREATEST(auth_logs_daily.fingerprints, EXCLUDED.fingerprints),
+    ips = GREATEST(auth_logs_daily.ips, EXCLUDED.ips)
+ `,
+	"search_queries": `
+  INSERT INTO lumos.search_queries_daily (day, query_norm, searches, zero_results)
+  SELECT
+    (created_at AT TIME ZONE 'UTC')::date,
+    query_norm,
+    COUNT(*),
+    COUNT(*) FILTER (WHERE results_count = 0)
+  FROM lumos.%s
+  WHERE coalesce(query_norm, '') <> ''
+  GROUP BY 1, 2
+  ON CONFLICT (day, query_norm) DO UPDATE
+  SET
+    searches = search_queries_daily.searches + EXCLUDED.searches,
+    zero_results = search_queries_daily.zero_results + EXCLUDED.zero_results
+ `,
+}
+
+type PartitionRepo struct {
+	db *sqlx.DB
+}
+
+func NewPartitionRepo(db *sqlx.DB) *PartitionRepo {
+	return &PartitionRepo{db: db}
+}
+
+// EnsureMonthly creates missing monthly partitions of the table for months in [from, to].
+func (r *PartitionRepo) EnsureMonthly(ctx context.Context, table string, from time.Time, to time.Time) error {
+	if _, ok := rollups[table]; !ok {
+		return fmt.Errorf("table %s is not partitioned", table)
+	}
+
+	const q = `SELECT lumos.create_monthly_partitions($1, $2::date, $3::date)`
+
+	_, err := r.db.ExecContext(ctx, q, table, from.Format(time.DateOnly), to.Format(time.DateOnly))
+
+	return err
+}
+
+// Monthly returns partitions of the table ordered by month, partition bounds are UTC.
+func (r *PartitionRepo) Monthly(ctx context.Context, table string) ([]domain.Partition, error) {
+	const q = `
+  SELECT c.relname
+  FROM pg_inherits i
+  JOIN pg_class c ON c.oid = i.inhrelid
+  JOIN pg_class p ON p.oid = i.inhparent
+  JOIN pg_namespace n ON n.oid = p.relnamespace
+  WHERE n.nspname = 'lumos' AND p.relname = $1
+  ORDER BY c.relname
+ `
+
+	names := make([]string, 0)
+	err := r.db.SelectContext(ctx, &names, q, table)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]domain.Partition, 0, len(names))
+	for _, name := range names {
+		month, ok := strings.CutPrefix(name, table+"_p")
+		if !ok {
+			continue
+		}
+		from, err := time.ParseInLocation("2006_01", month, time.UTC)
+		if err != nil {
+			continue
+		}
+
+		partitions = append(partitions, domain.Partition{
+			Table: table,
+			Name:  name,
+			From:  from,
+			To:    from.AddDate(0, 1, 0),
+		})
+	}
+
+	return partitions, nil
+}
+
+// RollupAndDrop aggregates partition rows into the daily rollup table and drops
+// the partition in a single transaction.
+func (r *PartitionRepo) RollupAndDrop(ctx context.Context, p domain.Partition) error {
+	rollup, ok := rollups[p.Table]
+	if !ok {
+		return fmt.Errorf("table %s is not partitioned", p.Table)
+	}
+	if !strings.HasPrefix(p.Name, p.Table+"_p") {
+		return fmt.Errorf("partition %s does not belong to %s", p.Name, p.Table)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ident := quoteIdent(p.Name)
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(rollup, ident))
+	if err != nil {
+		return fmt.Errorf("failed to rollup %s: %w", p.Name, err)
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE lumos.%s`, ident))
+	if err != nil {
+		return fmt.Errorf("failed to drop %s: %w", p.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,181 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const referralColumns = `id, referrer_id, referee_email, source, status, reason, reward_days, created_at, rewarded_at`
+
+type ReferralRepo struct {
+	db *sqlx.DB
+}
+
+func NewReferralRepo(db *sqlx.DB) *ReferralRepo {
+	return &ReferralRepo{db: db}
+}
+
+func (r *ReferralRepo) CodeByUserID(ctx context.Context, userID string) (string, error) {
+	const q = `SELECT code FROM lumos.referral_codes WHERE user_id = $1`
+
+	var code string
+	err := r.db.GetContext(ctx, &code, q, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return code, nil
+}
+
+// CreateCode stores referral code for the user, if the user already has one
+// (concurrent request), the existing code is returned.
+func (r *ReferralRepo) CreateCode(ctx context.Context, userID string, code string) (string, error) {
+	const q = `
+  INSERT INTO lumos.referral_codes (user_id, code)
+  VALUES ($1, $2)
+  ON CONFLICT (user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+  RETURNING code
+ `
+
+	var stored string
+	err := r.db.GetContext(ctx, &stored, q, userID, code)
+	if err != nil {
+		return "", err
+	}
+
+	return stored, nil
+}
+
+func (r *ReferralRepo) UserIDByCode(ctx context.Context, code string) (string, error) {
+	const q = `SELECT user_id FROM lumos.referral_codes WHERE code = $1`
+
+	var userID string
+	err := r.db.GetContext(ctx, &userID, q, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// Create stores referral attribution, returns false if the referee is already
+// attributed to somebody.
+func (r *ReferralRepo) Create(ctx context.Context, referral domain.Referral) (bool, error) {
+	const q = `
+  INSERT INTO lumos.referrals (id, referrer_id, referee_email, source, status, reason)
+  VALUES ($1, $2, $3, $4, $5, $6)
+  ON CONFLICT (referee_email) DO NOTHING
+ `
+
+	res, err := r.db.ExecContext(
+		ctx,
+		q,
+		referral.ID,
+		referral.ReferrerID,
+		referral.RefereeEmail,
+		referral.Source,
+		referral.Status,
+		referral.Reason,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *ReferralRepo) PendingByRefereeEmail(ctx context.Context, email string) (*domain.Referral, error) {
+	const q = `
+  SELECT ` + referralColumns + `
+  FROM lumos.referrals
+  WHERE referee_email = $1 AND status = $2
+  LIMIT 1
+ `
+
+	var referral domain.Referral
+	err := r.db.GetContext(ctx, &referral, q, email, domain.ReferralStatusPending)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &referral, nil
+}
+
+func (r *ReferralRepo) CountRewardedSince(ctx context.Context, referrerID string, since time.Time) (int, error) {
+	const q = `
+  SELECT COUNT(*)
+  FROM lumos.referrals
+  WHERE referrer_id = $1 AND status = $2 AND rewarded_at >= $3
+ `
+
+	var count int
+	err := r.db.GetContext(ctx, &count, q, referrerID, domain.ReferralStatusRewarded, since)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Reject moves pending referral to rejected status, returns false if the
+// referral is not pending anymore.
+func (r *ReferralRepo) Reject(ctx context.Context, id string, reason string) (bool, error) {
+	const q = `
+  UPDATE lumos.referrals
+  SET status = $1, reason = $2
+  WHERE id = $3 AND status = $4
+ `
+
+	res, err := r.db.ExecContext(ctx, q, domain.ReferralStatusRejected, reason, id, domain.ReferralStatusPending)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// MarkRewarded moves pending referral to rewarded status, returns false if the
+// referral is not pending anymore (e.g. duplicate webhook).
+func (r *ReferralRepo) MarkRewarded(ctx context.Context, id string, rewardDays int, reason string) (bool, error) {
+	const q = `
+  UPDATE lumos.referrals
+  SET status = $1, reward_days = $2, reason = $3, rewarded_at = NOW()
+  WHERE id = $4 AND status = $5
+ `
+
+	res, err := r.db.ExecContext(ctx, q, domain.ReferralStatusRewarded, rewardDays, reason, id, domain.ReferralStatusPending)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,107 @@
+package pg
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jmoiron/sqlx"
+)
+
+type SearchQueryRepo struct {
+	db *sqlx.DB
+}
+
+func NewSearchQueryRepo(db *sqlx.DB) *SearchQueryRepo {
+	return &SearchQueryRepo{db: db}
+}
+
+// SaveBatch stores search queries collected by the ingestion queue.
+func (r *SearchQueryRepo) SaveBatch(ctx context.Context, queries []domain.SearchQuery) error {
+	rows := make([][]any, 0, len(queries))
+	for _, q := range queries {
+		rows = append(rows, []any{q.Query, q.ResultsCount, q.CreatedAt})
+	}
+
+	return copyFrom(
+		ctx,
+		r.db,
+		pgx.Identifier{"lumos", "search_queries"},
+		[]string{"query", "results_count", "created_at"},
+		rows,
+	)
+}
+
+func (r *SearchQueryRepo) Top(ctx context.Context, rng domain.DateRange, limit int) ([]domain.QueryStat, error) {
+	const q = `
+  SELECT query_norm AS query, COUNT(*) AS count
+  FROM lumos.search_queries
+  WHERE created_at >= $1 AND created_at < $2 AND coalesce(query_norm, '') <> ''
+  GROUP BY query_norm
+  ORDER BY count DESC, query_norm
+  LIMIT $3
+ `
+
+	stats := make([]domain.QueryStat, 0)
+	err := r.db.SelectContext(ctx, &stats, q, rng.From, rng.To, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ZeroResults returns queries reported by the client with zero results,
+// queries without reported results count are skipped.
+func (r *SearchQueryRepo) ZeroResults(ctx context.Context, rng domain.DateRange, limit int) ([]domain.QueryStat, error) {
+	const q = `
+  SELECT query_norm AS query, COUNT(*) AS count
+  FROM lumos.search_queries
+  WHERE created_at >= $1 AND created_at < $2 AND results_count = 0 AND coalesce(query_norm, '') <> ''
+  GROUP BY query_norm
+  ORDER BY count DESC, query_norm
+  LIMIT $3
+ `
+
+	stats := make([]domain.QueryStat, 0)
+	err := r.db.SelectContext(ctx, &stats, q, rng.From, rng.To, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Trending returns queries searched at least minCount times in the range ordered
+// by growth against the previous range of the same length.
+func (r *SearchQueryRepo) Trending(ctx context.Context, rng domain.DateRange, minCount int, limit int) ([]domain.TrendingQuery, error) {
+	const q = `
+  WITH cur AS (
+    SELECT query_norm, COUNT(*) AS cnt
+    FROM lumos.search_queries
+    WHERE created_at >= $1 AND created_at < $2 AND coalesce(query_norm, '') <> ''
+    GROUP BY query_norm
+  ),
+  prev AS (
+    SELECT query_norm, COUNT(*) AS cnt
+    FROM lumos.search_queries
+    WHERE created_at >= $3 AND created_at < $1 AND coalesce(query_norm, '') <> ''
+    GROUP BY query_norm
+  )
+  SELECT cur.query_norm AS query, cur.cnt AS count, coalesce(prev.cnt, 0) AS prev_count
+  FROM cur
+  LEFT JOIN prev ON prev.query_norm = cur.query_norm
+  WHERE cur.cnt >= $4 AND cur.cnt > coalesce(prev.cnt, 0)
+  ORDER BY cur.cnt::float / (coalesce(prev.cnt, 0) + 1) DESC, cur.cnt DESC
+  LIMIT $5
+ `
+
+	prev := rng.Previous()
+	trending := make([]domain.TrendingQuery, 0)
+	err := r.db.SelectContext(ctx, &trending, q, rng.From, rng.To, prev.From, minCount, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return trending, nil
+}
@@ -0,0 +1,156 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SeatRepo struct {
+	db *sqlx.DB
+}
+
+func NewSeatRepo(db *sqlx.DB) *SeatRepo {
+	return &SeatRepo{db: db}
+}
+
+func (r *SeatRepo) Members(ctx context.Context, ownerID string) ([]domain.Seat, error) {
+	const q = `
+  SELECT id, owner_id, member_email, created_at
+  FROM lumos.seats
+  WHERE owner_id = $1
+  ORDER BY created_at ASC
+ `
+
+	seats := make([]domain.Seat, 0)
+	err := r.db.SelectContext(ctx, &seats, q, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return seats, nil
+}
+
+func (r *SeatRepo) ByMemberEmail(ctx context.Context, email string) (*domain.Seat, error) {
+	const q = `
+  SELECT id, owner_id, member_email, created_at
+  FROM lumos.seats
+  WHERE member_email = $1
+  LIMIT 1
+ `
+
+	var seat domain.Seat
+	err := r.db.GetContext(ctx, &seat, q, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &seat, nil
+}
+
+// MemberPosition returns 1-based position of the member among the owner seats
+// ordered by invite time, 0 if the email is not a member.
+func (r *SeatRepo) MemberPosition(ctx context.Context, email string) (*domain.Seat, int, error) {
+	const q = `
+  SELECT id, owner_id, member_email, created_at, position
+  FROM (
+    SELECT s.*, ROW_NUMBER() OVER (PARTITION BY s.owner_id ORDER BY s.created_at ASC) AS position
+    FROM lumos.seats s
+    WHERE s.owner_id = (SELECT owner_id FROM lumos.seats WHERE member_email = $1)
+  ) ranked
+  WHERE member_email = $1
+ `
+
+	var row struct {
+		domain.Seat
+		Position int `db:"position"`
+	}
+	err := r.db.GetContext(ctx, &row, q, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+
+	return &row.Seat, row.Position, nil
+}
+
+// Add inserts member seat if the owner has less than limit seats taken,
+// returns false if the limit is reached or the email is already a member.
+func (r *SeatRepo) Add(ctx context.Context, seat domain.Seat, limit int) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Concurrency safety:
+	// serialize seat changes per owner within this transaction so the limit can not be exceeded.
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "seats:"+seat.OwnerID); err != nil {
+		return false, err
+	}
+
+	const q = `
+  INSERT INTO lumos.seats (id, owner_id, member_email)
+  SELECT $1, $2, $3
+  WHERE (SELECT COUNT(*) FROM lumos.seats WHERE owner_id = $2) < $4
+  ON CONFLICT (member_email) DO NOTHING
+ `
+
+	res, err := tx.ExecContext(ctx, q, seat.ID, seat.OwnerID, seat.MemberEmail, limit)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *SeatRepo) Remove(ctx context.Context, ownerID string, memberEmail string) (bool, error) {
+	const q = `DELETE FROM lumos.seats WHERE owner_id = $1 AND member_email = $2`
+
+	res, err := r.db.ExecContext(ctx, q, ownerID, memberEmail)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *SeatRepo) Usage(ctx context.Context) ([]domain.SeatUsage, error) {
+	const q = `
+  SELECT u.id AS owner_id, u.email AS owner_email, u.tariff, u.expires_at, COUNT(s.id) AS used
+  FROM lumos.seats s
+  JOIN lumos.users u ON u.id = s.owner_id
+  GROUP BY u.id, u.email, u.tariff, u.expires_at
+  ORDER BY used DESC, u.email ASC
+ `
+
+	usage := make([]domain.SeatUsage, 0)
+	err := r.db.SelectContext(ctx, &usage, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,50 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SyncStateRepo struct {
+	db *sqlx.DB
+}
+
+func NewSyncStateRepo(db *sqlx.DB) *SyncStateRepo {
+	return &SyncStateRepo{db: db}
+}
+
+// SyncedAt returns zero time when the sync has never completed.
+func (r *SyncStateRepo) SyncedAt(ctx context.Context, name string) (time.Time, error) {
+	const q = `
+  SELECT synced_at
+  FROM lumos.sync_state
+  WHERE name = $1
+ `
+
+	var syncedAt time.Time
+	err := r.db.GetContext(ctx, &syncedAt, q, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	return syncedAt, nil
+}
+
+func (r *SyncStateRepo) SetSyncedAt(ctx context.Context, name string, syncedAt time.Time) error {
+	const q = `
+  INSERT INTO lumos.sync_state (name, synced_at)
+  VALUES ($1, $2)
+  ON CONFLICT (name) DO UPDATE SET synced_at = EXCLUDED.synced_at
+ `
+
+	_, err := r.db.ExecContext(ctx, q, name, syncedAt)
+
+	return err
+}
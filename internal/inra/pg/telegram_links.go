@@ -0,0 +1,123 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"doctormakarhina/lumos/internal/core/domain"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type TelegramLinkRepo struct {
+	db *sqlx.DB
+}
+
+func NewTelegramLinkRepo(db *sqlx.DB) *TelegramLinkRepo {
+	return &TelegramLinkRepo{db: db}
+}
+
+// CreateCode saves the code and drops expired ones.
+func (r *TelegramLinkRepo) CreateCode(ctx context.Context, code domain.TelegramLinkCode) error {
+	const cleanupQ = `DELETE FROM lumos.telegram_link_codes WHERE expires_at <= NOW()`
+	const q = `
+  INSERT INTO lumos.telegram_link_codes (code_hash, user_id, expires_at)
+  VALUES ($1, $2, $3)
+ `
+
+	if _, err := r.db.ExecContext(ctx, cleanupQ); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, q, code.CodeHash, code.UserID, code.ExpiresAt)
+
+	return err
+}
+
+// ConsumeCode deletes not expired code and returns it, nil if there is no such code.
+func (r *TelegramLinkRepo) ConsumeCode(ctx context.Context, codeHash string) (*domain.TelegramLinkCode, error) {
+	const q = `
+  DELETE FROM lumos.telegram_link_codes
+  WHERE code_hash = $1 AND expires_at > NOW()
+  RETURNING code_hash, user_id, expires_at
+ `
+
+	var code domain.TelegramLinkCode
+	err := r.db.GetContext(ctx, &code, q, codeHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// Save links the chat to the user, previous links of both the user and the chat are replaced.
+func (r *TelegramLinkRepo) Save(ctx context.Context, link domain.TelegramLink) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	const deleteQ = `DELETE FROM lumos.telegram_links WHERE user_id = $1 OR chat_id = $2`
+	if _, err := tx.ExecContext(ctx, deleteQ, link.UserID, link.ChatID); err != nil {
+		return err
+	}
+
+	const q = `
+  INSERT INTO lumos.telegram_links (user_id, chat_id, username, linked_at)
+  VALUES ($1, $2, $3, $4)
+ `
+	if _, err := tx.ExecContext(ctx, q, link.UserID, link.ChatID, link.Username, link.LinkedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *TelegramLinkRepo) DeleteByChatID(ctx context.Context, chatID int64) (bool, error) {
+	const q = `DELETE FROM lumos.telegram_links WHERE chat_id = $1`
+
+	res, err := r.db.ExecContext(ctx, q, chatID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+
+	return n > 0, err
+}
+
+func (r *TelegramLinkRepo) ByChatID(ctx context.Context, chatID int64) (*domain.TelegramLink, error) {
+	const q = `
+  SELECT user_id, chat_id, username, linked_at
+  FROM lumos.telegram_links
+  WHERE chat_id = $1
+ `
+
+	return r.get(ctx, q, chatID)
+}
+
+func (r *TelegramLinkRepo) ByUserID(ctx context.Context, userID string) (*domain.TelegramLink, error) {
+	const q = `
+  SELECT user_id, chat_id, username, linked_at
+  FROM lumos.telegram_links
+  WHERE user_id = $1
+ `
+
+	return r.get(ctx, q, userID)
+}
+
+func (r *TelegramLinkRepo) get(ctx context.Context, q string, arg any) (*domain.TelegramLink, error) {
+	var link domain.TelegramLink
+	err := r.db.GetContext(ctx, &link, q, arg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &link, nil
+}
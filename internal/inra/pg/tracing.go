@@ -0,0 +1,14 @@
+package pg
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/pkg/tracing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan traces a repo method, query arguments are not recorded as they hold personal data.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, semconv.DBSystemPostgreSQL, semconv.DBOperationName(name))
+}
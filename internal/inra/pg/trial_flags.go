@@ -0,0 +1,36 @@
+package pg
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type TrialFlagRepo struct {
+	db *sqlx.DB
+}
+
+func NewTrialFlagRepo(db *sqlx.DB) *TrialFlagRepo {
+	return &TrialFlagRepo{db: db}
+}
+
+func (r *TrialFlagRepo) Create(ctx context.Context, flag domain.TrialFlag) error {
+	const q = `
+  INSERT INTO lumos.trial_flags (id, email, action, reasons, linked_logins, fingerprint)
+  VALUES ($1, $2, $3, $4, $5, $6)
+ `
+
+	_, err := r.db.ExecContext(
+		ctx,
+		q,
+		flag.ID,
+		flag.Email,
+		flag.Action,
+		flag.Reasons,
+		flag.LinkedLogins,
+		flag.Fingerprint,
+	)
+
+	return err
+}
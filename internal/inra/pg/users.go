@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/pkg/tracing"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -18,7 +20,10 @@ func NewUserRepo(db *sqlx.DB) *UserRepo {
 	return &UserRepo{db: db}
 }
 
-func (r *UserRepo) ByEmail(ctx context.Context, email string) (*domain.User, error) {
+func (r *UserRepo) ByEmail(ctx context.Context, email string) (_ *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ByEmail")
+	defer func() { tracing.End(span, err) }()
+
 	const q = `
   SELECT id, email, name, tariff, expires_at, subscription_id, subscription_status, last_sub_price, last_transaction_id
   FROM lumos.users
@@ -27,7 +32,7 @@ func (r *UserRepo) ByEmail(ctx context.Context, email string) (*domain.User, err
  `
 
 	var user domain.User
-	err := r.db.GetContext(ctx, &user, q, email)
+	err = r.db.GetContext(ctx, &user, q, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -38,7 +43,56 @@ func (r *UserRepo) ByEmail(ctx context.Context, email string) (*domain.User, err
 	return &user, nil
 }
 
-func (r *UserRepo) Create(ctx context.Context, user domain.User) (*domain.User, error) {
+func (r *UserRepo) ByID(ctx context.Context, id string) (_ *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ByID")
+	defer func() { tracing.End(span, err) }()
+
+	const q = `
+  SELECT id, email, name, tariff, expires_at, subscription_id, subscription_status, last_sub_price, last_transaction_id
+  FROM lumos.users
+  WHERE id = $1
+  LIMIT 1
+ `
+
+	var user domain.User
+	err = r.db.GetContext(ctx, &user, q, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepo) ByEmails(ctx context.Context, emails []string) (_ []domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ByEmails")
+	defer func() { tracing.End(span, err) }()
+
+	const q = `
+  SELECT id, email, name, tariff, expires_at, subscription_id, subscription_status, last_sub_price, last_transaction_id
+  FROM lumos.users
+  WHERE email = ANY($1)
+ `
+
+	users := make([]domain.User, 0)
+	if len(emails) == 0 {
+		return users, nil
+	}
+
+	err = r.db.SelectContext(ctx, &users, q, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *UserRepo) Create(ctx context.Context, user domain.User) (_ *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.Create")
+	defer func() { tracing.End(span, err) }()
+
 	const q = `
   INSERT INTO lumos.users (id, email, name, tariff, expires_at, subscription_id, subscription_status, last_sub_price, last_transaction_id)
   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
@@ -46,7 +100,7 @@ func (r *UserRepo) Create(ctx context.Context, user domain.User) (*domain.User,
  `
 
 	var created domain.User
-	err := r.db.GetContext(
+	err = r.db.GetContext(
 		ctx,
 		&created,
 		q,
@@ -67,7 +121,10 @@ func (r *UserRepo) Create(ctx context.Context, user domain.User) (*domain.User,
 	return &created, nil
 }
 
-func (r *UserRepo) FindByEmailOrCreate(ctx context.Context, user domain.User) (*domain.User, error) {
+func (r *UserRepo) FindByEmailOrCreate(ctx context.Context, user domain.User) (_ *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.FindByEmailOrCreate")
+	defer func() { tracing.End(span, err) }()
+
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -131,7 +188,10 @@ func (r *UserRepo) FindByEmailOrCreate(ctx context.Context, user domain.User) (*
 	return &created, nil
 }
 
-func (r *UserRepo) UpdateSub(ctx context.Context, user domain.User) (*domain.User, error) {
+func (r *UserRepo) UpdateSub(ctx context.Context, user domain.User) (_ *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.UpdateSub")
+	defer func() { tracing.End(span, err) }()
+
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -184,7 +244,10 @@ func (r *UserRepo) UpdateSub(ctx context.Context, user domain.User) (*domain.Use
 	return &updated, nil
 }
 
-func (r *UserRepo) UpdateSubStatusBySubID(ctx context.Context, subscriptionID string, status string) error {
+func (r *UserRepo) UpdateSubStatusBySubID(ctx context.Context, subscriptionID string, status string) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.UpdateSubStatusBySubID")
+	defer func() { tracing.End(span, err) }()
+
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
@@ -222,3 +285,100 @@ func (r *UserRepo) UpdateSubStatusBySubID(ctx context.Context, subscriptionID st
 
 	return nil
 }
+
+// ExtendExpiresAt atomically prolongs user access by the given duration,
+// counting from now if the access has already expired.
+// Users with unlimited tariff are left untouched.
+func (r *UserRepo) ExtendExpiresAt(ctx context.Context, id string, d time.Duration) (_ *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ExtendExpiresAt")
+	defer func() { tracing.End(span, err) }()
+
+	const q = `
+		UPDATE lumos.users
+		SET expires_at = GREATEST(expires_at, NOW()) + $1 * INTERVAL '1 second'
+		WHERE id = $2 AND tariff <> $3
+		RETURNING id, email, name, tariff, expires_at, subscription_id, subscription_status, last_sub_price, last_transaction_id
+	`
+
+	var updated domain.User
+	err = r.db.GetContext(ctx, &updated, q, int64(d.Seconds()), id, domain.UserTariffUnlimited)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// ExpiringBetween returns users whose access ends in [from, to) and will not
+// be extended automatically, unlimited users and active autopayments are skipped.
+func (r *UserRepo) ExpiringBetween(ctx context.Context, from time.Time, to time.Time) (_ []domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ExpiringBetween")
+	defer func() { tracing.End(span, err) }()
+
+	const q = `
+  SELECT id, email, name, tariff, expires_at, subscription_id, subscription_status, last_sub_price, last_transaction_id
+  FROM lumos.users
+  WHERE expires_at >= $1 AND expires_at < $2
+    AND tariff <> $3
+    AND subscription_status <> $4
+  ORDER BY expires_at
+ `
+
+	users := make([]domain.User, 0)
+	err = r.db.SelectContext(ctx, &users, q, from, to, domain.UserTariffUnlimited, domain.UserSubStatusActive)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ActiveByTariff counts users with access at now per tariff, unlimited users are always active.
+func (r *UserRepo) ActiveByTariff(ctx context.Context, now time.Time) (_ []domain.TariffCount, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ActiveByTariff")
+	defer func() { tracing.End(span, err) }()
+
+	const q = `
+  SELECT tariff, count(*) AS count
+  FROM lumos.users
+  WHERE expires_at > $1 OR tariff = $2
+  GROUP BY tariff
+ `
+
+	counts := make([]domain.TariffCount, 0)
+	err = r.db.SelectContext(ctx, &counts, q, now, domain.UserTariffUnlimited)
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// UpdatedAfter pages users by (updated_at, id) keyset, empty afterID starts from the beginning of afterTime.
+func (r *UserRepo) UpdatedAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) (_ []domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.UpdatedAfter")
+	defer func() { tracing.End(span, err) }()
+
+	const q = `
+  SELECT id, email, name, tariff, expires_at, subscription_id, subscription_status, last_sub_price, last_transaction_id, updated_at
+  FROM lumos.users
+  WHERE (updated_at, id) > ($1, $2::uuid)
+  ORDER BY updated_at, id
+  LIMIT $3
+ `
+
+	if afterID == "" {
+		afterID = "00000000-0000-0000-0000-000000000000"
+	}
+
+	users := make([]domain.User, 0, limit)
+	err = r.db.SelectContext(ctx, &users, q, afterTime, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
@@ -0,0 +1,139 @@
+package tgbot
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"doctormakarhina/lumos/internal/core/notify"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+type UserAdmin interface {
+	UserByID(ctx context.Context, userID string, projectID string) (*domain.User, error)
+	ExtendAccess(ctx context.Context, userID string, days int) (*domain.User, error)
+	CancelSubscription(ctx context.Context, userID string) (*domain.User, error)
+}
+
+type ActionNonces interface {
+	// Use marks the nonce used, false means it was used before
+	Use(ctx context.Context, nonce string) (bool, error)
+}
+
+// actionConfirmCancel is the button of confirmation message sent on cancel subscription press.
+const actionConfirmCancel = "cancel_sub_confirm"
+
+var (
+	errActionUsed    = errors.New("action already applied")
+	errActionExpired = errors.New("action has no nonce")
+)
+
+// RegInNotificationActions handles buttons attached to notifications, see notify.UserActions.
+func (r *Bot) RegInNotificationActions(srv UserAdmin, nonces ActionNonces) {
+	r.actions.Handle(&tele.Btn{Unique: notify.ActionOpenUser}, r.userAction("open user",
+		func(ctx context.Context, userID string) (*domain.User, error) {
+			return srv.UserByID(ctx, userID, "")
+		},
+	))
+	r.actions.Handle(&tele.Btn{Unique: notify.ActionExtendWeek}, r.userAction("extend access",
+		func(ctx context.Context, data string) (*domain.User, error) {
+			userID, nonce := notify.SplitNonce(data)
+			if nonce == "" {
+				return nil, errActionExpired
+			}
+			ok, err := nonces.Use(ctx, nonce)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, errActionUsed
+			}
+
+			return srv.ExtendAccess(ctx, userID, 7)
+		},
+	))
+	r.actions.Handle(&tele.Btn{Unique: notify.ActionCancelSubscription}, r.confirmCancel(srv))
+	r.actions.Handle(&tele.Btn{Unique: actionConfirmCancel}, r.userAction("cancel subscription",
+		srv.CancelSubscription,
+	))
+}
+
+// confirmCancel asks to confirm cancellation with a separate button, so one misclick does not cancel.
+func (r *Bot) confirmCancel(srv UserAdmin) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		user, err := srv.UserByID(ctx, c.Data(), "")
+		if err == nil && user == nil {
+			err = fmt.Errorf("user not found")
+		}
+		if err != nil {
+			return r.actionFailed(c, "cancel subscription", err)
+		}
+
+		if err := c.Respond(); err != nil {
+			return err
+		}
+
+		markup := &tele.ReplyMarkup{}
+		markup.Inline(markup.Row(markup.Data("Confirm cancel", actionConfirmCancel, user.ID)))
+		text := fmt.Sprintf("Cancel subscription of <b>%s</b>?", html.EscapeString(user.Email))
+		return c.Reply(text, markup, tele.ModeHTML)
+	}
+}
+
+func (r *Bot) userAction(name string, do func(ctx context.Context, data string) (*domain.User, error)) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		user, err := do(ctx, c.Data())
+		if err == nil && user == nil {
+			err = fmt.Errorf("user not found")
+		}
+		if err != nil {
+			return r.actionFailed(c, name, err)
+		}
+
+		if err := c.Respond(); err != nil {
+			return err
+		}
+		return c.Reply(formatUser(user, name, c.Sender()), tele.ModeHTML)
+	}
+}
+
+// actionFailed logs the error and answers the callback with generic text, errors are not shown in chats.
+func (r *Bot) actionFailed(c tele.Context, name string, err error) error {
+	r.logger.Error(
+		"notification action failed",
+		slog.String("err", err.Error()),
+		slog.String("action", name),
+		slog.String("data", c.Data()),
+	)
+
+	text := "Failed to " + name + ", see logs"
+	switch {
+	case errors.Is(err, errActionUsed):
+		text = "Already applied"
+	case errors.Is(err, errActionExpired):
+		text = "Button is outdated"
+	}
+	return c.Respond(&tele.CallbackResponse{Text: text, ShowAlert: true})
+}
+
+func formatUser(user *domain.User, action string, by *tele.User) string {
+	text := fmt.Sprintf("<b>%s</b> (%s)\n%s",
+		html.EscapeString(user.Email),
+		html.EscapeString(user.Name),
+		html.EscapeString(user.StatusInfo(time.Now())),
+	)
+	if by != nil {
+		text += fmt.Sprintf("\n\n<i>%s by %s</i>", html.EscapeString(action), html.EscapeString(by.Username))
+	}
+	return text
+}
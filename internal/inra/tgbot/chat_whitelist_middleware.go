@@ -22,3 +22,23 @@ func ChatWhitelist(chats ...int64) tele.MiddlewareFunc {
 		}
 	}
 }
+
+// ActionWhitelist allows callbacks from the chats or pressed by the users,
+// other callbacks are answered without running the handler.
+func ActionWhitelist(chats []int64, users []int64) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(ctx tele.Context) error {
+			if chat := ctx.Chat(); chat != nil && slices.Contains(chats, chat.ID) {
+				return next(ctx)
+			}
+			if sender := ctx.Sender(); sender != nil && slices.Contains(users, sender.ID) {
+				return next(ctx)
+			}
+
+			if ctx.Callback() != nil {
+				return ctx.Respond(&tele.CallbackResponse{Text: "Not allowed"})
+			}
+			return nil
+		}
+	}
+}
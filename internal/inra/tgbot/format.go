@@ -0,0 +1,216 @@
+package tgbot
+
+import (
+	"doctormakarhina/lumos/internal/core/notify"
+	"html"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+var severityIcons = map[notify.Severity]string{
+	notify.SeverityInfo:    "ℹ️",
+	notify.SeverityWarning: "⚠️",
+	notify.SeverityError:   "🔴",
+}
+
+// formatHTML renders notification for telegram HTML parse mode, all the data is escaped.
+func formatHTML(n notify.Notification) string {
+	var b strings.Builder
+
+	b.WriteString(severityIcons[n.Severity])
+	if event := strings.TrimPrefix(n.Event, "legacy."); event != "" && event != "legacy" {
+		b.WriteString(" <b>")
+		b.WriteString(html.EscapeString(event))
+		b.WriteString("</b>")
+	}
+	b.WriteString("\n")
+	b.WriteString(html.EscapeString(n.Message))
+
+	for _, f := range n.Fields {
+		b.WriteString("\n<b>")
+		b.WriteString(html.EscapeString(f.Key))
+		b.WriteString("</b>: <code>")
+		b.WriteString(html.EscapeString(f.Value))
+		b.WriteString("</code>")
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// keyboard renders actions as inline buttons handled by RegInNotificationActions.
+func keyboard(actions []notify.Action) *tele.ReplyMarkup {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	markup := &tele.ReplyMarkup{}
+	btns := make([]tele.Btn, 0, len(actions))
+	for _, a := range actions {
+		btns = append(btns, markup.Data(a.Label, a.Name, a.Value))
+	}
+	markup.Inline(markup.Row(btns...))
+
+	return markup
+}
+
+// splitTelegramMessage splits s by lines into chunks of at most maxLen runes,
+// too long lines are cut between HTML tags and entities. Tags open at a cut are
+// closed at the end of the chunk and reopened in the next one, so every chunk is valid HTML.
+func splitTelegramMessage(s string, maxLen int) []string {
+	if maxLen <= 0 || len([]rune(s)) <= maxLen {
+		return []string{s}
+	}
+
+	sp := &htmlSplitter{maxLen: maxLen, empty: true}
+	for i, line := range strings.Split(s, "\n") {
+		sp.addLine(tokenizeHTML(line), i > 0)
+	}
+	sp.flush()
+
+	return sp.out
+}
+
+type htmlToken struct {
+	text  string
+	runes int
+	// tag is name of the opening or closing tag, closing is set for the latter
+	tag     string
+	closing bool
+}
+
+// tokenizeHTML splits s into tags, entities and single runes, which are never cut.
+func tokenizeHTML(s string) []htmlToken {
+	rs := []rune(s)
+	var tokens []htmlToken
+	for i := 0; i < len(rs); {
+		end := i + 1
+		switch rs[i] {
+		case '<':
+			if j := indexRune(rs[i:], '>', len(rs)); j != -1 {
+				end = i + j + 1
+			}
+		case '&':
+			// entities are short, longer runs are plain text with a bare ampersand
+			if j := indexRune(rs[i:], ';', 10); j != -1 {
+				end = i + j + 1
+			}
+		}
+
+		t := htmlToken{text: string(rs[i:end]), runes: end - i}
+		if rs[i] == '<' && end-i > 2 {
+			name := strings.TrimPrefix(t.text[1:len(t.text)-1], "/")
+			t.closing = len(name) < len(t.text)-2
+			t.tag, _, _ = strings.Cut(name, " ")
+		}
+		tokens = append(tokens, t)
+		i = end
+	}
+	return tokens
+}
+
+func indexRune(rs []rune, r rune, limit int) int {
+	for i := 0; i < len(rs) && i < limit; i++ {
+		if rs[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+type openTag struct {
+	name string
+	text string
+}
+
+type htmlSplitter struct {
+	maxLen int
+	out    []string
+
+	cur    strings.Builder
+	curLen int
+	// empty is set while cur holds only tags reopened after a cut
+	empty bool
+	open  []openTag
+}
+
+// addLine appends the line to the current chunk when it fits, otherwise the line
+// starts a new chunk and is cut further by tokens if it is still too long.
+func (r *htmlSplitter) addLine(tokens []htmlToken, newLine bool) {
+	n, open := 0, r.open
+	for _, t := range tokens {
+		n += t.runes
+		open = applyTag(open, t)
+	}
+	if newLine && !r.empty {
+		n++
+	}
+	if r.curLen+n+closingLen(open) <= r.maxLen {
+		if newLine && !r.empty {
+			r.write(htmlToken{text: "\n", runes: 1})
+		}
+		for _, t := range tokens {
+			r.write(t)
+		}
+		return
+	}
+
+	r.flush()
+	for _, t := range tokens {
+		if !r.empty && r.curLen+t.runes+closingLen(applyTag(r.open, t)) > r.maxLen {
+			r.flush()
+		}
+		r.write(t)
+	}
+	r.empty = false
+}
+
+func (r *htmlSplitter) write(t htmlToken) {
+	r.cur.WriteString(t.text)
+	r.curLen += t.runes
+	r.open = applyTag(r.open, t)
+	r.empty = false
+}
+
+// flush closes open tags of the current chunk and reopens them in the next one.
+func (r *htmlSplitter) flush() {
+	if r.empty {
+		return
+	}
+	for i := len(r.open) - 1; i >= 0; i-- {
+		r.cur.WriteString("</" + r.open[i].name + ">")
+	}
+	r.out = append(r.out, r.cur.String())
+
+	r.cur.Reset()
+	r.curLen = 0
+	for _, t := range r.open {
+		r.cur.WriteString(t.text)
+		r.curLen += len([]rune(t.text))
+	}
+	r.empty = true
+}
+
+// applyTag returns open tags after the token, open is not modified.
+func applyTag(open []openTag, t htmlToken) []openTag {
+	if t.tag == "" {
+		return open
+	}
+	if !t.closing {
+		return append(open[:len(open):len(open)], openTag{name: t.tag, text: t.text})
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i].name == t.tag {
+			return open[:i:i]
+		}
+	}
+	return open
+}
+
+func closingLen(open []openTag) int {
+	n := 0
+	for _, t := range open {
+		n += len(t.name) + 3
+	}
+	return n
+}
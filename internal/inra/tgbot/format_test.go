@@ -0,0 +1,70 @@
+//go:build unit
+// +build unit
+
+package tgbot
+
+import (
+	"doctormakarhina/lumos/internal/core/notify"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatHTMLEscapes(t *testing.T) {
+	n := notify.Warning("payments.accepted", "user <b>paid</b> & left",
+		notify.F("name", "<script>"),
+	)
+
+	require.Equal(t,
+		"⚠️ <b>payments.accepted</b>\nuser &lt;b&gt;paid&lt;/b&gt; &amp; left\n<b>name</b>: <code>&lt;script&gt;</code>",
+		formatHTML(n),
+	)
+	require.Equal(t, "🔴 <b>UserInfo</b>\nerror fetching", formatHTML(notify.FromLegacy("[UserInfo]: error fetching")))
+}
+
+func TestKeyboard(t *testing.T) {
+	require.Nil(t, keyboard(nil))
+
+	markup := keyboard(notify.UserActions("7f1c1b7e-8d52-4a39-9b8e-0a4d8b6a2f10", true))
+	require.Len(t, markup.InlineKeyboard, 1)
+	require.Len(t, markup.InlineKeyboard[0], 3)
+	for _, btn := range markup.InlineKeyboard[0] {
+		require.LessOrEqual(t, len(btn.Data), 64)
+	}
+}
+
+func TestSplitTelegramMessageByLines(t *testing.T) {
+	line := strings.Repeat("a", 6)
+	s := strings.Join([]string{line, line, line}, "\n")
+
+	require.Equal(t, []string{s}, splitTelegramMessage(s, 100))
+	require.Equal(t, []string{line + "\n" + line, line}, splitTelegramMessage(s, 14))
+	require.Equal(t, []string{line, line, line}, splitTelegramMessage(s, 10))
+}
+
+func TestSplitTelegramMessageKeepsEntities(t *testing.T) {
+	chunks := splitTelegramMessage("aaaa&amp;bbbb", 6)
+
+	require.Equal(t, "aaaa", chunks[0])
+	require.Equal(t, "aaaa&amp;bbbb", strings.Join(chunks, ""))
+	for _, c := range chunks {
+		require.LessOrEqual(t, len(c), 6)
+	}
+}
+
+func TestSplitTelegramMessageKeepsTags(t *testing.T) {
+	s := "<b>title</b>\n<b>key</b>: <code>" + strings.Repeat("v", 30) + "</code>"
+	chunks := splitTelegramMessage(s, 20)
+
+	require.Equal(t, "<b>title</b>", chunks[0])
+	for _, c := range chunks {
+		require.LessOrEqual(t, len([]rune(c)), 20)
+		require.Equal(t, strings.Count(c, "<code>"), strings.Count(c, "</code>"), c)
+		require.Equal(t, strings.Count(c, "<b>"), strings.Count(c, "</b>"), c)
+	}
+	require.Equal(t,
+		strings.Repeat("v", 30),
+		strings.NewReplacer("<code>", "", "</code>", "").Replace(strings.Join(chunks[2:], "")),
+	)
+}
@@ -0,0 +1,221 @@
+package tgbot
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/members"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// MemberBot is the customer facing bot, it talks in private chats only.
+type MemberBot struct {
+	token         string
+	debug         bool
+	pollerTimeout time.Duration
+
+	logger  *slog.Logger
+	bot     *tele.Bot
+	queue   *sendQueue
+	webhook *webhookPoller
+}
+
+type MemberBotCfg struct {
+	Token         string
+	Debug         bool
+	PollerTimeout time.Duration
+	Webhook       WebhookCfg
+	Queue         QueueCfg
+	Logger        *slog.Logger
+}
+
+func NewMemberTgBot(cfg MemberBotCfg) (*MemberBot, error) {
+	logger := cfg.Logger.With("context", "MemberTgBot")
+
+	bot := &MemberBot{
+		token:         cfg.Token,
+		debug:         cfg.Debug,
+		pollerTimeout: cfg.PollerTimeout,
+
+		logger: logger,
+	}
+	if cfg.Webhook.URL != "" {
+		bot.webhook = newWebhookPoller(cfg.Webhook, logger.With(slog.String("component", "Webhook")))
+	}
+	bot.queue = newSendQueue(cfg.Queue, bot.sendText, logger.With(slog.String("component", "SendQueue")))
+
+	err := bot.Init()
+	if err != nil {
+		return nil, err
+	}
+
+	return bot, nil
+}
+
+func (r *MemberBot) Init() error {
+	settings := tele.Settings{
+		Token:   r.token,
+		Poller:  newPoller(r.webhook, r.pollerTimeout),
+		Verbose: r.debug,
+		OnError: func(err error, _ tele.Context) {
+			r.logger.Error(
+				"unexpected error on member tg bot",
+				slog.String("err", err.Error()),
+			)
+		},
+	}
+	bot, err := tele.NewBot(settings)
+	if err != nil {
+		return err
+	}
+	r.bot = bot
+
+	err = dropWebhook(r.bot, r.webhook)
+	if err != nil {
+		return err
+	}
+
+	r.bot.Use(PrivateChatsOnly())
+
+	return nil
+}
+
+// WebhookHandler accepts telegram updates in webhook mode, it is nil for long polling.
+func (r *MemberBot) WebhookHandler() http.Handler {
+	if r.webhook == nil {
+		return nil
+	}
+	return r.webhook
+}
+
+func (r *MemberBot) Run(ctx context.Context) error {
+	if r.bot == nil {
+		panic("error: member tg bot is not initialized")
+	}
+
+	return runBot(ctx, r.bot, r.queue)
+}
+
+// RegInMemberCommands registers account linking and status commands.
+func (r *MemberBot) RegInMemberCommands(srv members.Service) {
+	link := func(c tele.Context, code string) error {
+		if code == "" {
+			return c.Send(memberLinkHelpText)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		user, err := srv.Link(ctx, code, c.Chat().ID, c.Sender().Username)
+		if errors.Is(err, members.ErrInvalidCode) {
+			return c.Send(memberInvalidCodeText)
+		}
+		if err != nil {
+			r.logger.Error("failed to link member chat", slog.String("err", err.Error()))
+			return c.Send(memberErrorText)
+		}
+
+		return c.Send(fmt.Sprintf(memberLinkedText, user.Email))
+	}
+
+	r.bot.Handle("/start", func(c tele.Context) error {
+		if code := strings.TrimSpace(c.Message().Payload); code != "" {
+			return link(c, code)
+		}
+		return c.Send(memberWelcomeText)
+	})
+	r.bot.Handle("/help", func(c tele.Context) error {
+		return c.Send(memberWelcomeText)
+	})
+	r.bot.Handle("/link", func(c tele.Context) error {
+		return link(c, strings.TrimSpace(c.Message().Payload))
+	})
+
+	r.bot.Handle("/status", func(c tele.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		msg, err := srv.Status(ctx, c.Chat().ID)
+		if errors.Is(err, members.ErrNotLinked) {
+			return c.Send(memberNotLinkedText)
+		}
+		if err != nil {
+			r.logger.Error("failed to fetch member status", slog.String("err", err.Error()))
+			return c.Send(memberErrorText)
+		}
+
+		return c.Send(msg.Text, &tele.SendOptions{ReplyMarkup: urlKeyboard(msg.Buttons)})
+	})
+
+	r.bot.Handle("/unlink", func(c tele.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err := srv.Unlink(ctx, c.Chat().ID)
+		if errors.Is(err, members.ErrNotLinked) {
+			return c.Send(memberNotLinkedText)
+		}
+		if err != nil {
+			r.logger.Error("failed to unlink member chat", slog.String("err", err.Error()))
+			return c.Send(memberErrorText)
+		}
+
+		return c.Send(memberUnlinkedText)
+	})
+}
+
+// SendMessage implements members.Messenger, the message is delivered in background.
+func (r *MemberBot) SendMessage(_ context.Context, chatID int64, msg members.Message) error {
+	chunks := splitTelegramMessage(msg.Text, telegramMaxMessageLen)
+	msgs := make([]outMsg, len(chunks))
+	for i, chunk := range chunks {
+		msgs[i] = outMsg{text: chunk}
+	}
+	msgs[len(msgs)-1].markup = urlKeyboard(msg.Buttons)
+
+	if !r.queue.Enqueue(chatKey{chatID: chatID}, msgs...) {
+		return fmt.Errorf("telegram send queue rejected message to chat %d", chatID)
+	}
+	return nil
+}
+
+func (r *MemberBot) sendText(key chatKey, text string, markup *tele.ReplyMarkup) error {
+	return observeSend("member", key, func() error {
+		_, err := r.bot.Send(tele.ChatID(key.chatID), text, &tele.SendOptions{ReplyMarkup: markup})
+		return err
+	})
+}
+
+func urlKeyboard(buttons []members.Button) *tele.ReplyMarkup {
+	if len(buttons) == 0 {
+		return nil
+	}
+
+	markup := &tele.ReplyMarkup{}
+	rows := make([]tele.Row, 0, len(buttons))
+	for _, b := range buttons {
+		rows = append(rows, markup.Row(markup.URL(b.Label, b.URL)))
+	}
+	markup.Inline(rows...)
+
+	return markup
+}
+
+// PrivateChatsOnly ignores updates from groups and channels.
+func PrivateChatsOnly() tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(ctx tele.Context) error {
+			chat := ctx.Chat()
+			if chat == nil || chat.Type != tele.ChatPrivate {
+				return nil
+			}
+
+			return next(ctx)
+		}
+	}
+}
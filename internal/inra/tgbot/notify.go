@@ -1,59 +1,43 @@
 package tgbot
 
 import (
-	"log/slog"
-	"strings"
-
-	tele "gopkg.in/telebot.v4"
+	"context"
+	"doctormakarhina/lumos/internal/core/notify"
+	"fmt"
 )
 
-func (r *Bot) ForAdmin(msg string) {
-	msg = strings.TrimSpace(msg)
-	if msg == "" {
-		return
-	}
-
-	recipient := tele.ChatID(r.chatID)
-	chunks := r.splitTelegramMessage(msg, telegramMaxMessageLen)
-
-	for i, chunk := range chunks {
-		chunk = strings.TrimSpace(chunk)
-		if chunk == "" {
-			continue
-		}
+// notifyTarget sends notifications to a chat or a forum topic of the chat when threadID is set.
+type notifyTarget struct {
+	bot      *Bot
+	chatID   int64
+	threadID int
+}
 
-		if _, err := r.bot.Send(recipient, chunk); err != nil {
-			r.logger.Error(
-				"failed to send admin notification message",
-				slog.String("err", err.Error()),
-				slog.Int64("chat_id", r.chatID),
-				slog.Int("chunk_idx", i),
-				slog.Int("chunks_total", len(chunks)),
-			)
-		}
+// Target returns notify.Sender for the chat, zero chatID means the admin chat.
+func (r *Bot) Target(chatID int64, threadID int) notify.Sender {
+	if chatID == 0 {
+		chatID = r.chatID
 	}
+	return &notifyTarget{bot: r, chatID: chatID, threadID: threadID}
 }
 
-func (r *Bot) splitTelegramMessage(s string, maxLen int) []string {
-	if maxLen <= 0 {
-		return []string{s}
-	}
-	if len(s) <= maxLen {
-		return []string{s}
+// Send enqueues the notification rendered as HTML, delivery happens in background so callers
+// are not delayed by telegram, errors are logged by the queue.
+func (r *notifyTarget) Send(_ context.Context, n notify.Notification) error {
+	msg := formatHTML(n)
+	if msg == "" {
+		return nil
 	}
 
-	rs := []rune(s)
-	if len(rs) <= maxLen {
-		return []string{s}
+	chunks := splitTelegramMessage(msg, telegramMaxMessageLen)
+	msgs := make([]outMsg, len(chunks))
+	for i, chunk := range chunks {
+		msgs[i] = outMsg{text: chunk}
 	}
+	msgs[len(msgs)-1].markup = keyboard(n.Actions)
 
-	out := make([]string, 0, (len(rs)+maxLen-1)/maxLen)
-	for start := 0; start < len(rs); start += maxLen {
-		end := start + maxLen
-		if end > len(rs) {
-			end = len(rs)
-		}
-		out = append(out, string(rs[start:end]))
+	if !r.bot.queue.Enqueue(chatKey{chatID: r.chatID, threadID: r.threadID}, msgs...) {
+		return fmt.Errorf("telegram send queue rejected message to chat %d", r.chatID)
 	}
-	return out
+	return nil
 }
@@ -0,0 +1,317 @@
+package tgbot
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/pkg/metrics"
+	"doctormakarhina/lumos/internal/pkg/tracing"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	tele "gopkg.in/telebot.v4"
+)
+
+// QueueCfg tunes async delivery, telegram allows about one message per second per chat,
+// 20 messages per minute per group and 30 messages per second overall.
+type QueueCfg struct {
+	// Size bounds amount of pending messages across all chats, new ones are dropped when it is full
+	Size int
+	// ChatInterval is min delay between messages to the same chat
+	ChatInterval time.Duration
+	// GlobalInterval is min delay between any two messages
+	GlobalInterval time.Duration
+	// MaxAttempts is how many times a message is sent before it is dropped, flood waits are not counted
+	MaxAttempts int
+	// DrainTimeout bounds delivery of pending messages on shutdown
+	DrainTimeout time.Duration
+}
+
+type chatKey struct {
+	chatID   int64
+	threadID int
+}
+
+type outMsg struct {
+	text string
+	// markup is kept with the message, such messages are never coalesced
+	markup   *tele.ReplyMarkup
+	attempts int
+}
+
+type chatQueue struct {
+	key    chatKey
+	msgs   []outMsg
+	nextAt time.Time
+}
+
+type sendFunc func(key chatKey, text string, markup *tele.ReplyMarkup) error
+
+// sendQueue delivers messages from a single worker, pending messages of a chat are
+// coalesced into one telegram message while they fit into the length limit.
+type sendQueue struct {
+	cfg    QueueCfg
+	send   sendFunc
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	chats  map[chatKey]*chatQueue
+	order  []chatKey
+	size   int
+	closed bool
+	wake   chan struct{}
+}
+
+// observeSend traces a send to telegram and counts its failures.
+func observeSend(bot string, key chatKey, send func() error) error {
+	_, span := tracing.Start(context.Background(), "telegram send",
+		attribute.String("telegram.bot", bot),
+		attribute.Int64("telegram.chat_id", key.chatID),
+	)
+	err := send()
+	tracing.End(span, err)
+	if err == nil {
+		return nil
+	}
+
+	reason := "error"
+	var flood tele.FloodError
+	if errors.As(err, &flood) {
+		reason = "flood"
+	}
+	metrics.TelegramSendFailures.WithLabelValues(bot, reason).Inc()
+	return err
+}
+
+func newSendQueue(cfg QueueCfg, send sendFunc, logger *slog.Logger) *sendQueue {
+	if cfg.Size <= 0 {
+		cfg.Size = 1000
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = 10 * time.Second
+	}
+
+	return &sendQueue{
+		cfg:    cfg,
+		send:   send,
+		logger: logger,
+		chats:  make(map[chatKey]*chatQueue),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Enqueue adds messages without blocking, it reports false when they were dropped.
+func (q *sendQueue) Enqueue(key chatKey, msgs ...outMsg) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.size+len(msgs) > q.cfg.Size {
+		q.logger.Error(
+			"telegram send queue is full or closed, message dropped",
+			slog.Int64("chat_id", key.chatID),
+			slog.Int("queued", q.size),
+		)
+		return false
+	}
+
+	cq, ok := q.chats[key]
+	if !ok {
+		cq = &chatQueue{key: key}
+		q.chats[key] = cq
+		q.order = append(q.order, key)
+	}
+	cq.msgs = append(cq.msgs, msgs...)
+	q.size += len(msgs)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (q *sendQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// Run delivers messages until ctx is done, then it stops accepting new ones
+// and drains pending messages within DrainTimeout.
+func (q *sendQueue) Run(ctx context.Context) error {
+	q.loop(ctx)
+
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), q.cfg.DrainTimeout)
+	defer cancel()
+	q.loop(drainCtx)
+
+	if left := q.Len(); left > 0 {
+		q.logger.Error("telegram send queue is not drained", slog.Int("dropped", left))
+	}
+	return nil
+}
+
+func (q *sendQueue) loop(ctx context.Context) {
+	draining := ctx.Err() == nil && q.isClosed()
+
+	for {
+		batch, wait := q.next(time.Now())
+		if batch != nil {
+			q.deliver(batch)
+			if q.cfg.GlobalInterval > 0 && !sleep(ctx, q.cfg.GlobalInterval) {
+				return
+			}
+			continue
+		}
+		if draining && q.Len() == 0 {
+			return
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+func (q *sendQueue) isClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+type batch struct {
+	key  chatKey
+	msgs []outMsg
+}
+
+// next pops coalesced messages of the first ready chat in round robin order,
+// otherwise it returns how long to wait for the closest ready chat.
+func (q *sendQueue) next(now time.Time) (*batch, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	wait := time.Hour
+	for i, key := range q.order {
+		cq := q.chats[key]
+		if len(cq.msgs) == 0 {
+			continue
+		}
+		if d := cq.nextAt.Sub(now); d > 0 {
+			wait = min(wait, d)
+			continue
+		}
+
+		n, length := 0, 0
+		for _, m := range cq.msgs {
+			if m.markup != nil {
+				if n == 0 {
+					n = 1
+				}
+				break
+			}
+			l := len([]rune(m.text))
+			if n > 0 {
+				l += len(coalesceSep)
+			}
+			if n > 0 && length+l > telegramMaxMessageLen {
+				break
+			}
+			length += l
+			n++
+		}
+
+		b := &batch{key: key, msgs: append([]outMsg(nil), cq.msgs[:n]...)}
+		cq.msgs = cq.msgs[n:]
+		q.size -= n
+		q.order = append(append(q.order[:i:i], q.order[i+1:]...), key)
+		return b, 0
+	}
+
+	return nil, wait
+}
+
+const coalesceSep = "\n\n"
+
+func (q *sendQueue) deliver(b *batch) {
+	texts := make([]string, len(b.msgs))
+	for i, m := range b.msgs {
+		texts[i] = m.text
+	}
+
+	err := q.send(b.key, strings.Join(texts, coalesceSep), b.msgs[len(b.msgs)-1].markup)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cq := q.chats[b.key]
+	now := time.Now()
+	if err == nil {
+		cq.nextAt = now.Add(q.cfg.ChatInterval)
+		return
+	}
+
+	var flood tele.FloodError
+	if errors.As(err, &flood) {
+		retryAfter := time.Duration(flood.RetryAfter) * time.Second
+		q.logger.Warn(
+			"telegram flood limit hit, delaying chat",
+			slog.Int64("chat_id", b.key.chatID),
+			slog.Duration("retry_after", retryAfter),
+		)
+		cq.nextAt = now.Add(max(retryAfter, q.cfg.ChatInterval))
+		q.requeue(cq, b.msgs)
+		return
+	}
+
+	retry := make([]outMsg, 0, len(b.msgs))
+	for _, m := range b.msgs {
+		m.attempts++
+		if m.attempts < q.cfg.MaxAttempts {
+			retry = append(retry, m)
+		}
+	}
+	q.logger.Error(
+		"failed to send telegram message",
+		slog.String("err", err.Error()),
+		slog.Int64("chat_id", b.key.chatID),
+		slog.Int("messages", len(b.msgs)),
+		slog.Int("dropped", len(b.msgs)-len(retry)),
+	)
+	attempts := 1
+	if len(retry) > 0 {
+		attempts = retry[0].attempts
+	}
+	cq.nextAt = now.Add(max(q.cfg.ChatInterval, time.Duration(attempts)*time.Second))
+	q.requeue(cq, retry)
+}
+
+func (q *sendQueue) requeue(cq *chatQueue, msgs []outMsg) {
+	cq.msgs = append(msgs, cq.msgs...)
+	q.size += len(msgs)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,118 @@
+//go:build unit
+// +build unit
+
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	tele "gopkg.in/telebot.v4"
+)
+
+type fakeTelegram struct {
+	mu   sync.Mutex
+	sent []string
+	errs []error
+}
+
+func (f *fakeTelegram) send(_ chatKey, text string, _ *tele.ReplyMarkup) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	f.sent = append(f.sent, text)
+	return nil
+}
+
+func (f *fakeTelegram) messages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+func testQueue(f *fakeTelegram, cfg QueueCfg) *sendQueue {
+	return newSendQueue(cfg, f.send, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestSendQueueCoalescesBurst(t *testing.T) {
+	f := &fakeTelegram{}
+	q := testQueue(f, QueueCfg{ChatInterval: time.Hour})
+	key := chatKey{chatID: 1}
+
+	require.True(t, q.Enqueue(key, outMsg{text: "a"}))
+	require.True(t, q.Enqueue(key, outMsg{text: "b"}, outMsg{text: "c"}))
+	require.True(t, q.Enqueue(chatKey{chatID: 2}, outMsg{text: "other"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, q.Run(ctx))
+
+	require.ElementsMatch(t, []string{"a\n\nb\n\nc", "other"}, f.messages())
+	require.False(t, q.Enqueue(key, outMsg{text: "late"}))
+}
+
+func TestSendQueueSplitsLongBurst(t *testing.T) {
+	f := &fakeTelegram{}
+	q := testQueue(f, QueueCfg{})
+	long := strings.Repeat("x", telegramMaxMessageLen-3)
+
+	require.True(t, q.Enqueue(chatKey{chatID: 1}, outMsg{text: long}, outMsg{text: "tail"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, q.Run(ctx))
+
+	sent := f.messages()
+	require.Len(t, sent, 2)
+	require.Equal(t, long, sent[0])
+	require.Equal(t, "tail", sent[1])
+}
+
+func TestSendQueueRetries(t *testing.T) {
+	f := &fakeTelegram{errs: []error{tele.FloodError{RetryAfter: 0}, errors.New("boom")}}
+	q := testQueue(f, QueueCfg{ChatInterval: time.Millisecond, MaxAttempts: 3, DrainTimeout: 5 * time.Second})
+
+	require.True(t, q.Enqueue(chatKey{chatID: 1}, outMsg{text: "paid"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, q.Run(ctx))
+
+	require.Equal(t, []string{"paid"}, f.messages())
+	require.Zero(t, q.Len())
+}
+
+func TestSendQueueDropsAfterMaxAttempts(t *testing.T) {
+	boom := errors.New("boom")
+	f := &fakeTelegram{errs: []error{boom, boom}}
+	q := testQueue(f, QueueCfg{ChatInterval: time.Millisecond, MaxAttempts: 2, DrainTimeout: 5 * time.Second})
+
+	require.True(t, q.Enqueue(chatKey{chatID: 1}, outMsg{text: "lost"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, q.Run(ctx))
+
+	require.Empty(t, f.messages())
+	require.Zero(t, q.Len())
+}
+
+func TestSendQueueFull(t *testing.T) {
+	q := testQueue(&fakeTelegram{}, QueueCfg{Size: 2})
+
+	require.True(t, q.Enqueue(chatKey{chatID: 1}, outMsg{text: "a"}, outMsg{text: "b"}))
+	require.False(t, q.Enqueue(chatKey{chatID: 1}, outMsg{text: "c"}))
+}
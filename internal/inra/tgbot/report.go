@@ -0,0 +1,36 @@
+package tgbot
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/notify"
+	"log/slog"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+type BusinessReporter interface {
+	Report(ctx context.Context, period string) (notify.Notification, error)
+}
+
+// RegInReportCommand registers /report [today|yesterday|week|month] command showing business metrics.
+func (r *Bot) RegInReportCommand(srv BusinessReporter) {
+	r.admin.Handle("/report", func(c tele.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		n, err := srv.Report(ctx, c.Message().Payload)
+		if err != nil {
+			r.logger.Error("failed to build business report", slog.String("err", err.Error()))
+			return c.Send("Failed to build business report: " + err.Error())
+		}
+
+		for _, chunk := range splitTelegramMessage(formatHTML(n), telegramMaxMessageLen) {
+			if err := c.Send(chunk, tele.ModeHTML); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,53 @@
+package tgbot
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+type SeatsReporter interface {
+	Usage(ctx context.Context) ([]domain.SeatUsage, error)
+}
+
+// RegInSeatsCommand registers /seats command showing seats usage per team owner.
+func (r *Bot) RegInSeatsCommand(srv SeatsReporter) {
+	r.admin.Handle("/seats", func(c tele.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		usage, err := srv.Usage(ctx)
+		if err != nil {
+			r.logger.Error("failed to fetch seats usage", slog.String("err", err.Error()))
+			return c.Send("Failed to fetch seats usage: " + err.Error())
+		}
+
+		if len(usage) == 0 {
+			return c.Send("No team seats in use")
+		}
+
+		now := time.Now()
+		var b strings.Builder
+		b.WriteString("Seats usage:\n")
+		for _, u := range usage {
+			status := "🆗"
+			if u.Used > u.Limit || now.After(u.ExpiresAt) {
+				status = "⚠️"
+			}
+			fmt.Fprintf(&b, "\n%s %s — %s — %d/%d, до %s", status, u.OwnerEmail, u.Tariff, u.Used, u.Limit, u.ExpiresAt.Format("02.01.2006"))
+		}
+
+		for _, chunk := range splitTelegramMessage(b.String(), telegramMaxMessageLen) {
+			if err := c.Send(chunk); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
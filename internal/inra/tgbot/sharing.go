@@ -0,0 +1,58 @@
+package tgbot
+
+import (
+	"context"
+	"doctormakarhina/lumos/internal/core/domain"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+type SharingReporter interface {
+	Report(ctx context.Context) ([]domain.SharingScore, error)
+}
+
+// RegInSharingCommand registers /sharing command showing accounts likely shared among several people.
+func (r *Bot) RegInSharingCommand(srv SharingReporter) {
+	r.admin.Handle("/sharing", func(c tele.Context) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		scores, err := srv.Report(ctx)
+		if err != nil {
+			r.logger.Error("failed to build sharing report", slog.String("err", err.Error()))
+			return c.Send("Failed to build sharing report: " + err.Error())
+		}
+
+		if len(scores) == 0 {
+			return c.Send("No accounts used from several devices")
+		}
+
+		var b strings.Builder
+		b.WriteString("Account sharing report:\n")
+		for _, sc := range scores {
+			fmt.Fprintf(
+				&b,
+				"\n%.1f — %s — %s — fp %d, ip %d, ua %d, до %s",
+				sc.Score,
+				sc.Login,
+				sc.Tariff,
+				sc.Fingerprints,
+				sc.IPs,
+				sc.UserAgents,
+				sc.ExpiresAt.Format("02.01.2006"),
+			)
+		}
+
+		for _, chunk := range splitTelegramMessage(b.String(), telegramMaxMessageLen) {
+			if err := c.Send(chunk); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -3,3 +3,16 @@ package tgbot
 const (
 	welcomeText = "Welcome to Lumos API BOT!"
 )
+
+const (
+	memberWelcomeText = "Здравствуйте! Это бот Lumos.\n\n" +
+		"Чтобы привязать аккаунт, откройте ссылку из личного кабинета или отправьте /link КОД.\n" +
+		"/status — статус доступа\n" +
+		"/unlink — отвязать аккаунт"
+	memberLinkHelpText    = "Отправьте /link КОД, код можно получить в личном кабинете."
+	memberInvalidCodeText = "Код неверный или устарел, получите новый в личном кабинете."
+	memberLinkedText      = "Аккаунт %s привязан. Сюда будут приходить напоминания и чеки об оплате, статус доступа — /status."
+	memberNotLinkedText   = "Аккаунт не привязан. Отправьте /link КОД, код можно получить в личном кабинете."
+	memberUnlinkedText    = "Аккаунт отвязан, уведомления больше не будут приходить."
+	memberErrorText       = "Что-то пошло не так, попробуйте позже."
+)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"time"
 
 	tele "gopkg.in/telebot.v4"
@@ -14,18 +15,30 @@ const telegramMaxMessageLen = 4096
 type Bot struct {
 	token         string
 	chatID        int64
+	actionChatIDs []int64
+	actionUserIDs []int64
 	debug         bool
 	pollerTimeout time.Duration
 
-	logger *slog.Logger
-	bot    *tele.Bot
+	logger  *slog.Logger
+	bot     *tele.Bot
+	admin   *tele.Group
+	actions *tele.Group
+	queue   *sendQueue
+	webhook *webhookPoller
 }
 
 type BotCfg struct {
-	Token         string
-	ChatID        int64
+	Token  string
+	ChatID int64
+	// ActionChatIDs are chats besides the admin chat whose members may press notification buttons
+	ActionChatIDs []int64
+	// ActionUserIDs are telegram users allowed to press notification buttons in any chat
+	ActionUserIDs []int64
 	Debug         bool
 	PollerTimeout time.Duration
+	Webhook       WebhookCfg
+	Queue         QueueCfg
 	Logger        *slog.Logger
 }
 
@@ -35,11 +48,17 @@ func NewAdminTgBot(cfg BotCfg) (*Bot, error) {
 	bot := &Bot{
 		token:         cfg.Token,
 		chatID:        cfg.ChatID,
+		actionChatIDs: cfg.ActionChatIDs,
+		actionUserIDs: cfg.ActionUserIDs,
 		debug:         cfg.Debug,
 		pollerTimeout: cfg.PollerTimeout,
 
 		logger: logger,
 	}
+	if cfg.Webhook.URL != "" {
+		bot.webhook = newWebhookPoller(cfg.Webhook, logger.With(slog.String("component", "Webhook")))
+	}
+	bot.queue = newSendQueue(cfg.Queue, bot.sendText, logger.With(slog.String("component", "SendQueue")))
 
 	err := bot.Init()
 	if err != nil {
@@ -52,7 +71,7 @@ func NewAdminTgBot(cfg BotCfg) (*Bot, error) {
 func (r *Bot) Init() error {
 	settings := tele.Settings{
 		Token:   r.token,
-		Poller:  &tele.LongPoller{Timeout: r.pollerTimeout},
+		Poller:  newPoller(r.webhook, r.pollerTimeout),
 		Verbose: r.debug,
 		OnError: func(err error, _ tele.Context) {
 			r.logger.Error(
@@ -67,35 +86,79 @@ func (r *Bot) Init() error {
 	}
 	r.bot = bot
 
-	r.bot.Use(ChatWhitelist(r.chatID))
+	err = dropWebhook(r.bot, r.webhook)
+	if err != nil {
+		return err
+	}
+
+	// commands are served in the admin chat only, notification buttons may be
+	// pressed in other chats by explicitly allowed chats or users
+	r.admin = r.bot.Group()
+	r.admin.Use(ChatWhitelist(r.chatID))
+	r.actions = r.bot.Group()
+	r.actions.Use(ActionWhitelist(append([]int64{r.chatID}, r.actionChatIDs...), r.actionUserIDs))
 
-	r.bot.Handle("/start", r.HandleOnStart)
+	r.admin.Handle("/start", r.HandleOnStart)
 
 	return nil
 }
 
+// WebhookHandler accepts telegram updates in webhook mode, it is nil for long polling.
+func (r *Bot) WebhookHandler() http.Handler {
+	if r.webhook == nil {
+		return nil
+	}
+	return r.webhook
+}
+
 func (r *Bot) Run(ctx context.Context) error {
 	if r.bot == nil {
 		panic("error: admin tg bot is not initialized")
 	}
 
+	return runBot(ctx, r.bot, r.queue)
+}
+
+// runBot polls updates and delivers queued messages until ctx is done,
+// the queue is drained after polling stops.
+func runBot(ctx context.Context, bot *tele.Bot, queue *sendQueue) error {
 	stopped := make(chan struct{})
+	queueCtx, queueCancel := context.WithCancel(context.Background())
+	queueDone := make(chan struct{})
 
 	go func() {
 		defer close(stopped)
-		r.bot.Start()
+		bot.Start()
+	}()
+	go func() {
+		defer close(queueDone)
+		_ = queue.Run(queueCtx)
 	}()
 
-	for {
-		select {
-		case <-stopped:
-			return fmt.Errorf("tgbot stopped unexpectedly")
-		case <-ctx.Done():
-			r.bot.Stop()
-			<-stopped
-			return nil
-		}
+	var err error
+	select {
+	case <-stopped:
+		err = fmt.Errorf("tgbot stopped unexpectedly")
+	case <-ctx.Done():
+		bot.Stop()
+		<-stopped
 	}
+
+	queueCancel()
+	<-queueDone
+	return err
+}
+
+func (r *Bot) sendText(key chatKey, text string, markup *tele.ReplyMarkup) error {
+	return observeSend("admin", key, func() error {
+		_, err := r.bot.Send(tele.ChatID(key.chatID), text, &tele.SendOptions{
+			ThreadID:              key.threadID,
+			ParseMode:             tele.ModeHTML,
+			ReplyMarkup:           markup,
+			DisableWebPagePreview: true,
+		})
+		return err
+	})
 }
 
 func (r *Bot) HandleOnStart(ctx tele.Context) error {
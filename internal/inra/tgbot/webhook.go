@@ -0,0 +1,124 @@
+package tgbot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	webhookSecretHeader  = "X-Telegram-Bot-Api-Secret-Token"
+	webhookRetryInterval = 30 * time.Second
+	webhookMaxBodyBytes  = 1 << 20
+)
+
+// WebhookCfg switches the bot from long polling to webhook,
+// updates are accepted by WebhookHandler mounted at URL.
+type WebhookCfg struct {
+	// URL is public address of the handler, long polling is used when empty
+	URL string
+	// SecretToken is sent by telegram in X-Telegram-Bot-Api-Secret-Token header
+	SecretToken string
+}
+
+// webhookPoller registers the webhook and feeds updates received over http to the bot,
+// unlike tele.Webhook it does not start own http server and rejects requests
+// arriving before the bot is started.
+type webhookPoller struct {
+	cfg    WebhookCfg
+	logger *slog.Logger
+
+	mu      sync.RWMutex
+	updates chan tele.Update
+	stop    chan struct{}
+}
+
+func newWebhookPoller(cfg WebhookCfg, logger *slog.Logger) *webhookPoller {
+	return &webhookPoller{cfg: cfg, logger: logger}
+}
+
+func (p *webhookPoller) Poll(b *tele.Bot, updates chan tele.Update, stop chan struct{}) {
+	p.mu.Lock()
+	p.updates, p.stop = updates, stop
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.updates, p.stop = nil, nil
+		p.mu.Unlock()
+	}()
+
+	// every replica sets the same webhook on start, the webhook is kept on shutdown
+	for {
+		err := b.SetWebhook(&tele.Webhook{
+			SecretToken: p.cfg.SecretToken,
+			Endpoint:    &tele.WebhookEndpoint{PublicURL: p.cfg.URL},
+		})
+		if err == nil {
+			break
+		}
+		p.logger.Error("failed to set tg webhook", slog.String("err", err.Error()))
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(webhookRetryInterval):
+		}
+	}
+
+	<-stop
+}
+
+func (p *webhookPoller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(p.cfg.SecretToken)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var upd tele.Update
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, webhookMaxBodyBytes)).Decode(&upd)
+	if err != nil {
+		p.logger.Warn("failed to decode tg update", slog.String("err", err.Error()))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	p.mu.RLock()
+	updates, stop := p.updates, p.stop
+	p.mu.RUnlock()
+
+	// telegram retries the update on non 2xx response
+	if updates == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	select {
+	case updates <- upd:
+		w.WriteHeader(http.StatusOK)
+	case <-stop:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// newPoller returns webhook poller when it is configured and long poller otherwise.
+func newPoller(webhook *webhookPoller, timeout time.Duration) tele.Poller {
+	if webhook != nil {
+		return webhook
+	}
+	return &tele.LongPoller{Timeout: timeout}
+}
+
+// dropWebhook removes webhook left from webhook mode, getUpdates fails while it is set.
+func dropWebhook(bot *tele.Bot, webhook *webhookPoller) error {
+	if webhook != nil {
+		return nil
+	}
+	return bot.RemoveWebhook()
+}
@@ -0,0 +1,38 @@
+//go:build unit
+// +build unit
+
+package tgbot
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	p := newWebhookPoller(WebhookCfg{URL: "https://example.com/hook", SecretToken: "s3cret"}, slog.Default())
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{"update_id":42}`))
+		if token != "" {
+			req.Header.Set(webhookSecretHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, serve(""))
+	require.Equal(t, http.StatusUnauthorized, serve("wrong"))
+	// not polling yet, telegram retries later
+	require.Equal(t, http.StatusServiceUnavailable, serve("s3cret"))
+
+	updates := make(chan tele.Update, 1)
+	p.updates, p.stop = updates, make(chan struct{})
+	require.Equal(t, http.StatusOK, serve("s3cret"))
+	require.Equal(t, 42, (<-updates).ID)
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"doctormakarhina/lumos/internal/core/notify"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SignatureHeader carries hex hmac sha256 of the request body when secret is configured.
+const SignatureHeader = "X-Lumos-Signature"
+
+// NotifyTarget posts notifications as json to the url.
+type NotifyTarget struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewNotifyTarget(url string, secret string, client *http.Client) *NotifyTarget {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &NotifyTarget{url: url, secret: secret, client: client}
+}
+
+func (r *NotifyTarget) Send(ctx context.Context, n notify.Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.secret != "" {
+		req.Header.Set(SignatureHeader, Sign(r.secret, body))
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,178 @@
+package authtoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+const algHS256 = "HS256"
+
+// Claims is a payload of JWT access token.
+type Claims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Email     string `json:"email"`
+	ProjectID string `json:"pid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+type Config struct {
+	// Keys maps key id to HMAC secret, all keys are accepted for verification.
+	Keys map[string][]byte
+	// ActiveKeyID is id of the key used to sign new tokens.
+	ActiveKeyID string
+	// TTL is lifetime of issued tokens.
+	TTL time.Duration
+	// Issuer is put to and checked in the iss claim.
+	Issuer string
+}
+
+// Manager issues and verifies HS256 JWT tokens, keys are rotated by adding a new
+// key, switching ActiveKeyID to it and removing the old key after TTL has passed.
+type Manager struct {
+	keys        map[string][]byte
+	activeKeyID string
+	ttl         time.Duration
+	issuer      string
+	now         func() time.Time
+}
+
+func NewManager(cfg Config) (*Manager, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("authtoken: at least one key is required")
+	}
+	for id, secret := range cfg.Keys {
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("authtoken: key %s must be at least 32 bytes long", id)
+		}
+	}
+	if _, ok := cfg.Keys[cfg.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("authtoken: active key %s is not configured", cfg.ActiveKeyID)
+	}
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("authtoken: TTL must be > 0")
+	}
+
+	return &Manager{
+		keys:        cfg.Keys,
+		activeKeyID: cfg.ActiveKeyID,
+		ttl:         cfg.TTL,
+		issuer:      cfg.Issuer,
+		now:         time.Now,
+	}, nil
+}
+
+// ParseKeys decodes base64 (std or url encoding) secrets from config.
+func ParseKeys(raw map[string]string) (map[string][]byte, error) {
+	keys := make(map[string][]byte, len(raw))
+	for id, encoded := range raw {
+		id = strings.TrimSpace(id)
+		encoded = strings.TrimSpace(encoded)
+
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			secret, err = base64.RawURLEncoding.DecodeString(encoded)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("authtoken: failed to decode key %s: %w", id, err)
+		}
+		keys[id] = secret
+	}
+
+	return keys, nil
+}
+
+// Issue signs claims with the active key, iss, iat and exp are set by the manager.
+func (m *Manager) Issue(claims Claims) (string, time.Time, error) {
+	now := m.now()
+	expiresAt := now.Add(m.ttl)
+
+	claims.Issuer = m.issuer
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = expiresAt.Unix()
+
+	headerJSON, err := json.Marshal(header{Alg: algHS256, Typ: "JWT", Kid: m.activeKeyID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	signature := sign(m.keys[m.activeKeyID], signingInput)
+
+	return signingInput + "." + signature, expiresAt, nil
+}
+
+func (m *Manager) Verify(token string) (*Claims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var h header
+	if err := json.Unmarshal(headerJSON, &h); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if h.Alg != algHS256 {
+		return nil, ErrInvalidToken
+	}
+
+	secret, ok := m.keys[h.Kid]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	expected := sign(secret, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Issuer != m.issuer {
+		return nil, ErrInvalidToken
+	}
+	if m.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+func sign(secret []byte, signingInput string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,47 @@
+//go:build unit
+// +build unit
+
+package authtoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueVerify(t *testing.T) {
+	keys := map[string][]byte{
+		"k1": []byte(strings.Repeat("a", 32)),
+		"k2": []byte(strings.Repeat("b", 32)),
+	}
+
+	old, err := NewManager(Config{Keys: keys, ActiveKeyID: "k1", TTL: time.Hour, Issuer: "lumos"})
+	require.NoError(t, err)
+	rotated, err := NewManager(Config{Keys: keys, ActiveKeyID: "k2", TTL: time.Hour, Issuer: "lumos"})
+	require.NoError(t, err)
+
+	token, _, err := old.Issue(Claims{Subject: "user-id", Email: "user@mail.com", ProjectID: "p1"})
+	require.NoError(t, err)
+
+	// token signed by the previous key is still accepted after rotation
+	claims, err := rotated.Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, "user-id", claims.Subject)
+	require.Equal(t, "user@mail.com", claims.Email)
+	require.Equal(t, "p1", claims.ProjectID)
+
+	parts := strings.Split(token, ".")
+	_, err = rotated.Verify(parts[0] + "." + parts[1] + "." + parts[2][:len(parts[2])-2] + "AA")
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	removed, err := NewManager(Config{Keys: map[string][]byte{"k2": keys["k2"]}, ActiveKeyID: "k2", TTL: time.Hour, Issuer: "lumos"})
+	require.NoError(t, err)
+	_, err = removed.Verify(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	rotated.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = rotated.Verify(token)
+	require.ErrorIs(t, err, ErrExpiredToken)
+}
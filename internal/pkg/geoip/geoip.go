@@ -0,0 +1,70 @@
+// Package geoip resolves ip addresses to location with offline MaxMind database.
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+type Location struct {
+	Country string
+	City    string
+}
+
+type Locator interface {
+	Lookup(ip string) Location
+}
+
+type Reader struct {
+	db *maxminddb.Reader
+}
+
+// Open opens GeoLite2/GeoIP2 City or Country mmdb file.
+func Open(path string) (*Reader, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{db: db}, nil
+}
+
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Lookup returns empty location for unknown or invalid addresses.
+func (r *Reader) Lookup(ip string) Location {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Location{}
+	}
+
+	var rec record
+	if err := r.db.Lookup(parsed, &rec); err != nil {
+		return Location{}
+	}
+
+	return Location{Country: rec.Country.ISOCode, City: rec.City.Names["en"]}
+}
+
+func (r *Reader) Close() error {
+	return r.db.Close()
+}
+
+type noop struct{}
+
+// NewNoop returns locator used when geoip database is not configured.
+func NewNoop() Locator {
+	return noop{}
+}
+
+func (noop) Lookup(string) Location {
+	return Location{}
+}
@@ -0,0 +1,123 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// ParseTrustedProxies parses CIDRs or bare ip addresses of trusted proxies.
+func ParseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, raw := range list {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		if strings.Contains(raw, "/") {
+			prefix, err := netip.ParsePrefix(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// ClientIP resolves the client address and stores it in the request context.
+// Forwarding headers are honored only when the peer is a trusted proxy,
+// X-Forwarded-For is walked from the right skipping trusted proxies.
+func ClientIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+// ClientIPFrom returns address resolved by ClientIP middleware, empty if it is unknown.
+func ClientIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, ok := parseAddr(hostOnly(r.RemoteAddr))
+	if !ok {
+		return ""
+	}
+	if !isTrusted(peer, trusted) {
+		return peer.String()
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := make([]string, 0)
+		for _, v := range xff {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+
+		var leftmost netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseAddr(hostOnly(strings.TrimSpace(hops[i])))
+			if !ok {
+				// garbage in the chain can be client supplied, nothing left of it is trusted
+				break
+			}
+			leftmost = addr
+			if !isTrusted(addr, trusted) {
+				return addr.String()
+			}
+		}
+		if leftmost.IsValid() {
+			return leftmost.String()
+		}
+	}
+
+	if addr, ok := parseAddr(hostOnly(strings.TrimSpace(r.Header.Get("X-Real-IP")))); ok {
+		return addr.String()
+	}
+
+	return peer.String()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAddr(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// hostOnly strips port and ipv6 brackets if present.
+func hostOnly(s string) string {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return host
+	}
+	return strings.Trim(s, "[]")
+}
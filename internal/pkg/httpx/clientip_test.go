@@ -0,0 +1,52 @@
+//go:build unit
+// +build unit
+
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{name: "direct ipv4", remote: "203.0.113.7:5555", want: "203.0.113.7"},
+		{name: "direct ipv6", remote: "[2001:db8::1]:5555", want: "2001:db8::1"},
+		{name: "untrusted peer ignores headers", remote: "203.0.113.7:1", headers: map[string]string{"X-Forwarded-For": "1.1.1.1"}, want: "203.0.113.7"},
+		{name: "trusted peer", remote: "10.0.0.5:1", headers: map[string]string{"X-Forwarded-For": "198.51.100.2"}, want: "198.51.100.2"},
+		{name: "spoofed left hops", remote: "10.0.0.5:1", headers: map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.2, 192.168.1.1"}, want: "198.51.100.2"},
+		{name: "ipv6 forwarded", remote: "10.0.0.5:1", headers: map[string]string{"X-Forwarded-For": "2001:db8::2"}, want: "2001:db8::2"},
+		{name: "all hops trusted", remote: "10.0.0.5:1", headers: map[string]string{"X-Forwarded-For": "10.1.1.1, 10.2.2.2"}, want: "10.1.1.1"},
+		{name: "real ip", remote: "10.0.0.5:1", headers: map[string]string{"X-Real-IP": "198.51.100.3"}, want: "198.51.100.3"},
+		{name: "mapped ipv4", remote: "[::ffff:10.0.0.5]:1", headers: map[string]string{"X-Forwarded-For": "198.51.100.4"}, want: "198.51.100.4"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			h := ClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIPFrom(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remote
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
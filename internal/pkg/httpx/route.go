@@ -0,0 +1,40 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	UnmatchedRoute = "unmatched"
+	secretSegment  = "{secret}"
+)
+
+// RouteNamer returns chi route pattern of routed request, secrets are replaced in patterns,
+// e.g. webhook route hashes, so they do not leak to metrics and traces.
+func RouteNamer(secrets ...string) func(r *http.Request) string {
+	var replace []string
+	for _, s := range secrets {
+		if s != "" {
+			replace = append(replace, s, secretSegment)
+		}
+	}
+	replacer := strings.NewReplacer(replace...)
+
+	return func(r *http.Request) string {
+		rctx := chi.RouteContext(r.Context())
+		if rctx == nil {
+			return UnmatchedRoute
+		}
+		route := rctx.RoutePattern()
+		if route == "" {
+			return UnmatchedRoute
+		}
+		if len(replace) > 0 {
+			route = replacer.Replace(route)
+		}
+		return route
+	}
+}
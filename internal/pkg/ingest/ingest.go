@@ -0,0 +1,181 @@
+// Package ingest implements bounded in-process queue which flushes items in batches.
+package ingest
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FlushFunc persists the batch, it must not retain the slice after return.
+type FlushFunc[T any] func(ctx context.Context, items []T) error
+
+type Config struct {
+	Name string
+	// QueueSize bounds amount of not flushed items, new items are dropped when the queue is full.
+	QueueSize int
+	// BatchSize triggers flush when that many items are collected.
+	BatchSize int
+	// FlushInterval triggers flush of not full batch.
+	FlushInterval time.Duration
+	// FlushTimeout bounds a single flush, it is also used for the final drain.
+	FlushTimeout time.Duration
+}
+
+type Stats struct {
+	Queued   int    `json:"queued"`
+	Accepted uint64 `json:"accepted"`
+	Dropped  uint64 `json:"dropped"`
+	Flushed  uint64 `json:"flushed"`
+	Failed   uint64 `json:"failed"`
+}
+
+type Queue[T any] struct {
+	cfg    Config
+	flush  FlushFunc[T]
+	items  chan T
+	logger *slog.Logger
+
+	// mu guards closed together with the enqueue, so nothing is accepted after the final drain
+	mu       sync.RWMutex
+	closed   bool
+	accepted atomic.Uint64
+	dropped  atomic.Uint64
+	flushed  atomic.Uint64
+	failed   atomic.Uint64
+}
+
+func New[T any](cfg Config, flush FlushFunc[T], rootLogger *slog.Logger) *Queue[T] {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = 10 * time.Second
+	}
+
+	return &Queue[T]{
+		cfg:    cfg,
+		flush:  flush,
+		items:  make(chan T, cfg.QueueSize),
+		logger: rootLogger.With(slog.String("context", "Ingest"), slog.String("queue", cfg.Name)),
+	}
+}
+
+// Enqueue adds items without blocking and returns amount of accepted ones,
+// the rest are dropped because the queue is full or already draining.
+func (q *Queue[T]) Enqueue(items ...T) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	accepted := 0
+	for _, item := range items {
+		if q.closed {
+			break
+		}
+
+		select {
+		case q.items <- item:
+			accepted++
+		default:
+		}
+	}
+
+	q.accepted.Add(uint64(accepted))
+	q.dropped.Add(uint64(len(items) - accepted))
+
+	return accepted
+}
+
+func (q *Queue[T]) Stats() Stats {
+	return Stats{
+		Queued:   len(q.items),
+		Accepted: q.accepted.Load(),
+		Dropped:  q.dropped.Load(),
+		Flushed:  q.flushed.Load(),
+		Failed:   q.failed.Load(),
+	}
+}
+
+// Run flushes batches until ctx is done, then stops accepting new items
+// and flushes the rest of the queue.
+func (q *Queue[T]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(q.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, q.cfg.BatchSize)
+	reportedDropped := uint64(0)
+
+	for {
+		select {
+		case item := <-q.items:
+			batch = append(batch, item)
+			if len(batch) >= q.cfg.BatchSize {
+				q.flushBatch(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				q.flushBatch(batch)
+				batch = batch[:0]
+			}
+			if dropped := q.dropped.Load(); dropped > reportedDropped {
+				q.logger.Warn("queue is full, items dropped",
+					slog.Uint64("dropped", dropped-reportedDropped),
+					slog.Uint64("dropped_total", dropped),
+				)
+				reportedDropped = dropped
+			}
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.closed = true
+			q.mu.Unlock()
+			q.drain(batch)
+			return nil
+		}
+	}
+}
+
+func (q *Queue[T]) drain(batch []T) {
+	for {
+		select {
+		case item := <-q.items:
+			batch = append(batch, item)
+			if len(batch) >= q.cfg.BatchSize {
+				q.flushBatch(batch)
+				batch = batch[:0]
+			}
+		default:
+			if len(batch) > 0 {
+				q.flushBatch(batch)
+			}
+			q.logger.Info("queue drained", slog.Any("stats", q.Stats()))
+			return
+		}
+	}
+}
+
+func (q *Queue[T]) flushBatch(batch []T) {
+	// flush is not bound to the run context, so the final drain is not cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), q.cfg.FlushTimeout)
+	defer cancel()
+
+	err := q.flush(ctx, batch)
+	if err != nil {
+		q.failed.Add(uint64(len(batch)))
+		q.logger.Error("failed to flush batch",
+			slog.Int("size", len(batch)),
+			slog.String("err", err.Error()),
+		)
+		return
+	}
+
+	q.flushed.Add(uint64(len(batch)))
+}
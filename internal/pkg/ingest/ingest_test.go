@@ -0,0 +1,61 @@
+//go:build unit
+// +build unit
+
+package ingest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueFlushesAndDrains(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		flushed []int
+		batches int
+	)
+	flush := func(_ context.Context, items []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		flushed = append(flushed, items...)
+		batches++
+		return nil
+	}
+
+	q := New(Config{Name: "test", QueueSize: 10, BatchSize: 3, FlushInterval: time.Hour}, flush, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	require.Equal(t, 10, q.Enqueue(1, 2, 3, 4, 5, 6, 7, 8, 9, 10))
+	require.Equal(t, 0, q.Enqueue(11, 12))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, q.Run(ctx))
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(flushed) >= 9
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+
+	require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, flushed)
+	require.Equal(t, 4, batches)
+	require.Equal(t, 0, q.Enqueue(13))
+
+	stats := q.Stats()
+	require.Equal(t, uint64(10), stats.Accepted)
+	require.Equal(t, uint64(3), stats.Dropped)
+	require.Equal(t, uint64(10), stats.Flushed)
+	require.Equal(t, 0, stats.Queued)
+}
@@ -37,5 +37,5 @@ func New(
 			Level:     level,
 		})
 	}
-	return slog.New(handler).With(slogArgs...), nil
+	return slog.New(traceHandler{handler}).With(slogArgs...), nil
 }
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const businessQueryTimeout = 5 * time.Second
+
+// TariffCounter returns amount of active subscribers per tariff.
+type TariffCounter func(ctx context.Context) (map[string]int, error)
+
+// subscribersCollector queries counts on scrape, results are cached for ttl
+// so frequent scrapes do not load the db.
+type subscribersCollector struct {
+	count TariffCounter
+	ttl   time.Duration
+	desc  *prometheus.Desc
+
+	mu        sync.Mutex
+	counts    map[string]int
+	fetchedAt time.Time
+}
+
+// RegisterSubscribers exposes active subscribers gauge by tariff.
+func RegisterSubscribers(count TariffCounter, ttl time.Duration) error {
+	return Registry.Register(&subscribersCollector{
+		count: count,
+		ttl:   ttl,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "active_subscribers"),
+			"Users with not expired access by tariff.",
+			[]string{"tariff"},
+			nil,
+		),
+	})
+}
+
+func (c *subscribersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *subscribersCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil || time.Since(c.fetchedAt) >= c.ttl {
+		ctx, cancel := context.WithTimeout(context.Background(), businessQueryTimeout)
+		counts, err := c.count(ctx)
+		cancel()
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(c.desc, err)
+			return
+		}
+		c.counts, c.fetchedAt = counts, time.Now()
+	}
+
+	for tariff, n := range c.counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), tariff)
+	}
+}
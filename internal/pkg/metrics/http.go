@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// HTTPMiddleware counts requests by route name, see httpx.RouteNamer.
+func HTTPMiddleware(routeName func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			route := routeName(r)
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+			HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		})
+	}
+}
@@ -0,0 +1,39 @@
+//go:build unit
+// +build unit
+
+package metrics
+
+import (
+	"doctormakarhina/lumos/internal/pkg/httpx"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMiddlewareRedactsSecrets(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware(httpx.RouteNamer("s3cret", "")))
+	r.Route("/api", func(r chi.Router) {
+		r.Post("/payments/webhook/s3cret", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+		r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/payments/webhook/s3cret", nil),
+		httptest.NewRequest(http.MethodGet, "/api/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/api/users/2", nil),
+		httptest.NewRequest(http.MethodGet, "/nope", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	require.Equal(t, 1.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("POST", "/api/payments/webhook/{secret}", "202")))
+	require.Equal(t, 2.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/api/users/{id}", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", httpx.UnmatchedRoute, "404")))
+}
@@ -0,0 +1,44 @@
+//go:build unit
+// +build unit
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"doctormakarhina/lumos/internal/pkg/httpx"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPMiddlewareNamesSpanByRoute(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, ServiceName: "test", SampleRatio: 1, Writer: &out})
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	var traceID string
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware(httpx.RouteNamer("s3cret")))
+	r.Post("/webhook/s3cret/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "UserRepo.ByEmail")
+		End(span, errors.New("boom"))
+		traceID = trace.SpanContextFromContext(r.Context()).TraceID().String()
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/s3cret/7", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, "0af7651916cd43dd8448eb211c80319c", traceID)
+	require.Contains(t, out.String(), `"Name":"POST /webhook/{secret}/{id}"`)
+	require.Contains(t, out.String(), `"Name":"UserRepo.ByEmail"`)
+	require.Contains(t, out.String(), `"Description":"boom"`)
+	require.NotContains(t, out.String(), "s3cret")
+}